@@ -0,0 +1,129 @@
+// Package age decrypts config files encrypted with age (https://age-encryption.org),
+// such as config.yaml.age, and encrypts them again when they are written.
+package age
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+
+    "filippo.io/age"
+    "filippo.io/age/armor"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Extension of age encrypted config files.
+const Ext = "age"
+
+// Reads the age identities in the files at paths, in the format of age-keygen, and
+// registers them on c to decrypt .age config files, see SetIdentitiesFrom.
+func SetIdentities(c *cfg.Config, paths ...string) error {
+    readers := make([]io.Reader, 0, len(paths))
+    for _, path := range paths {
+        f, err := os.Open(path)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        readers = append(readers, f)
+    }
+
+    return SetIdentitiesFrom(c, readers...)
+}
+
+// Reads the age identities in readers and registers them on c to decrypt .age config
+// files. Written files are encrypted to the recipients of the X25519 identities, so a
+// file read can be written back, and to any recipients added with AddRecipients.
+func SetIdentitiesFrom(c *cfg.Config, readers ...io.Reader) error {
+    cipher := &fileCipher{}
+    for _, r := range readers {
+        ids, err := age.ParseIdentities(r)
+        if err != nil {
+            return fmt.Errorf("Parsing age identities: %w", err)
+        }
+
+        for _, id := range ids {
+            cipher.identities = append(cipher.identities, id)
+            if x, ok := id.(*age.X25519Identity); ok {
+                cipher.recipients = append(cipher.recipients, x.Recipient())
+            }
+        }
+    }
+
+    if len(cipher.identities) == 0 {
+        return fmt.Errorf("No age identities found")
+    }
+
+    c.SetFileCipher(Ext, cipher)
+    return nil
+}
+
+// Encrypts the .age config files written by c to recipients too, in the format of
+// age-keygen -y, so others can read them. SetIdentities or SetIdentitiesFrom must be
+// called first.
+func AddRecipients(c *cfg.Config, recipients ...string) error {
+    cipher, ok := currentCipher(c)
+    if !ok {
+        return fmt.Errorf("No age identities set")
+    }
+
+    for _, s := range recipients {
+        r, err := age.ParseX25519Recipient(s)
+        if err != nil {
+            return err
+        }
+        cipher.recipients = append(cipher.recipients, r)
+    }
+
+    return nil
+}
+
+// currentCipher returns the age cipher registered on c.
+func currentCipher(c *cfg.Config) (*fileCipher, bool) {
+    fc, ok := c.FileCipher(Ext).(*fileCipher)
+    return fc, ok
+}
+
+type fileCipher struct {
+    identities []age.Identity
+    recipients []age.Recipient
+}
+
+// Decrypt decrypts binary and ASCII armored age files.
+func (f *fileCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+    var src io.Reader = bytes.NewReader(ciphertext)
+    if bytes.HasPrefix(bytes.TrimSpace(ciphertext), []byte(armor.Header)) {
+        src = armor.NewReader(src)
+    }
+
+    r, err := age.Decrypt(src, f.identities...)
+    if err != nil {
+        return nil, err
+    }
+
+    return ioutil.ReadAll(r)
+}
+
+// Encrypt encrypts to binary age files.
+func (f *fileCipher) Encrypt(plaintext []byte) ([]byte, error) {
+    if len(f.recipients) == 0 {
+        return nil, fmt.Errorf("No age recipients to encrypt to")
+    }
+
+    var buf bytes.Buffer
+    w, err := age.Encrypt(&buf, f.recipients...)
+    if err != nil {
+        return nil, err
+    }
+    if _, err := w.Write(plaintext); err != nil {
+        return nil, err
+    }
+    if err := w.Close(); err != nil {
+        return nil, err
+    }
+
+    return buf.Bytes(), nil
+}
@@ -0,0 +1,174 @@
+package age
+
+import (
+    "bytes"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "filippo.io/age"
+    "filippo.io/age/armor"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Encrypts plaintext to r, armored or not.
+func encrypt(t *testing.T, r age.Recipient, plaintext string, armored bool) []byte {
+    t.Helper()
+
+    var buf bytes.Buffer
+    var dst io.Writer = &buf
+    var aw io.WriteCloser
+    if armored {
+        aw = armor.NewWriter(&buf)
+        dst = aw
+    }
+
+    w, err := age.Encrypt(dst, r)
+    if err != nil {
+        t.Fatal(err)
+    }
+    io.WriteString(w, plaintext)
+    if err := w.Close(); err != nil {
+        t.Fatal(err)
+    }
+    if aw != nil {
+        aw.Close()
+    }
+
+    return buf.Bytes()
+}
+
+func newIdentity(t *testing.T) *age.X25519Identity {
+    t.Helper()
+
+    id, err := age.GenerateX25519Identity()
+    if err != nil {
+        t.Fatal(err)
+    }
+    return id
+}
+
+func TestDecrypt(t *testing.T) {
+    id, other := newIdentity(t), newIdentity(t)
+
+    tests := []struct {
+        name    string
+        id      *age.X25519Identity
+        armored bool
+        wantErr bool
+    }{
+        {"binary", id, false, false},
+        {"armored", id, true, false},
+        {"other identity", other, false, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            file := filepath.Join(t.TempDir(), "config.yaml."+Ext)
+            if err := os.WriteFile(file, encrypt(t, id.Recipient(), "db:\n  password: hunter2\n", tt.armored), 0600); err != nil {
+                t.Fatal(err)
+            }
+
+            c := cfg.New()
+            if err := SetIdentitiesFrom(c, strings.NewReader(tt.id.String())); err != nil {
+                t.Fatal(err)
+            }
+            c.SetConfigFile(file)
+
+            err := c.ReadInConfig()
+            if tt.wantErr {
+                if err == nil {
+                    t.Error("ReadInConfig() with the wrong identity succeeded")
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := c.GetString("db.password"); got != "hunter2" {
+                t.Errorf("GetString(db.password) = %q, want hunter2", got)
+            }
+        })
+    }
+}
+
+func TestWriteEncrypts(t *testing.T) {
+    id, reader := newIdentity(t), newIdentity(t)
+
+    file := filepath.Join(t.TempDir(), "config.yaml."+Ext)
+    if err := os.WriteFile(file, encrypt(t, id.Recipient(), "name: api\n", false), 0600); err != nil {
+        t.Fatal(err)
+    }
+
+    c := cfg.New()
+    if err := SetIdentitiesFrom(c, strings.NewReader(id.String())); err != nil {
+        t.Fatal(err)
+    }
+    if err := AddRecipients(c, reader.Recipient().String()); err != nil {
+        t.Fatal(err)
+    }
+    c.SetConfigFile(file)
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    c.Set("name", "web")
+    if err := c.WriteConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    written, err := os.ReadFile(file)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if bytes.Contains(written, []byte("web")) {
+        t.Fatalf("written file holds plaintext:\n%s", written)
+    }
+
+    // Both the identity read with and the added recipient can read it back.
+    for _, id := range []*age.X25519Identity{id, reader} {
+        r := cfg.New()
+        if err := SetIdentitiesFrom(r, strings.NewReader(id.String())); err != nil {
+            t.Fatal(err)
+        }
+        r.SetConfigFile(file)
+        if err := r.ReadInConfig(); err != nil {
+            t.Fatal(err)
+        }
+        if got := r.GetString("name"); got != "web" {
+            t.Errorf("GetString(name) = %q, want web", got)
+        }
+    }
+}
+
+func TestIdentitiesErrors(t *testing.T) {
+    tests := []struct {
+        name string
+        run  func(c *cfg.Config) error
+    }{
+        {"no identities", func(c *cfg.Config) error {
+            return SetIdentitiesFrom(c, strings.NewReader("# nothing\n"))
+        }},
+        {"invalid identity", func(c *cfg.Config) error {
+            return SetIdentitiesFrom(c, strings.NewReader("AGE-SECRET-KEY-INVALID\n"))
+        }},
+        {"recipients first", func(c *cfg.Config) error {
+            return AddRecipients(c, newIdentity(t).Recipient().String())
+        }},
+        {"invalid recipient", func(c *cfg.Config) error {
+            SetIdentitiesFrom(c, strings.NewReader(newIdentity(t).String()))
+            return AddRecipients(c, "age1invalid")
+        }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := tt.run(cfg.New()); err == nil {
+                t.Error("succeeded")
+            }
+        })
+    }
+}
@@ -0,0 +1,173 @@
+package cfg
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Denotes an alias that would resolve back to itself, directly or through other aliases.
+type AliasCycleError struct {
+    Alias string
+    Key   string
+
+    // The keys the alias would resolve through, starting with the alias
+    Chain []string
+}
+
+// Returns the formatted cycle error.
+func (ace AliasCycleError) Error() string {
+    return fmt.Sprintf("Cannot alias %q to %q, cyclic alias %s", ace.Alias, ace.Key, strings.Join(ace.Chain, " -> "))
+}
+
+// Aliases provide another accessor for the same key.
+// This enables one to change a name without breaking the application.
+//
+// Both alias and key may be nested, aliasing db.host to database.hostname, and an alias
+// of a map applies to everything beneath it. Aliases only change how keys are looked up:
+// values stored under the alias name in any layer are found when key is read, below
+// the values stored under key itself. Registering an alias that would resolve back to
+// itself fails with an AliasCycleError, registering an alias again is ignored.
+func RegisterAlias(alias string, key string) error { return c.RegisterAlias(alias, key) }
+func (c *Config) RegisterAlias(alias string, key string) error {
+    c.mustNotBeFrozen()
+
+    alias, key = c.normalizeKey(alias), c.normalizeKey(key)
+
+    c.mu.Lock()
+    _, exists := c.aliases[alias]
+    err := c.registerAlias(alias, key)
+    c.mu.Unlock()
+
+    if err == nil && !exists {
+        c.recordChange(AuditRegisterAlias, alias, nil, key)
+    }
+
+    return err
+}
+
+func (c *Config) registerAlias(alias string, key string) error {
+    if _, exists := c.aliases[alias]; exists {
+        return nil
+    }
+
+    if chain := c.keyCycle(alias, key); chain != nil {
+        return AliasCycleError{alias, key, chain}
+    }
+
+    c.aliases[alias] = key
+    c.invalidate()
+
+    return nil
+}
+
+// keyCycle follows key the way realKey would, through aliases and deprecations, and
+// returns the keys passed when alias is reached on the way, which would make alias
+// resolve in circles. Caller must hold mu.
+func (c *Config) keyCycle(alias, key string) []string {
+    chain := []string{alias}
+    for k, more := key, true; more; {
+        chain = append(chain, k)
+        if _, ok := c.trimKeyPrefix(k, alias); ok {
+            return chain
+        }
+
+        var d *deprecation
+        if k, more = c.aliasTarget(k); !more {
+            k, d = c.undeprecate(k)
+            more = d != nil
+        }
+    }
+
+    return nil
+}
+
+// Returns the registered aliases, mapped to the key each was registered for.
+func Aliases() map[string]string { return c.Aliases() }
+func (c *Config) Aliases() map[string]string {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    aliases := make(map[string]string, len(c.aliases))
+    for alias, key := range c.aliases {
+        aliases[alias] = key
+    }
+
+    return aliases
+}
+
+// aliasTarget maps key, when it or a parent of it is an alias, one step towards the
+// key it stands for. The longest alias wins. Caller must hold mu.
+func (c *Config) aliasTarget(key string) (string, bool) {
+    if newkey, exists := c.aliases[key]; exists {
+        return newkey, true
+    }
+
+    if strings.Contains(key, c.keyDelm) {
+        path := strings.Split(key, c.keyDelm)
+        for i := len(path) - 1; i > 0; i-- {
+            if newkey, exists := c.aliases[strings.Join(path[:i], c.keyDelm)]; exists {
+                return newkey + c.keyDelm + strings.Join(path[i:], c.keyDelm), true
+            }
+        }
+    }
+
+    return key, false
+}
+
+// unalias resolves the aliases of key, leaving deprecated names as they are. Caller must
+// hold mu.
+func (c *Config) unalias(key string) string {
+    for more := len(c.aliases) > 0; more; {
+        key, more = c.aliasTarget(key)
+    }
+
+    return key
+}
+
+// aliasNames returns the aliases values of key may be stored under, those of key and
+// of its parents, in order. Caller must hold mu.
+func (c *Config) aliasNames(key string) []string {
+    var names []string
+    for alias, target := range c.aliases {
+        if rest, ok := c.trimKeyPrefix(key, c.realKey(target)); ok {
+            names = append(names, alias+rest)
+        }
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+// aliasesBeneath returns the aliases of keys beneath key, in order, along with the
+// paths below key of the keys they stand for. Caller must hold mu.
+func (c *Config) aliasesBeneath(key string) (aliases []string, paths [][]string) {
+    for alias, target := range c.aliases {
+        if rest, ok := c.trimKeyPrefix(c.realKey(target), key); ok && rest != "" {
+            aliases = append(aliases, alias)
+        }
+    }
+    sort.Strings(aliases)
+
+    for _, alias := range aliases {
+        rest, _ := c.trimKeyPrefix(c.realKey(c.aliases[alias]), key)
+        paths = append(paths, strings.Split(rest[len(c.keyDelm):], c.keyDelm))
+    }
+
+    return aliases, paths
+}
+
+// searchAliased looks key up in m, then under the aliases in names. Caller must hold mu.
+func (c *Config) searchAliased(m map[string]interface{}, key string, names []string) (interface{}, bool) {
+    if val, exists := c.searchLayer(m, key); exists {
+        return val, true
+    }
+
+    for _, name := range names {
+        if val, exists := c.searchLayer(m, name); exists {
+            return val, true
+        }
+    }
+
+    return nil, false
+}
@@ -0,0 +1,87 @@
+package cfg
+
+import (
+    "errors"
+    "reflect"
+    "testing"
+)
+
+func TestRegisterAlias(t *testing.T) {
+    tests := []struct {
+        name  string
+        alias string
+        key   string
+        read  string
+        want  interface{}
+    }{
+        {"plain", "host", "db.host", "host", "localhost"},
+        {"nested", "database.hostname", "db.host", "database.hostname", "localhost"},
+        {"map", "database", "db", "database.port", 5432},
+        {"case", "HOST", "DB.Host", "host", "localhost"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.Set("db", map[string]interface{}{"host": "localhost", "port": 5432})
+            if err := c.RegisterAlias(tt.alias, tt.key); err != nil {
+                t.Fatal(err)
+            }
+
+            if got := c.Get(tt.read); !sameValue(got, tt.want) {
+                t.Errorf("Get(%s) = %#v, want %#v", tt.read, got, tt.want)
+            }
+        })
+    }
+}
+
+func TestRegisterAliasSetThroughAlias(t *testing.T) {
+    c := New()
+    c.RegisterAlias("host", "db.host")
+    c.Set("host", "localhost")
+
+    if got := c.GetString("db.host"); got != "localhost" {
+        t.Errorf("GetString(db.host) = %q, want localhost", got)
+    }
+    if got, want := c.Aliases(), map[string]string{"host": "db.host"}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Aliases() = %v, want %v", got, want)
+    }
+
+    c.Unset("host")
+    if c.IsSet("db.host") {
+        t.Error("db.host still set after Unset(host)")
+    }
+}
+
+func TestRegisterAliasCycle(t *testing.T) {
+    tests := []struct {
+        name  string
+        setup func(c *Config) error
+        alias string
+        key   string
+    }{
+        {"self", func(c *Config) error { return nil }, "a", "a"},
+        {"beneath itself", func(c *Config) error { return nil }, "a", "a.b"},
+        {"two aliases", func(c *Config) error { return c.RegisterAlias("b", "a") }, "a", "b"},
+        {"through deprecation", func(c *Config) error { return c.Deprecate("b", "a", "") }, "a", "b"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            if err := tt.setup(c); err != nil {
+                t.Fatal(err)
+            }
+
+            var cycle AliasCycleError
+            if err := c.RegisterAlias(tt.alias, tt.key); !errors.As(err, &cycle) {
+                t.Fatalf("RegisterAlias(%s, %s) = %v, want AliasCycleError", tt.alias, tt.key, err)
+            }
+
+            c.Set("a", "value")
+            if got := c.GetString("a"); got != "value" {
+                t.Errorf("GetString(a) = %q, want value", got)
+            }
+        })
+    }
+}
@@ -0,0 +1,134 @@
+package cfg
+
+import (
+    "fmt"
+    "log/slog"
+    "reflect"
+    "runtime"
+    "strings"
+    "time"
+)
+
+// Names a change recorded in the audit log.
+type AuditOp string
+
+const (
+    AuditSet           AuditOp = "set"
+    AuditUnset         AuditOp = "unset"
+    AuditSetDefault    AuditOp = "set_default"
+    AuditRegisterAlias AuditOp = "register_alias"
+    AuditReadConfig    AuditOp = "read_config"
+    AuditMergeConfig   AuditOp = "merge_config"
+    AuditRollback      AuditOp = "rollback"
+)
+
+// Describes a change made to the config, see SetAuditLog.
+type AuditEntry struct {
+    Time time.Time
+    Op   AuditOp
+
+    // Key set, unset or aliased, empty when a config file was read or merged
+    Key string
+
+    // Value the layer changed held for the key before and after, with the values of
+    // secret keys replaced by "***". New is the key aliased for AuditRegisterAlias and
+    // the config file for AuditReadConfig and AuditMergeConfig.
+    Old interface{}
+    New interface{}
+
+    // File and line of the code outside the package that made the change
+    Caller string
+}
+
+// Returns the entry as a single line.
+func (e AuditEntry) String() string {
+    s := fmt.Sprintf("%s %s", e.Time.Format(time.RFC3339), e.Op)
+    if e.Key != "" {
+        s += fmt.Sprintf(" %s: %v -> %v", e.Key, e.Old, e.New)
+    } else {
+        s += fmt.Sprintf(" %v", e.New)
+    }
+    if e.Caller != "" {
+        s += " by " + e.Caller
+    }
+
+    return s
+}
+
+// Path of the package, the frames of its functions are skipped when looking for callers.
+var pkgPath = reflect.TypeOf((*Config)(nil)).Elem().PkgPath()
+
+// Records every Set, Unset, SetDefault, RegisterAlias and Rollback and every config file
+// read or merged with ReadInConfig and MergeInConfig, keeping the last max entries, see
+// AuditLog. Every entry is logged as a config_changed event too. A max of 0, the
+// default, stops recording and drops the entries kept.
+func SetAuditLog(max int) { c.SetAuditLog(max) }
+func (c *Config) SetAuditLog(max int) {
+    c.auditMu.Lock()
+    defer c.auditMu.Unlock()
+
+    c.auditMax = max
+    if max == 0 {
+        c.audit = nil
+    } else if len(c.audit) > max {
+        c.audit = append([]AuditEntry(nil), c.audit[len(c.audit)-max:]...)
+    }
+}
+
+// Returns the recorded changes, oldest first.
+func AuditLog() []AuditEntry { return c.AuditLog() }
+func (c *Config) AuditLog() []AuditEntry {
+    c.auditMu.Lock()
+    defer c.auditMu.Unlock()
+
+    return append([]AuditEntry(nil), c.audit...)
+}
+
+// auditing reports whether changes are recorded.
+func (c *Config) auditing() bool {
+    c.auditMu.Lock()
+    defer c.auditMu.Unlock()
+
+    return c.auditMax > 0
+}
+
+// recordChange records a change of key from old to new, redacting the values of secret
+// keys. Caller must not hold mu.
+func (c *Config) recordChange(op AuditOp, key string, old, new interface{}) {
+    if !c.auditing() {
+        return
+    }
+    if key != "" && op != AuditRegisterAlias {
+        old, new = c.redactKey(key, old), c.redactKey(key, new)
+    }
+
+    e := AuditEntry{Time: time.Now(), Op: op, Key: key, Old: old, New: new, Caller: caller()}
+
+    c.auditMu.Lock()
+    if c.auditMax > 0 {
+        if len(c.audit) >= c.auditMax {
+            c.audit = append(c.audit[:0], c.audit[len(c.audit)-c.auditMax+1:]...)
+        }
+        c.audit = append(c.audit, e)
+    }
+    c.auditMu.Unlock()
+
+    c.logEvent(slog.LevelInfo, "config_changed",
+        slog.String("op", string(op)), slog.String("key", key),
+        slog.Any("old", old), slog.Any("new", new), slog.String("caller", e.Caller))
+}
+
+// caller returns the file and line of the innermost caller outside the package.
+func caller() string {
+    pcs := make([]uintptr, 32)
+    frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+    for {
+        f, more := frames.Next()
+        if !strings.HasPrefix(f.Function, pkgPath+".") || strings.HasSuffix(f.File, "_test.go") {
+            return fmt.Sprintf("%s:%d", f.File, f.Line)
+        }
+        if !more {
+            return ""
+        }
+    }
+}
@@ -0,0 +1,55 @@
+// Package awssm provides a cfg source backed by AWS Secrets Manager.
+package awssm
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "time"
+
+    "github.com/aws/aws-sdk-go-v2/aws"
+    "github.com/aws/aws-sdk-go-v2/config"
+    "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Adds the JSON secret secretID stored in region as a named source on c.
+//
+// Credentials are resolved through the default AWS chain. The secret is re-fetched every
+// refresh interval to follow rotation, a zero interval fetches it only once.
+func AddSecretsManagerSource(c *cfg.Config, secretID, region string, refresh time.Duration) error {
+    awsCfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+    if err != nil {
+        return err
+    }
+
+    client := secretsmanager.NewFromConfig(awsCfg)
+
+    return c.AddSource("awssm:"+secretID, fetcher(client, secretID), refresh)
+}
+
+func fetcher(client *secretsmanager.Client, secretID string) cfg.SourceFunc {
+    return func() (map[string]interface{}, error) {
+        out, err := client.GetSecretValue(context.Background(), &secretsmanager.GetSecretValueInput{
+            SecretId: aws.String(secretID),
+        })
+        if err != nil {
+            return nil, err
+        }
+
+        var raw []byte
+        if out.SecretString != nil {
+            raw = []byte(*out.SecretString)
+        } else {
+            raw = out.SecretBinary
+        }
+
+        m := make(map[string]interface{})
+        if err := json.Unmarshal(raw, &m); err != nil {
+            return nil, fmt.Errorf("Secret %q is not a JSON object: %v", secretID, err)
+        }
+
+        return m, nil
+    }
+}
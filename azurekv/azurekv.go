@@ -0,0 +1,99 @@
+// Package azurekv provides a cfg source backed by Azure Key Vault.
+package azurekv
+
+import (
+    "context"
+    "strings"
+    "time"
+
+    "github.com/Azure/azure-sdk-for-go/sdk/azcore"
+    "github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+    "github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Transforms a vault secret name into a config key.
+type NameFunc func(secretName string) string
+
+// Maps secret names to keys by replacing "--" with the key delimiter, since vault
+// names may only contain alphanumerics and dashes. database--host becomes database.host.
+func DefaultName(secretName string) string {
+    return strings.ToLower(strings.Replace(secretName, "--", ".", -1))
+}
+
+// Controls how a vault is read.
+type Options struct {
+    // Maps secret names to config keys, DefaultName when nil.
+    Name NameFunc
+
+    // Client ID of a user-assigned managed identity. When empty the
+    // DefaultAzureCredential chain is used, which includes system-assigned managed identity.
+    ManagedIdentityClientID string
+
+    // Interval at which the vault is re-read, zero reads it only once.
+    Refresh time.Duration
+}
+
+// Adds every enabled secret in the vault at vaultURL as a source on c.
+func AddKeyVaultSource(c *cfg.Config, vaultURL string, opts Options) error {
+    cred, err := credential(opts.ManagedIdentityClientID)
+    if err != nil {
+        return err
+    }
+
+    client, err := azsecrets.NewClient(vaultURL, cred, nil)
+    if err != nil {
+        return err
+    }
+
+    name := opts.Name
+    if name == nil {
+        name = DefaultName
+    }
+
+    return c.AddSource("azurekv:"+vaultURL, fetcher(client, name), opts.Refresh)
+}
+
+func credential(clientID string) (azcore.TokenCredential, error) {
+    if clientID != "" {
+        return azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{
+            ID: azidentity.ClientID(clientID),
+        })
+    }
+
+    return azidentity.NewDefaultAzureCredential(nil)
+}
+
+func fetcher(client *azsecrets.Client, name NameFunc) cfg.SourceFunc {
+    return func() (map[string]interface{}, error) {
+        ctx := context.Background()
+        m := make(map[string]interface{})
+
+        pager := client.NewListSecretPropertiesPager(nil)
+        for pager.More() {
+            page, err := pager.NextPage(ctx)
+            if err != nil {
+                return nil, err
+            }
+
+            for _, props := range page.Value {
+                if props.ID == nil || (props.Attributes != nil && props.Attributes.Enabled != nil && !*props.Attributes.Enabled) {
+                    continue
+                }
+
+                secretName := props.ID.Name()
+                secret, err := client.GetSecret(ctx, secretName, "", nil)
+                if err != nil {
+                    return nil, err
+                }
+
+                if secret.Value != nil {
+                    m[name(secretName)] = *secret.Value
+                }
+            }
+        }
+
+        return m, nil
+    }
+}
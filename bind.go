@@ -0,0 +1,120 @@
+package cfg
+
+import (
+    "fmt"
+    "reflect"
+    "sync"
+    "sync/atomic"
+)
+
+type binding struct {
+    // Identifies the binding for Unbind, the target struct or atomic pointer.
+    target interface{}
+
+    // Decodes the current settings and publishes them.
+    update func() error
+}
+
+// Unmarshals all settings into target, a pointer to a struct, and does so again every
+// time the config file is re-read or merged, a drop-in directory is added, or a source
+// changes. Each update is decoded into a fresh value and copied into target while mu is
+// held, so readers holding mu never see a partial update. A failed update is logged and
+// leaves target as it was.
+func Bind(target interface{}, mu sync.Locker) error { return c.Bind(target, mu) }
+func (c *Config) Bind(target interface{}, mu sync.Locker) error {
+    return c.BindKey("", target, mu)
+}
+
+// Like Bind, but unmarshals the value of key as UnmarshalKey does. An empty key binds all
+// settings.
+func BindKey(key string, target interface{}, mu sync.Locker) error {
+    return c.BindKey(key, target, mu)
+}
+func (c *Config) BindKey(key string, target interface{}, mu sync.Locker) error {
+    v := reflect.ValueOf(target)
+    if v.Kind() != reflect.Ptr || v.IsNil() {
+        return fmt.Errorf("Bind target must be a non-nil pointer, got %T", target)
+    }
+
+    return c.addBinding(target, func() error {
+        fresh := reflect.New(v.Elem().Type())
+        if err := c.unmarshalFor(key, fresh.Interface()); err != nil {
+            return err
+        }
+
+        mu.Lock()
+        v.Elem().Set(fresh.Elem())
+        mu.Unlock()
+
+        return nil
+    })
+}
+
+// Like BindKey, but publishes every update by swapping the returned pointer, so readers
+// call Load and never block. An empty key binds all settings.
+func BindAtomic[T any](c *Config, key string) (*atomic.Pointer[T], error) {
+    p := new(atomic.Pointer[T])
+
+    err := c.addBinding(p, func() error {
+        fresh := new(T)
+        if err := c.unmarshalFor(key, fresh); err != nil {
+            return err
+        }
+
+        p.Store(fresh)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return p, nil
+}
+
+// Stops updating target, a struct passed to Bind or BindKey or a pointer returned by
+// BindAtomic.
+func Unbind(target interface{}) { c.Unbind(target) }
+func (c *Config) Unbind(target interface{}) {
+    c.bindMu.Lock()
+    defer c.bindMu.Unlock()
+
+    for i, b := range c.bindings {
+        if b.target == target {
+            c.bindings = append(c.bindings[:i], c.bindings[i+1:]...)
+            return
+        }
+    }
+}
+
+func (c *Config) addBinding(target interface{}, update func() error) error {
+    if err := update(); err != nil {
+        return err
+    }
+
+    c.bindMu.Lock()
+    c.bindings = append(c.bindings, &binding{target, update})
+    c.bindMu.Unlock()
+
+    return nil
+}
+
+func (c *Config) unmarshalFor(key string, rawVal interface{}) error {
+    if key == "" {
+        return c.Unmarshal(rawVal)
+    }
+    return c.UnmarshalKey(c.normalizeKey(key), rawVal)
+}
+
+// changed is called after the config file layer or a source has been replaced.
+func (c *Config) changed() {
+    c.bindMu.Lock()
+    bindings := make([]*binding, len(c.bindings))
+    copy(bindings, c.bindings)
+    c.bindMu.Unlock()
+
+    for _, b := range bindings {
+        if err := b.update(); err != nil {
+            c.logError("Failed to update bound config, keeping previous:", err)
+        }
+    }
+}
@@ -0,0 +1,86 @@
+package cfg
+
+import (
+    "sync"
+    "sync/atomic"
+)
+
+// Number of keys no layer holds a snapshot keeps, so probing arbitrary key names, such
+// as those requested from a Handler, cannot grow it without bound until the next change.
+const maxCachedMisses = 1024
+
+// Where a key was found and the type its value is converted to, as resolved by lookup.
+type resolvedKey struct {
+    lcaseKey string
+    realKey  string
+    val      interface{}
+    layer    layer
+    valType  interface{}
+
+    // Deprecations of the key read and of the key its value was found under
+    used  *deprecation
+    found *deprecation
+}
+
+// An immutable snapshot of the effective settings between two changes, holding every
+// key read since the first as resolved then. Reading a key again skips normalizing it,
+// resolving its aliases and walking the layers for it, and takes no lock, so concurrent
+// readers never contend. Up to maxCachedMisses keys no layer holds are kept too, so
+// probing unset keys with IsSet costs no more than reading set ones. Every change
+// publishes a new, empty snapshot, see invalidate.
+type keyCache struct {
+    keys   sync.Map
+    misses atomic.Int64
+}
+
+// resolve returns where key is found, from the current snapshot if it was resolved
+// since the last change.
+func (c *Config) resolve(key string) *resolvedKey {
+    cache := c.keyCache.Load()
+    if rk, ok := cache.keys.Load(key); ok {
+        return rk.(*resolvedKey)
+    }
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    rk := &resolvedKey{lcaseKey: c.normalizeKey(key)}
+    _, rk.used = c.undeprecate(rk.lcaseKey)
+    rk.val, rk.layer, rk.found = c.find(rk.lcaseKey)
+    rk.realKey = c.realKey(rk.lcaseKey)
+
+    // A map is merged with the maps and dotted keys beneath it in every layer, so Get and
+    // the map getters see deep defaults and overrides the way Sub and UnmarshalKey do. So
+    // is a key only set through the keys beneath it.
+    _, isMap := toStringMap(rk.val)
+    if isMap && rk.layer.kind != LayerComputed || rk.layer.kind == "" {
+        if settings, top, found := c.settingsBeneath(rk.lcaseKey); found {
+            rk.val = settings
+            if rk.layer.kind == "" {
+                rk.layer = top
+            }
+        }
+    }
+
+    rk.valType = rk.val
+    if rk.val != nil && (c.typeByDefValue || c.strictTypes) {
+        defVal, defExists := c.searchAliased(c.defaults, rk.realKey, c.aliasNames(rk.realKey))
+        if defExists && defVal != nil {
+            rk.valType = defVal
+        }
+    }
+
+    // Stored in the snapshot loaded before the layers were read, a change made meanwhile
+    // publishes another, so a stale result is never read.
+    if rk.layer.kind != "" || cache.misses.Add(1) <= maxCachedMisses {
+        cache.keys.Store(key, rk)
+    }
+
+    return rk
+}
+
+// invalidate publishes an empty snapshot, dropping every resolved key. Call it after
+// changing a layer or anything resolution depends on.
+func (c *Config) invalidate() {
+    c.keyCache.Store(new(keyCache))
+}
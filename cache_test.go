@@ -0,0 +1,137 @@
+package cfg
+
+import (
+    "fmt"
+    "testing"
+)
+
+// Returns a Config holding a nested key in its config layer beneath every other layer
+// kind, so reads of it walk the layers above first.
+func benchConfig(b *testing.B) *Config {
+    c := New()
+    c.config["server"] = map[string]interface{}{
+        "http": map[string]interface{}{"host": "localhost", "port": 8080},
+    }
+    c.SetDefault("server.http.timeout", "30s")
+    c.RegisterAlias("host", "server.http.host")
+    c.AddSource("bench", func() (map[string]interface{}, error) {
+        return map[string]interface{}{"other": "value"}, nil
+    }, 0)
+
+    if got := c.GetString("host"); got != "localhost" {
+        b.Fatalf("GetString(host) = %q, want localhost", got)
+    }
+
+    return c
+}
+
+func BenchmarkGetString(b *testing.B) {
+    c := benchConfig(b)
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.GetString("server.http.host")
+    }
+}
+
+// Resolves the key afresh on every read, as before resolved keys were cached.
+func BenchmarkGetStringUncached(b *testing.B) {
+    c := benchConfig(b)
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.invalidate()
+        c.GetString("server.http.host")
+    }
+}
+
+func BenchmarkGetStringAlias(b *testing.B) {
+    c := benchConfig(b)
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.GetString("HOST")
+    }
+}
+
+func BenchmarkGetIntParallel(b *testing.B) {
+    c := benchConfig(b)
+    b.ResetTimer()
+
+    b.RunParallel(func(pb *testing.PB) {
+        for pb.Next() {
+            c.GetInt("server.http.port")
+        }
+    })
+}
+
+// Reads a key between changes, so every read misses the cache.
+func BenchmarkGetAfterSet(b *testing.B) {
+    c := benchConfig(b)
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.Set("server.http.port", i)
+        c.GetInt("server.http.port")
+    }
+}
+
+// Feature keys probed with IsSet, most of them set in no layer.
+var featureKeys = func() []string {
+    keys := make([]string, 32)
+    for i := range keys {
+        keys[i] = fmt.Sprintf("features.flag%d.enabled", i)
+    }
+    return keys
+}()
+
+func BenchmarkIsSetMiss(b *testing.B) {
+    c := benchConfig(b)
+    c.SetDefault(featureKeys[0], true)
+    b.ReportAllocs()
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        for _, key := range featureKeys {
+            c.IsSet(key)
+        }
+    }
+}
+
+// Searches every layer for every probed key, as before misses were cached.
+func BenchmarkIsSetMissUncached(b *testing.B) {
+    c := benchConfig(b)
+    c.SetDefault(featureKeys[0], true)
+    b.ReportAllocs()
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.invalidate()
+        for _, key := range featureKeys {
+            c.IsSet(key)
+        }
+    }
+}
+
+func TestCachedMissesBounded(t *testing.T) {
+    c := New()
+    c.SetDefault("set", true)
+
+    for i := 0; i < 2*maxCachedMisses; i++ {
+        if c.IsSet(fmt.Sprintf("probe%d", i)) {
+            t.Fatalf("IsSet(probe%d) = true", i)
+        }
+    }
+    if !c.IsSet("set") {
+        t.Error("IsSet(set) = false")
+    }
+
+    cached := 0
+    c.keyCache.Load().keys.Range(func(_, _ interface{}) bool {
+        cached++
+        return true
+    })
+    if cached != maxCachedMisses+1 {
+        t.Errorf("%d keys cached, want %d", cached, maxCachedMisses+1)
+    }
+}
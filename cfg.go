@@ -4,69 +4,292 @@ package cfg
 
 import (
     "bytes"
+    "context"
+    "crypto/tls"
+    "errors"
     "fmt"
     "io"
-    "io/ioutil"
+    "io/fs"
+    "log/slog"
+    "net/http"
+    "os"
     "path/filepath"
     "reflect"
+    "sort"
+    "strconv"
     "strings"
+    "sync"
+    "sync/atomic"
     "time"
 
-    "github.com/kr/pretty"
     "github.com/mitchellh/mapstructure"
     "github.com/spf13/cast"
-    jww "github.com/spf13/jwalterweatherman"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
 )
 
 var c *Config
 
 func init() {
     c = New()
-    jww.UseTempLogFile("cfg")
 }
 
+// Config holds a layered configuration. Getters, Set, SetDefault, RegisterAlias and
+// the calls that read or merge config, sources and layers are safe for concurrent use.
+// The remaining setters are meant to be called while setting the Config up.
+//
+// A Config needs no config file: it can be populated with SetDefault, Set and
+// MergeConfigMap alone, see SetConfigFileOptional for a file that may be missing.
 type Config struct {
     // Delimiter used to access sub keys in a single command
     keyDelm string
 
-    // Name of file to look for in paths
-    configName string
+    // Keeps keys as written instead of lowercasing them
+    caseSensitive bool
+
+    // Turns every key into snake_case, and the spellings config files used for the
+    // elements turned, see SetKeyNormalization
+    keyNormalization bool
+    keySpellings     sync.Map
+
+    // Name of file to look for in paths, the first of configNames when several are
+    configName  string
+    configNames []string
     configFile string
     configType string
 
-    // List of to search for files
-    configPaths []string
+    // Whether a missing config file is not an error
+    configOptional bool
+
+    // List of to search for files, and how many levels of subdirectories are searched
+    // beneath those added with Recursive
+    configPaths      []string
+    configPathDepths map[string]int
+
+    // Whether the config file found is replaced by the file its symlinks lead to
+    resolveSymlinks bool
+
+    // Whether the working directory and its parents are searched first, and the
+    // directories the search stops at, see SetSearchUp
+    searchUp    bool
+    searchStops []string
+
+    // Drop-in directories merged over the config file
+    configDirs []string
+
+    // Profiles whose overlay files are merged over the config file, and the
+    // environment variable that can select them instead
+    profiles   []string
+    profileEnv string
+
+    // Role and host whose overlay files are merged over the profiles, see SetRole
+    // and SetHostOverlay
+    role        string
+    roleEnv     string
+    hostOverlay bool
+
+    // Guards config, defaults, overrides, aliases and deprecations. The
+    // config layer is replaced as a whole rather than changed in place.
+    mu sync.RWMutex
+
+    // Serializes the read-modify-write of the config layer done by merges
+    updateMu sync.Mutex
 
     config    map[string]interface{}
     defaults  map[string]interface{}
+
+    // Where the keys of the config layer were read from, replaced along with it
+    configOrigins map[string]fileOrigin
+
+    // The config layer as read and merged, without the drop-ins, profile overlays and
+    // extended files merged over it, which is what WriteConfig writes back. Replaced
+    // along with the config layer.
+    fileConfig map[string]interface{}
+
     overrides map[string]interface{}
+    computed  map[string]interface{}
     aliases   map[string]string
 
+    // Filesystem config files are read from, the OS filesystem when nil
+    fsys fs.FS
+
+    // Size config files are limited to, see SetMaxConfigSize
+    maxConfigSize int64
+
+    // Limits on the shape of config documents, see SetMaxConfigDepth
+    maxConfigDepth    int
+    maxConfigKeys     int
+    maxAliasExpansion int
+
+    // Whether config file reads and writes take an advisory lock, see SetFileLocking
+    fileLocking bool
+
+    // Settings used when the config file is an http(s) URL
+    httpHeaders http.Header
+    httpTimeout time.Duration
+    httpTLS     *tls.Config
+
+    // Client built from the HTTP settings and the last successful response for each URL
+    // fetched, config files and their sidecars alike, guarded by httpMu
+    httpMu     sync.Mutex
+    httpClient *http.Client
+    httpCache  map[string]httpCache
+
+    // Documents served by remote providers
+    remotes []*remoteConfig
+
+    // Checks run against a candidate config before it replaces the current one
+    validators []func(Snapshot) error
+
+    // Keys declared with Require
+    required []string
+
+    // Replaces the validate tag checks run after unmarshalling
+    structValidator func(interface{}) error
+
+    // Renamed keys registered with Deprecate
+    deprecations []*deprecation
+    onDeprecated []func(Deprecation)
+
+    // Structs kept up to date by Bind
+    bindings []*binding
+    bindMu   sync.Mutex
+
+    // Struct tag Unmarshal reads field names from
+    tagName string
+
+    // Hooks converting values for Unmarshal and SetTypeByDefaultValue
+    decodeHooks []mapstructure.DecodeHookFunc
+
+    // Parsing of timestamps by GetTime
+    timeLayouts  []string
+    timeLocation *time.Location
+
+    // Expressions compiled by GetRegexp, by key
+    regexps regexpCache
+
+    // Unknown key detection against the defaults
+    strictKeys    bool
+    allowedKeys   []string
+    onUnknownKeys []func([]string)
+
+    // Window over which watched file events are coalesced
+    watchDebounce time.Duration
+
+    // Cancels the running watches, which signal watchWG as they exit
+    watchCancels []context.CancelFunc
+    watchMu      sync.Mutex
+    watchWG      sync.WaitGroup
+
+    // Handlers run after the watched config is reloaded
+    onChange []func(ChangeEvent)
+    changeMu sync.Mutex
+
+    // Named layers fetched from external sources, in the order they were added
+    sources  []*source
+    sourceMu sync.RWMutex
+
+    // Layers added with AddLayer, also guarded by sourceMu
+    customLayers []*NamedLayer
+    layerSeq     int
+
+    // Merge options used when a merge function is given none
+    mergeOpts []MergeOption
+
+    // Set by Freeze
+    frozen atomic.Bool
+
+    // Keys read through Get, the getters and Unmarshal, see UnusedKeys
+    reads sync.Map
+
+    // Key patterns marked with MarkSecret, guarded by mu
+    secrets []string
+
+    // Changes recorded, the last auditMax of them, see SetAuditLog
+    auditMu  sync.Mutex
+    audit    []AuditEntry
+    auditMax int
+
+    // Per key *accessStats, kept while access statistics are on
+    accessStatsOn atomic.Bool
+    accessStats   sync.Map
+
+    // Counters of config operations, see Metrics
+    metrics metrics
+
+    // Pinned checksums by location, whether checksum files are required, and the
+    // checksum of the config file last read
+    checksums      map[string]string
+    checksumFile   bool
+    configChecksum string
+
+    // Keys config files must be signed with, none when signatures are not required
+    verifiers []signatureVerifier
+
+    // Resolvers of references by scheme, see RegisterResolver
+    resolverMu sync.RWMutex
+    resolvers  map[string]Resolver
+
+    // Ciphers of encrypted config files by extension
+    ciphers map[string]FileCipher
+
+    // Tracer of config loads, nil while tracing is off
+    tracer atomic.Pointer[trace.Tracer]
+
+    // Snapshot of the keys resolved since the last change, read without locking
+    keyCache atomic.Pointer[keyCache]
+
+    // Receives log messages, nothing is logged when nil
+    logger Logger
+
+    // File opened by SetLogFile, closed once the logger is replaced
+    logFile *os.File
+
     verbose        bool
     typeByDefValue bool
+    strictTypes    bool
+    interpolate    bool
+    templating     bool
+    includeFiles   bool
+    extendsFiles   bool
+    included       includeCache
+
+    // Whether durations may be given in days, weeks or ISO 8601, see SetExtendedDurations
+    extendedDurations bool
 }
 
-// Sets log file to the passed in parameter. Currently assumes the file is writable.
+// Sets log file to the passed in parameter, replacing the logger set with SetLogger and
+// closing the log file set before. Messages from debug on are written to it when
+// verbose, see SetVerbosity, and from warnings on otherwise.
 func SetLogFile(s string) { c.SetLogFile(s) }
 func (c *Config) SetLogFile(s string) {
+    f, err := os.OpenFile(s, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+    if err != nil {
+        c.logError("Cannot open log file", s, ":", err)
+        return
+    }
+
+    min := levelWarn
     if c.verbose {
-        jww.SetLogThreshold(jww.LevelDebug)
-        jww.SetStdoutThreshold(jww.LevelInfo)
-    } else {
-        jww.SetLogThreshold(jww.LevelWarn)
-        jww.SetStdoutThreshold(jww.LevelError)
+        min = levelDebug
+    }
+    c.closeLogFile()
+    c.logger = newWriterLogger(f, min)
+    c.logFile = f
+}
+
+// closeLogFile closes the file opened by SetLogFile, if any.
+func (c *Config) closeLogFile() {
+    if c.logFile == nil {
+        return
     }
-    jww.SetLogFile(s)
+    c.logFile.Close()
+    c.logFile = nil
 }
 
 func SetVerbosity(v bool) { c.SetVerbosity(v) }
 func (c *Config) SetVerbosity(v bool) {
-    if v != true && v != false {
-        jww.ERROR.Println("Incorrect value for verbosity provided.")
-        c.verbose = false
-    } else {
-        c.verbose = v
-    }
+    c.verbose = v
 }
 
 // Denotes finding an unsurpported config
@@ -87,20 +310,112 @@ func (fnfe ConfigFileNotFoundError) Error() string {
     return fmt.Sprintf("Config File %q Not Found in %q", fnfe.name, fnfe.locations)
 }
 
+// Denotes settings that do not match any field of the struct being unmarshalled into.
+type UnknownKeysError []string
+
+// Returns the error listing every unknown key.
+func (keys UnknownKeysError) Error() string {
+    return fmt.Sprintf("Unknown config keys: %s", strings.Join(keys, ", "))
+}
+
+// Denotes a value that cannot be converted to the type of the key's default.
+type TypeMismatchError struct {
+    Key string
+
+    // Type of the default value.
+    Expected string
+
+    // Name of the layer the value was found in.
+    Layer string
+
+    Value interface{}
+}
+
+// Returns the formatted type mismatch error.
+func (tme TypeMismatchError) Error() string {
+    return fmt.Sprintf("Value %#v of %q from %s is not a %s", tme.Value, tme.Key, tme.Layer, tme.Expected)
+}
+
 // Universally supported extensions.
-var SupportedExts []string = []string{"toml", "yaml", "yml"}
+var SupportedExts []string = []string{"json", "toml", "yaml", "yml"}
+
+// Configures a Config as it is created by New.
+type Option func(*Config)
+
+// Sets the delimiter used to access sub keys, for configs whose keys contain dots.
+func WithKeyDelimiter(delim string) Option {
+    return func(c *Config) {
+        c.SetKeyDelim(delim)
+    }
+}
+
+// Sets the name of the config file searched for, without its extension, see
+// SetConfigName.
+func WithConfigName(name string) Option {
+    return func(c *Config) {
+        c.SetConfigName(name)
+    }
+}
+
+// Sets the type of the config file, see SetConfigType.
+func WithConfigType(configType string) Option {
+    return func(c *Config) {
+        c.SetConfigType(configType)
+    }
+}
 
-// Returns a properly initialized Config instance
-func New() *Config {
+// Adds paths to search for the config file in, in order, see AddConfigPath.
+func WithConfigPaths(paths ...string) Option {
+    return func(c *Config) {
+        for _, p := range paths {
+            c.AddConfigPath(p)
+        }
+    }
+}
+
+// Sets the logger messages are sent to, see SetLogger.
+func WithLogger(l Logger) Option {
+    return func(c *Config) {
+        c.SetLogger(l)
+    }
+}
+
+// Sets the defaults of the keys of defaults, see SetDefault. Maps are kept as the
+// defaults of their parent keys, so nested keys are found beneath them.
+func WithDefaults(defaults map[string]interface{}) Option {
+    return func(c *Config) {
+        for key, value := range defaults {
+            c.SetDefault(key, value)
+        }
+    }
+}
+
+// Returns a properly initialized Config instance, configured by opts.
+func New(opts ...Option) *Config {
     c := new(Config)
     c.keyDelm = "."
     c.configName = "config"
     c.config = make(map[string]interface{})
+    c.fileConfig = make(map[string]interface{})
     c.defaults = make(map[string]interface{})
     c.overrides = make(map[string]interface{})
+    c.computed = make(map[string]interface{})
     c.aliases = make(map[string]string)
+    c.ciphers = make(map[string]FileCipher)
+    c.resolvers = make(map[string]Resolver)
+    c.checksums = make(map[string]string)
+    c.httpHeaders = make(http.Header)
+    c.httpTimeout = 30 * time.Second
+    c.maxConfigSize = DefaultMaxConfigSize
+    c.maxConfigDepth = DefaultMaxConfigDepth
+    c.maxAliasExpansion = DefaultMaxYAMLAliasExpansion
     c.typeByDefValue = false
     c.verbose = false
+    c.invalidate()
+
+    for _, opt := range opts {
+        opt(c)
+    }
 
     return c
 }
@@ -108,10 +423,12 @@ func New() *Config {
 func Reset() {
     c = New()
 
-    SupportedExts = []string{"toml", "yaml", "yml"}
+    SupportedExts = []string{"json", "toml", "yaml", "yml"}
 }
 
-// Explicitly sets the config file to be used.
+// Explicitly sets the config file to be used. "-" reads the config from standard input,
+// its type must then be set with SetConfigType. Standard input can only be read once,
+// so the config cannot be reloaded from it.
 func SetConfigFile(s string) { c.SetConfigFile(s) }
 func (c *Config) SetConfigFile(s string) {
     if s != "" {
@@ -119,12 +436,55 @@ func (c *Config) SetConfigFile(s string) {
     }
 }
 
+// Sets the delimiter used to access sub keys in a single command, "." by default. Set it
+// before reading any config or registering aliases.
+func SetKeyDelim(delim string) { c.SetKeyDelim(delim) }
+func (c *Config) SetKeyDelim(delim string) {
+    if delim != "" {
+        c.keyDelm = delim
+        c.invalidate()
+    }
+}
+
 // Explicitly sets the config name to be used.
 func SetConfigName(s string) { c.SetConfigName(s) }
 func (c *Config) SetConfigName(s string) {
     if s != "" {
         c.configName = s
+        c.configNames = nil
+    }
+}
+
+// Sets several config names to look for, replacing the name set with SetConfigName.
+//
+// Each config path is searched for every name in the order given, and within a name for
+// every extension in the order of SupportedExts, the first file found being used. So
+// with SetConfigNames("myapp", "config") a myapp.toml is preferred to a config.yaml in
+// the same directory, while a config.yaml in an earlier config path is preferred to both.
+//
+// A name may be a glob pattern, such as "myapp*" or "myapp-*.yaml", matching files of
+// any supported extension when it has none. Its matches are tried in lexical order.
+func SetConfigNames(names ...string) { c.SetConfigNames(names...) }
+func (c *Config) SetConfigNames(names ...string) {
+    var set []string
+    for _, name := range names {
+        if name != "" {
+            set = append(set, name)
+        }
+    }
+
+    if len(set) > 0 {
+        c.configName = set[0]
+        c.configNames = set
+    }
+}
+
+// getConfigNames returns the config names to look for.
+func (c *Config) getConfigNames() []string {
+    if len(c.configNames) > 0 {
+        return c.configNames
     }
+    return []string{c.configName}
 }
 
 // Explicitly sets the config file to be used.
@@ -140,7 +500,11 @@ func (c *Config) getConfigType() string {
         return c.configType
     }
 
-    cf := c.getConfigFile()
+    cf, _ := c.getConfigFile()
+    if isURL(cf) {
+        cf = urlPath(cf)
+    }
+    cf, _ = c.cipherFor(cf)
     ext := filepath.Ext(cf)
 
     if len(ext) > 1 {
@@ -150,46 +514,113 @@ func (c *Config) getConfigType() string {
     }
 }
 
-func (c *Config) getConfigFile() string {
+// getConfigFile returns the config file set with SetConfigFile, or else the one found
+// in the config paths, failing with a ConfigFileNotFoundError if there is none.
+func (c *Config) getConfigFile() (string, error) {
     if c.configFile != "" {
-        return c.configFile
+        return c.configFile, nil
     }
 
     cf, err := c.findConfigFile()
     if err != nil {
-        return ""
+        return "", err
     }
 
     c.configFile = cf
-    return c.getConfigFile()
+    return cf, nil
 }
 
 func (c *Config) searchInPath(in string) (filename string) {
-    jww.DEBUG.Println("Searching for config in ", in)
+    c.logDebug("Searching for config in ", in)
+    for _, name := range c.getConfigNames() {
+        if isGlob(name) {
+            filename = c.globInPath(in, name)
+        } else {
+            filename = c.searchName(in, name)
+        }
+        if filename != "" {
+            return filename
+        }
+    }
+
+    return ""
+}
+
+// searchName returns the config file called name in the directory in, trying every
+// supported extension.
+func (c *Config) searchName(in, name string) string {
     for _, ext := range SupportedExts {
-        jww.DEBUG.Println("Checking for", filepath.Join(in, c.configName+"."+ext))
-        if b, _ := exists(filepath.Join(in, c.configName+"."+ext)); b {
-            jww.DEBUG.Println("Found: ", filepath.Join(in, c.configName+"."+ext))
-            return filepath.Join(in, c.configName+"."+ext)
+        c.logDebug("Checking for", filepath.Join(in, name+"."+ext))
+        if b, _ := c.fileExists(filepath.Join(in, name+"."+ext)); b {
+            c.logDebug("Found: ", filepath.Join(in, name+"."+ext))
+            return filepath.Join(in, name+"."+ext)
+        }
+
+        for cipherExt := range c.ciphers {
+            file := filepath.Join(in, name+"."+ext+"."+cipherExt)
+            if b, _ := c.fileExists(file); b {
+                c.logDebug("Found: ", file)
+                return file
+            }
+        }
+    }
+
+    return ""
+}
+
+// globInPath returns the first config file in the directory in matching the glob
+// pattern, which is tried with every supported extension unless it has one.
+func (c *Config) globInPath(in, pattern string) string {
+    patterns := []string{pattern}
+    if plain, _ := c.cipherFor(pattern); !stringInSlice(strings.TrimPrefix(filepath.Ext(plain), "."), SupportedExts) {
+        patterns = patterns[:0]
+        for _, ext := range SupportedExts {
+            patterns = append(patterns, pattern+"."+ext)
+        }
+    }
+
+    for _, p := range patterns {
+        c.logDebug("Checking for", filepath.Join(in, p))
+        for _, file := range c.glob(filepath.Join(in, p)) {
+            plain, _ := c.cipherFor(file)
+            ext := strings.TrimPrefix(filepath.Ext(plain), ".")
+            if stringInSlice(ext, SupportedExts) && c.isFile(file) {
+                c.logDebug("Found: ", file)
+                return file
+            }
         }
     }
 
     return ""
 }
 
+// isGlob reports whether name holds glob metacharacters.
+func isGlob(name string) bool {
+    return strings.ContainsAny(name, "*?[")
+}
+
 // search all configPaths for any config file.
 // Returns the first path that exists (and is a config file)
 func (c *Config) findConfigFile() (string, error) {
 
-    jww.INFO.Println("Searching for config in ", c.configPaths)
+    if c.searchUp {
+        if file := c.searchUpward(); file != "" {
+            return c.resolveFound(file), nil
+        }
+    }
+
+    c.logInfo("Searching for config in ", c.configPaths)
 
     for _, cp := range c.configPaths {
-        file := c.searchInPath(cp)
+        file, err := c.searchRecursive(cp, c.configPathDepths[cp])
+        if err != nil {
+            return "", err
+        }
         if file != "" {
-            return file, nil
+            return c.resolveFound(file), nil
         }
     }
-    return "", ConfigFileNotFoundError{c.configName, fmt.Sprintf("%s", c.configPaths)}
+    return "", ConfigFileNotFoundError{strings.Join(c.getConfigNames(), ", "), fmt.Sprintf("%s", c.configPaths)}
 }
 
 // Return the file used to populate the config.
@@ -201,128 +632,303 @@ func (c *Config) ConfigFileUsed() string { return c.configFile }
 // Function takes a string parameter and adds the path to search to an array. This ordered array
 // Determines the search path for configuration files in order of presedence.
 // This function does NOT check whether the path is valid at the time it is being added.
-func AddConfigPath(s string) { c.AddConfigPath(s) }
-func (c *Config) AddConfigPath(s string) {
+// Options such as Recursive change how the path is searched.
+func AddConfigPath(s string, opts ...ConfigPathOption) { c.AddConfigPath(s, opts...) }
+func (c *Config) AddConfigPath(s string, opts ...ConfigPathOption) {
     if s != "" {
-        inPath := absPathify(s)
-        jww.INFO.Println("adding ", inPath, " to search paths.")
+        inPath := c.absPathify(s)
+        c.logInfo("adding ", inPath, " to search paths.")
         if !stringInSlice(inPath, c.configPaths) {
             c.configPaths = append(c.configPaths, inPath)
         }
+
+        var o configPathOptions
+        for _, opt := range opts {
+            opt(&o)
+        }
+        if o.depth > 0 {
+            if c.configPathDepths == nil {
+                c.configPathDepths = make(map[string]int)
+            }
+            c.configPathDepths[inPath] = o.depth
+        }
     }
 }
 
-func (c *Config) searchMap(s map[string]interface{}, p []string) interface{} {
+// searchMap follows the path p into s, returning the value it leads to and whether it
+// exists, which it does for a key explicitly set to null.
+func (c *Config) searchMap(s map[string]interface{}, p []string) (interface{}, bool) {
     if len(p) == 0 {
-        return s
+        return s, true
     }
 
     if next, ok := s[p[0]]; ok {
-        switch next.(type) {
-        case map[interface{}]interface{}:
-            return c.searchMap(cast.ToStringMap(next), p[1:])
-        case map[string]interface{}:
-            return c.searchMap(next.(map[string]interface{}), p[1:])
-        default:
-            return next
-        }
+        return c.searchValue(next, p[1:])
     } else {
-        return nil
+        return nil, false
+    }
+}
+
+// searchValue follows the path p into v, through maps by key and through slices by
+// index, so servers.0.host addresses the host of the first server.
+func (c *Config) searchValue(v interface{}, p []string) (interface{}, bool) {
+    if len(p) == 0 {
+        return v, true
+    }
+
+    switch val := v.(type) {
+    case map[interface{}]interface{}:
+        return c.searchMap(cast.ToStringMap(val), p)
+    case map[string]interface{}:
+        return c.searchMap(val, p)
+    }
+
+    if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+        i, err := strconv.Atoi(p[0])
+        if err != nil || i < 0 || i >= rv.Len() {
+            return nil, false
+        }
+        return c.searchValue(rv.Index(i).Interface(), p[1:])
     }
+
+    return nil, false
 }
 
 func Get(key string) interface{} { return c.Get(key) }
 func (c *Config) Get(key string) interface{} {
-    p := strings.Split(key, c.keyDelm)
-
-    lcaseKey := strings.ToLower(key)
-    val := c.find(lcaseKey)
+    val, _ := c.get(key)
+    return val
+}
 
+// Like Get, but returns a KeyNotFoundError if the key is not set and, when strict typing
+// is on, a TypeMismatchError if the value cannot be converted to the type of the key's
+// default.
+func GetE(key string) (interface{}, error) { return c.GetE(key) }
+func (c *Config) GetE(key string) (interface{}, error) {
+    val, err := c.get(key)
     if val == nil {
-        source := c.find(p[0])
-        if source != nil {
-            if reflect.TypeOf(source).Kind() == reflect.Map {
-                val = c.searchMap(cast.ToStringMap(source), p[1:])
-            }
+        // Set to a reference that cannot be resolved or interpolated
+        if err != nil {
+            return nil, err
         }
+        return nil, KeyNotFoundError(c.normalizeKey(key))
+    }
+    if !c.strictTypes {
+        return val, nil
     }
 
-    if val == nil {
-        return nil
+    return val, err
+}
+
+// get returns the value of key converted to the type it is coerced to, along with any
+// conversion error. The zero value of that type is returned when conversion fails.
+func (c *Config) get(key string) (interface{}, error) {
+    return c.lookup(key, true)
+}
+
+// lookup is get, noting key as read only when record is set.
+func (c *Config) lookup(key string, record bool) (interface{}, error) {
+    rk := c.resolve(key)
+    if record {
+        c.metrics.reads.Add(1)
+        c.recordRead(rk.realKey, rk.layer.name)
+    }
+
+    lcaseKey, realKey, val, l, valType := rk.lcaseKey, rk.realKey, rk.val, rk.layer, rk.valType
+
+    // Warn outside the lock, handlers may read the config.
+    for _, d := range []*deprecation{rk.used, rk.found} {
+        if d != nil {
+            c.warnDeprecated(d)
+        }
     }
 
-    var valType interface{}
-    if !c.typeByDefValue {
+    val, l = c.compute(realKey, val, l)
+    if _, ok := valType.(computedValue); ok {
         valType = val
-    } else {
-        defVal, defExists := c.defaults[lcaseKey]
-        if defExists {
-            valType = defVal
-        } else {
-            valType = val
+    }
+    if val == nil {
+        return nil, nil
+    }
+
+    if s, ok := val.(string); ok && c.mayExpand(s) {
+        resolved, err := c.expandValue(realKey, s, nil)
+        if err != nil {
+            c.logError(err)
+            return nil, err
         }
+        val = resolved
+        if valType == s {
+            valType = resolved
+        }
+    }
+
+    if isConverted(val, valType) {
+        return val, nil
     }
 
+    var (
+        out interface{}
+        err error
+    )
+
     switch valType.(type) {
     case bool:
-        return cast.ToBool(val)
+        out, err = cast.ToBoolE(val)
     case string:
-        return cast.ToString(val)
-    case int64, int32, int16, int8, int:
-        return cast.ToInt(val)
+        out, err = cast.ToStringE(val)
+    case int64:
+        out, err = cast.ToInt64E(val)
+    case int32:
+        out, err = cast.ToInt32E(val)
+    case int16, int8, int:
+        out, err = cast.ToIntE(val)
+    case uint64:
+        out, err = cast.ToUint64E(val)
+    case uint32:
+        out, err = cast.ToUint32E(val)
+    case uint16, uint8, uint:
+        out, err = cast.ToUintE(val)
     case float64, float32:
-        return cast.ToFloat64(val)
+        out, err = cast.ToFloat64E(val)
     case time.Time:
-        return cast.ToTime(val)
+        out, err = c.toTimeE(val)
     case time.Duration:
-        return cast.ToDuration(val)
+        out, err = c.toDurationE(val)
     case []string:
-        return cast.ToStringSlice(val)
+        out, err = cast.ToStringSliceE(val)
+    case []int:
+        out, err = cast.ToIntSliceE(val)
+    case []float64:
+        out, err = toFloat64SliceE(val)
+    case []bool:
+        out, err = cast.ToBoolSliceE(val)
+    case []time.Duration:
+        out, err = toDurationSliceE(val, c.toDurationE)
+    case []interface{}:
+        out, err = cast.ToSliceE(val)
+    case map[string]interface{}:
+        out, err = cast.ToStringMapE(val)
+    case map[string]string:
+        out, err = cast.ToStringMapStringE(val)
+    case map[string][]string:
+        out, err = cast.ToStringMapStringSliceE(val)
+    case map[string]int:
+        out, err = cast.ToStringMapIntE(val)
+    case map[string]int64:
+        out, err = cast.ToStringMapInt64E(val)
+    case map[string]bool:
+        out, err = cast.ToStringMapBoolE(val)
+    default:
+        if len(c.decodeHooks) == 0 {
+            return val, nil
+        }
+        out, err = c.decodeLike(val, valType)
     }
 
-    return val
+    if err != nil {
+        return out, TypeMismatchError{
+            Key:      lcaseKey,
+            Expected: reflect.TypeOf(valType).String(),
+            Layer:    l.name,
+            Value:    val,
+        }
+    }
+
+    return out, nil
 }
 
 // Returns the value associated with the key as a string
 func GetString(key string) string { return c.GetString(key) }
 func (c *Config) GetString(key string) string {
-    return cast.ToString(c.Get(key))
+    return toString(c.Get(key))
 }
 
 // Returns the value associated with the key asa boolean
 func GetBool(key string) bool { return c.GetBool(key) }
 func (c *Config) GetBool(key string) bool {
-    return cast.ToBool(c.Get(key))
+    return toBool(c.Get(key))
 }
 
 // Returns the value associated with the key as an integer
 func GetInt(key string) int { return c.GetInt(key) }
 func (c *Config) GetInt(key string) int {
-    return cast.ToInt(c.Get(key))
+    return toInt(c.Get(key))
+}
+
+// Returns the value associated with the key as a 32-bit integer
+func GetInt32(key string) int32 { return c.GetInt32(key) }
+func (c *Config) GetInt32(key string) int32 {
+    return toInt32(c.Get(key))
+}
+
+// Returns the value associated with the key as a 64-bit integer
+func GetInt64(key string) int64 { return c.GetInt64(key) }
+func (c *Config) GetInt64(key string) int64 {
+    return toInt64(c.Get(key))
+}
+
+// Returns the value associated with the key as an unsigned integer
+func GetUint(key string) uint { return c.GetUint(key) }
+func (c *Config) GetUint(key string) uint {
+    return toUint(c.Get(key))
+}
+
+// Returns the value associated with the key as a 32-bit unsigned integer
+func GetUint32(key string) uint32 { return c.GetUint32(key) }
+func (c *Config) GetUint32(key string) uint32 {
+    return toUint32(c.Get(key))
+}
+
+// Returns the value associated with the key as a 64-bit unsigned integer
+func GetUint64(key string) uint64 { return c.GetUint64(key) }
+func (c *Config) GetUint64(key string) uint64 {
+    return toUint64(c.Get(key))
 }
 
 // Returns the value associated with the key as a float64
 func GetFloat64(key string) float64 { return c.GetFloat64(key) }
 func (c *Config) GetFloat64(key string) float64 {
-    return cast.ToFloat64(c.Get(key))
+    return toFloat64(c.Get(key))
 }
 
 // Returns the value associated with the key as time
 func GetTime(key string) time.Time { return c.GetTime(key) }
 func (c *Config) GetTime(key string) time.Time {
-    return cast.ToTime(c.Get(key))
+    t, _ := c.toTimeE(c.Get(key))
+    return t
 }
 
 // Returns the value associated with the key as a duration
 func GetDuration(key string) time.Duration { return c.GetDuration(key) }
 func (c *Config) GetDuration(key string) time.Duration {
-    return cast.ToDuration(c.Get(key))
+    d, _ := c.toDurationE(c.Get(key))
+    return d
 }
 
 // Returns the value associated with the key as a slice of strings
 func GetStringSlice(key string) []string { return c.GetStringSlice(key) }
 func (c *Config) GetStringSlice(key string) []string {
-    return cast.ToStringSlice(c.Get(key))
+    return toStringSlice(c.Get(key))
+}
+
+// Returns the value associated with the key as a slice of integers
+func GetIntSlice(key string) []int { return c.GetIntSlice(key) }
+func (c *Config) GetIntSlice(key string) []int {
+    return cast.ToIntSlice(c.Get(key))
+}
+
+// Returns the value associated with the key as a slice of float64s
+func GetFloat64Slice(key string) []float64 { return c.GetFloat64Slice(key) }
+func (c *Config) GetFloat64Slice(key string) []float64 {
+    return toFloat64Slice(c.Get(key))
+}
+
+// Returns the value associated with the key as a slice of durations
+func GetDurationSlice(key string) []time.Duration { return c.GetDurationSlice(key) }
+func (c *Config) GetDurationSlice(key string) []time.Duration {
+    d, _ := toDurationSliceE(c.Get(key), c.toDurationE)
+    return d
 }
 
 // Returns the value associated with the key as a map of interfaces
@@ -343,227 +949,1040 @@ func (c *Config) GetStringMapStringSlice(key string) map[string][]string {
     return cast.ToStringMapStringSlice(c.Get(key))
 }
 
-// Returns the size of the value associated with the given key
-// in bytes.
-func GetSizeInBytes(key string) uint { return c.GetSizeInBytes(key) }
-func (c *Config) GetSizeInBytes(key string) uint {
-    sizeStr := cast.ToString(c.Get(key))
-    return parseSizeInBytes(sizeStr)
+// Returns the value associated with the key as a map of integers
+func GetStringMapInt(key string) map[string]int { return c.GetStringMapInt(key) }
+func (c *Config) GetStringMapInt(key string) map[string]int {
+    return cast.ToStringMapInt(c.Get(key))
 }
 
-func UnmarshalKey(key string, rawVal interface{}) error {
-    return c.UnmarshalKey(key, rawVal)
+// Returns the value associated with the key as a map of 64-bit integers
+func GetStringMapInt64(key string) map[string]int64 { return c.GetStringMapInt64(key) }
+func (c *Config) GetStringMapInt64(key string) map[string]int64 {
+    return cast.ToStringMapInt64(c.Get(key))
 }
-func (c *Config) UnmarshalKey(key string, rawVal interface{}) error {
-    return mapstructure.Decode(c.Get(key), rawVal)
+
+// Returns the value associated with the key as a map of booleans
+func GetStringMapBool(key string) map[string]bool { return c.GetStringMapBool(key) }
+func (c *Config) GetStringMapBool(key string) map[string]bool {
+    return cast.ToStringMapBool(c.Get(key))
 }
 
-func Unmarshal(rawVal interface{}) error {
-    return c.Unmarshal(rawVal)
+// Returns the size of the value associated with the given key
+// in bytes, see GetSizeInBytesE for the accepted units.
+func GetSizeInBytes(key string) uint64 { return c.GetSizeInBytes(key) }
+func (c *Config) GetSizeInBytes(key string) uint64 {
+    sizeStr := cast.ToString(c.Get(key))
+    return parseSizeInBytes(sizeStr)
 }
-func (c *Config) Unmarshal(rawVal interface{}) error {
-    err := mapstructure.WeakDecode(c.AllSettings(), rawVal)
 
-    if err != nil {
-        return err
+// Returns a new Config rooted at the map under key, so a component can be handed
+// Sub("database") and read GetString("host") without knowing its prefix.
+//
+// The maps found under key in every layer are merged by precedence into the config
+// layer of the result, which shares nothing with c. Returns nil if key does not hold a map.
+func Sub(key string) *Config { return c.Sub(key) }
+func (c *Config) Sub(key string) *Config {
+    settings, found := c.settingsUnder(key)
+    if !found {
+        return nil
     }
 
-    c.insensitiviseMaps()
-
-    return nil
+    sub := New()
+    sub.keyDelm = c.keyDelm
+    sub.typeByDefValue = c.typeByDefValue
+    sub.strictTypes = c.strictTypes
+    sub.timeLayouts = c.timeLayouts
+    sub.timeLocation = c.timeLocation
+    sub.tagName = c.tagName
+    sub.decodeHooks = c.decodeHooks
+    sub.config = settings
+
+    return sub
 }
 
-func (c *Config) find(key string) interface{} {
-    var val interface{}
-    var exists bool
+// settingsUnder returns the effective nested map under key, merging the maps and dotted
+// keys beneath it in every layer by precedence. found is false if key does not hold a map.
+func (c *Config) settingsUnder(key string) (settings map[string]interface{}, found bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    settings, _, found = c.settingsBeneath(c.normalizeKey(key))
+    return settings, found
+}
 
+// settingsBeneath returns the effective nested map under the normalized key like
+// settingsUnder, along with the layer of highest precedence holding part of it. Caller
+// must hold mu.
+func (c *Config) settingsBeneath(key string) (settings map[string]interface{}, top layer, found bool) {
     key = c.realKey(key)
+    // Values stored under aliases are merged in below those stored under key.
+    names := append(c.aliasNames(key), key)
+    beneath, paths := c.aliasesBeneath(key)
 
-    val, exists = c.overrides[key]
-    if exists {
-        jww.TRACE.Println(key, "found in overrides: ", val)
-        return val
-    }
+    settings = make(map[string]interface{})
 
-    val, exists = c.config[key]
-    if exists {
-        jww.TRACE.Println(key, "found in config: ", val)
-        return val
-    }
+    layers := c.layers()
+    for i := len(layers) - 1; i >= 0; i-- {
+        // Computed values cannot be computed while holding mu.
+        if layers[i].kind == LayerComputed {
+            continue
+        }
+        values := layers[i].values
 
-    if strings.Contains(key, c.keyDelm) {
-        path := strings.Split(key, c.keyDelm)
+        for j, alias := range beneath {
+            if val, exists := c.searchLayer(values, alias); exists {
+                setNested(settings, paths[j], normalizeMaps(val))
+                found, top = true, layers[i]
+            }
+        }
+
+        for _, name := range names {
+            if val, exists := c.searchLayer(values, name); exists {
+                m, isMap := normalizeMaps(val).(map[string]interface{})
+                if !isMap {
+                    // A scalar in a higher layer hides the maps below it.
+                    settings = make(map[string]interface{})
+                    found, top = false, layer{}
+                    continue
+                }
+                mergeMapsWith(settings, m, mergeOptions{})
+                found, top = true, layers[i]
+            }
 
-        source := c.find(path[0])
-        if source != nil {
-            if reflect.TypeOf(source).Kind() == reflect.Map {
-                val := c.searchMap(cast.ToStringMap(source), path[1:])
-                jww.TRACE.Println(key, "Found in nested config: ", val)
-                return val
+            prefix := name + c.keyDelm
+            var dotted []string
+            for k := range values {
+                if strings.HasPrefix(k, prefix) {
+                    dotted = append(dotted, k)
+                }
+            }
+            sort.Strings(dotted)
+            for _, k := range dotted {
+                setNested(settings, strings.Split(strings.TrimPrefix(k, prefix), c.keyDelm), normalizeMaps(values[k]))
+                found, top = true, layers[i]
             }
         }
     }
 
-    val, exists = c.defaults[key]
-    if exists {
-        jww.TRACE.Println(key, "found in defaults: ", val)
-        return val
+    return settings, top, found
+}
+
+// effectiveValue returns the value of key for decoding, the merged map of every layer if
+// it holds a map, see settingsUnder, and the value Get returns otherwise.
+func (c *Config) effectiveValue(key string) interface{} {
+    if settings, found := c.settingsUnder(key); found {
+        c.recordRead(c.resolveKey(key), "")
+        return settings
     }
 
-    return nil
+    return c.Get(key)
 }
 
-// Aliases provide another accessor for the same key.
-// This enables one to change a name without breaking the application
-func RegisterAlias(alias string, key string) { c.RegisterAlias(alias, key) }
-func (c *Config) RegisterAlias(alias string, key string) {
-    c.registerAlias(alias, strings.ToLower(key))
+// Decodes the value of key into rawVal, then checks the validate tags of the result,
+// see SetStructValidator. When key holds a map, the maps and dotted keys beneath it in
+// every layer are merged by precedence first, so defaults and overrides set for single
+// fields are decoded along with the config file.
+func UnmarshalKey(key string, rawVal interface{}) error {
+    return c.UnmarshalKey(key, rawVal)
 }
+func (c *Config) UnmarshalKey(key string, rawVal interface{}) (err error) {
+    _, span := c.startSpan(context.Background(), "cfg.UnmarshalKey", attribute.String("cfg.key", key), targetType(rawVal))
+    defer func() { endSpan(span, err) }()
 
-func (c *Config) registerAlias(alias string, key string) {
-    alias = strings.ToLower(alias)
-    if alias != key && alias != c.realKey(key) {
-        _, exists := c.aliases[alias]
-
-        if !exists {
-            // if we alias something that exists in one of the maps to another
-            // name, we'll never be able to get that value using the original
-            // name, so move the config value to the new realkey.
-            if val, ok := c.config[alias]; ok {
-                delete(c.config, alias)
-                c.config[key] = val
-            }
-            if val, ok := c.defaults[alias]; ok {
-                delete(c.defaults, alias)
-                c.defaults[key] = val
-            }
-            if val, ok := c.overrides[alias]; ok {
-                delete(c.overrides, alias)
-                c.overrides[key] = val
-            }
-            c.aliases[alias] = key
-        }
-    } else {
-        jww.WARN.Println("Creating circular reference alias", alias, key, c.realKey(key))
+    if err := c.decode(c.effectiveValue(key), rawVal, false); err != nil {
+        return err
     }
+
+    return c.validateStruct(rawVal, c.normalizeKey(key))
 }
 
-func IsSet(key string) bool { return c.IsSet(key) }
-func (c *Config) IsSet(key string) bool {
-    t := c.Get(key)
-    return t != nil
+// Decodes all settings into rawVal, then checks the validate tags of the result,
+// see SetStructValidator.
+func Unmarshal(rawVal interface{}) error {
+    return c.Unmarshal(rawVal)
 }
+func (c *Config) Unmarshal(rawVal interface{}) (err error) {
+    _, span := c.startSpan(context.Background(), "cfg.Unmarshal", targetType(rawVal))
+    defer func() { endSpan(span, err) }()
 
-func (c *Config) realKey(key string) string {
-    newkey, exists := c.aliases[key]
-    if exists {
-        jww.DEBUG.Println("Alias", key, "to", newkey)
-        return c.realKey(newkey)
-    } else {
-        return key
+    err = c.decode(c.AllSettingsNested(), rawVal, true)
+
+    if err != nil {
+        return err
     }
+    c.recordFields(rawVal)
+
+    return c.validateStruct(rawVal, "")
 }
 
-func InConfig(key string) bool { return c.InConfig(key) }
-func (c *Config) InConfig(key string) bool {
-    key = c.realKey(key)
+// Like UnmarshalKey, but fails with an UnknownKeysError if the value holds keys that do
+// not match any field of rawVal, so typos in the config file are caught.
+func UnmarshalKeyExact(key string, rawVal interface{}) error {
+    return c.UnmarshalKeyExact(key, rawVal)
+}
+func (c *Config) UnmarshalKeyExact(key string, rawVal interface{}) (err error) {
+    _, span := c.startSpan(context.Background(), "cfg.UnmarshalKeyExact", attribute.String("cfg.key", key), targetType(rawVal))
+    defer func() { endSpan(span, err) }()
+
+    if err := c.decodeExact(c.effectiveValue(key), rawVal, false, c.normalizeKey(key)); err != nil {
+        return err
+    }
+
+    return c.validateStruct(rawVal, c.normalizeKey(key))
+}
+
+// Like Unmarshal, but fails with an UnknownKeysError if any setting does not match a
+// field of rawVal, so typos in the config file are caught.
+func UnmarshalExact(rawVal interface{}) error {
+    return c.UnmarshalExact(rawVal)
+}
+func (c *Config) UnmarshalExact(rawVal interface{}) (err error) {
+    _, span := c.startSpan(context.Background(), "cfg.UnmarshalExact", targetType(rawVal))
+    defer func() { endSpan(span, err) }()
+
+    if err := c.decodeExact(c.AllSettingsNested(), rawVal, true, ""); err != nil {
+        return err
+    }
+    c.recordFields(rawVal)
+
+    return c.validateStruct(rawVal, "")
+}
+
+// decodeExact decodes input into rawVal and reports the keys no field consumed,
+// prefixed with the key input was read from.
+func (c *Config) decodeExact(input interface{}, rawVal interface{}, weak bool, prefix string) error {
+    var md mapstructure.Metadata
+    config := c.decoderConfig(rawVal, weak)
+    config.Metadata = &md
+
+    decoder, err := mapstructure.NewDecoder(config)
+    if err != nil {
+        return err
+    }
+
+    if err := decoder.Decode(input); err != nil {
+        return err
+    }
+
+    if len(md.Unused) == 0 {
+        return nil
+    }
+
+    unknown := make(UnknownKeysError, len(md.Unused))
+    for i, key := range md.Unused {
+        key = c.normalizeKey(key)
+        if prefix != "" {
+            key = prefix + c.keyDelm + key
+        }
+        unknown[i] = key
+    }
+    sort.Strings(unknown)
+
+    return unknown
+}
+
+// find returns the value of key, the layer it was found in and, if it was stored under a
+// deprecated name, the deprecation. Caller must hold mu.
+func (c *Config) find(key string) (interface{}, layer, *deprecation) {
+    key = c.realKey(key)
+    aliases := c.aliasNames(key)
+    oldKeys, deprecations := c.deprecatedNames(key)
+
+    for _, l := range c.layers() {
+        if val, exists := c.searchAliased(l.values, key, aliases); exists {
+            c.logDebug(key, "found in", l.name, ": ", val)
+            return val, l, nil
+        }
+
+        for i, oldKey := range oldKeys {
+            if val, exists := c.searchLayer(l.values, oldKey); exists {
+                c.logDebug(key, "found in", l.name, "as", oldKey, ": ", val)
+                return val, l, deprecations[i]
+            }
+        }
+    }
+
+    return nil, layer{}, nil
+}
+
+// searchLayer looks key up in a single layer, first as a flat key and then as a
+// path into the maps it holds, trying the longest prefix first so maps stored under
+// dotted keys are found too.
+func (c *Config) searchLayer(m map[string]interface{}, key string) (interface{}, bool) {
+    if val, exists := m[key]; exists {
+        return val, true
+    }
+
+    if strings.Contains(key, c.keyDelm) {
+        path := strings.Split(key, c.keyDelm)
+
+        for i := len(path) - 1; i > 0; i-- {
+            source, exists := m[strings.Join(path[:i], c.keyDelm)]
+            if exists && source != nil {
+                if val, exists := c.searchValue(source, path[i:]); exists {
+                    return val, true
+                }
+            }
+        }
+    }
+
+    return nil, false
+}
+
+// Returns whether key is set, explicitly set to null included, see Has.
+func IsSet(key string) bool { return c.IsSet(key) }
+func (c *Config) IsSet(key string) bool {
+    return c.Has(key)
+}
+
+// Returns whether any layer holds key, even explicitly set to null, without reading or
+// converting its value.
+func Has(key string) bool { return c.Has(key) }
+func (c *Config) Has(key string) bool {
+    return c.resolve(key).layer.kind != ""
+}
+
+// resolveKey normalizes key and resolves it with realKey.
+func (c *Config) resolveKey(key string) string {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    return c.realKey(c.normalizeKey(key))
+}
+
+// realKey resolves aliases and deprecated names of key. Caller must hold mu.
+func (c *Config) realKey(key string) string {
+    // An alias of a parent path applies to everything beneath it.
+    if newkey, exists := c.aliasTarget(key); exists {
+        c.logDebug("Alias", key, "to", newkey)
+        return c.realKey(newkey)
+    }
+
+    if newkey, d := c.undeprecate(key); d != nil {
+        return c.realKey(newkey)
+    }
+
+    return key
+}
+
+func InConfig(key string) bool { return c.InConfig(key) }
+func (c *Config) InConfig(key string) bool {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
 
-    _, exists := c.config[key]
+    key = c.realKey(c.normalizeKey(key))
+    _, exists := c.searchAliased(c.config, key, c.aliasNames(key))
     return exists
 }
 
 func SetDefault(key string, value interface{}) { c.SetDefault(key, value) }
 func (c *Config) SetDefault(key string, value interface{}) {
-    key = c.realKey(strings.ToLower(key))
+    c.mustNotBeFrozen()
+
+    value = c.normalizeValue(value)
+    auditing := c.auditing()
+
+    var old interface{}
+
+    c.mu.Lock()
+    key = c.realKey(c.normalizeKey(key))
+    if auditing {
+        old, _ = c.searchLayer(c.defaults, key)
+    }
     c.defaults[key] = value
+    c.invalidate()
+    c.mu.Unlock()
+
+    if auditing {
+        c.recordChange(AuditSetDefault, key, old, value)
+    }
 }
 
 func Set(key string, value interface{}) { c.Set(key, value) }
 func (c *Config) Set(key string, value interface{}) {
-    key = c.realKey(strings.ToLower(key))
-    c.overrides[key] = value
+    if err := c.set(key, value); err != nil {
+        panic(err)
+    }
+}
+
+// set is Set returning ErrFrozen rather than panicking. Frozen is checked under the
+// same lock as the write, so a concurrent Freeze cannot slip in between.
+func (c *Config) set(key string, value interface{}) error {
+    value = c.normalizeValue(value)
+
+    auditing := c.auditing()
+
+    c.mu.Lock()
+    if err := c.checkFrozen(); err != nil {
+        c.mu.Unlock()
+        return err
+    }
+    key, shadowed, old := c.setIn(c.overrides, key, value, auditing)
+    c.invalidate()
+    c.mu.Unlock()
+
+    c.logEvent(slog.LevelDebug, "key_overridden", slog.String("key", key), slog.String("shadowed", string(shadowed.kind)))
+    if auditing {
+        c.recordChange(AuditSet, key, old, value)
+    }
+
+    return nil
+}
+
+// setIn stores the normalized value under key in values, the overrides or a custom
+// layer, and returns the real key, the layer it shadowed when logging and the value it
+// replaced when auditing. Caller must hold mu.
+func (c *Config) setIn(values map[string]interface{}, key string, value interface{}, auditing bool) (string, layer, interface{}) {
+    var (
+        shadowed layer
+        old      interface{}
+    )
+
+    key = c.realKey(c.normalizeKey(key))
+    if c.logger != nil {
+        _, shadowed, _ = c.find(key)
+    }
+    if auditing {
+        old, _ = c.searchLayer(values, key)
+    }
+    values[key] = value
+
+    return key, shadowed, old
+}
+
+// Removes key, and everything beneath it, from the overrides so the value of a lower
+// layer applies again. Undoes Set.
+func Unset(key string) { c.Unset(key) }
+func (c *Config) Unset(key string) {
+    c.mustNotBeFrozen()
+    auditing := c.auditing()
+
+    c.mu.Lock()
+    key, old := c.unsetIn(c.overrides, key, auditing)
+    c.invalidate()
+    c.mu.Unlock()
+
+    if auditing {
+        c.recordChange(AuditUnset, key, old, nil)
+    }
+}
+
+// unsetIn removes key, its aliases and everything beneath them from values, the
+// overrides or a custom layer, and returns the real key and the value it held when
+// auditing. Caller must hold mu.
+func (c *Config) unsetIn(values map[string]interface{}, key string, auditing bool) (string, interface{}) {
+    var old interface{}
+
+    key = c.realKey(c.normalizeKey(key))
+    if auditing {
+        old, _ = c.searchLayer(values, key)
+    }
+    c.deleteKey(values, key)
+    for _, alias := range c.aliasNames(key) {
+        c.deleteKey(values, alias)
+    }
+
+    return key, old
+}
+
+// Removes key, and everything beneath it, from the values read from the config file,
+// as if the file did not hold it. The result is validated like a merge, and the
+// config is left untouched if it does not pass.
+func DeleteFromConfig(key string) error { return c.DeleteFromConfig(key) }
+func (c *Config) DeleteFromConfig(key string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    c.mu.RLock()
+    key = c.realKey(c.normalizeKey(key))
+    names := append(c.aliasNames(key), key)
+    c.mu.RUnlock()
+
+    config := c.copyConfig()
+    c.mu.RLock()
+    file := copyMap(c.fileConfig)
+    c.mu.RUnlock()
+
+    removed := false
+    for _, name := range names {
+        if c.deleteKey(config, name) {
+            removed = true
+        }
+        c.deleteKey(file, name)
+    }
+    if !removed {
+        return nil
+    }
+
+    if err := c.validate(config); err != nil {
+        return err
+    }
+
+    c.setConfig(config, file, c.copyOrigins())
+    return nil
+}
+
+// deleteKey removes key and every key beneath it from m, whether they are stored under
+// their full name or nested in maps. Nested maps are copied rather than changed in
+// place. Returns whether anything was removed.
+func (c *Config) deleteKey(m map[string]interface{}, key string) bool {
+    removed := false
+
+    prefix := key + c.keyDelm
+    for k := range m {
+        if k == key || strings.HasPrefix(k, prefix) {
+            delete(m, k)
+            removed = true
+        }
+    }
+
+    path := strings.Split(key, c.keyDelm)
+    for i := len(path) - 1; i > 0; i-- {
+        parent := strings.Join(path[:i], c.keyDelm)
+        sub, ok := toStringMap(m[parent])
+        if ok {
+            sub = copyMap(sub)
+        }
+        if ok && c.deleteKey(sub, strings.Join(path[i:], c.keyDelm)) {
+            m[parent] = sub
+            removed = true
+        }
+    }
+
+    return removed
 }
 
 func ReadInConfig() error { return c.ReadInConfig() }
 func (c *Config) ReadInConfig() error {
-    jww.INFO.Println("Attempting to read in config file")
+    return c.ReadInConfigContext(context.Background())
+}
+
+// Like ReadInConfig, but gives up with ctx's error once ctx is done, aborting a config
+// file being fetched over HTTP. The current config is left in place.
+func ReadInConfigContext(ctx context.Context) error { return c.ReadInConfigContext(ctx) }
+func (c *Config) ReadInConfigContext(ctx context.Context) (err error) {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    ctx, span := c.startSpan(ctx, "cfg.ReadInConfig")
+    defer func() { endSpan(span, err) }()
+
+    c.logInfo("Attempting to read in config file")
+    file, cf, err := c.readConfigFile(ctx)
+    if c.missingOptional(err) {
+        return nil
+    }
+    if err != nil {
+        return err
+    }
+    span.SetAttributes(
+        attribute.String("cfg.file", cf),
+        attribute.String("cfg.format", c.getConfigType()),
+        attribute.Int("cfg.size", len(file)),
+    )
+
+    // Parse into a fresh map so a failed read leaves the current config in place.
+    config := make(map[string]interface{})
+    if err := c.unmarshalReader(bytes.NewReader(file), config); err != nil {
+        return parseErrorIn(err, cf)
+    }
+    origins := c.fileOrigins(config, file, c.getConfigType(), cf)
+    own := copyMap(config)
+    if c.extendsFiles {
+        if config, origins, err = c.applyExtends(ctx, config, origins, cf, nil); err != nil {
+            return err
+        }
+    }
+
+    for _, dir := range c.configDirs {
+        if err := c.mergeConfigDir(ctx, config, origins, dir); err != nil {
+            return err
+        }
+    }
+
+    if err := c.mergeProfiles(ctx, config, origins); err != nil {
+        return err
+    }
+
+    if err := c.validate(config); err != nil {
+        return err
+    }
+
+    keys := c.countKeys(config)
+    span.SetAttributes(attribute.Int("cfg.keys", keys))
+    c.setConfig(config, own, origins)
+    c.logEvent(slog.LevelInfo, "config_loaded",
+        slog.String("file", cf), slog.String("format", c.getConfigType()), slog.Int("keys", keys), slog.String("op", "read"))
+    c.recordChange(AuditReadConfig, "", nil, cf)
+
+    return nil
+}
+
+// Reads a document of the type set with SetConfigType from in and replaces the current
+// config with it, as ReadInConfig does with the config file, so config can come from
+// network buffers, decrypted blobs or test fixtures. Drop-in directories and profiles
+// are not merged in.
+func ReadConfig(in io.Reader) error { return c.ReadConfig(in) }
+func (c *Config) ReadConfig(in io.Reader) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
     if !stringInSlice(c.getConfigType(), SupportedExts) {
         return UnsupportedConfigError(c.getConfigType())
     }
 
-    file, err := ioutil.ReadFile(c.getConfigFile())
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    config := make(map[string]interface{})
+    if err := c.unmarshalReader(in, config); err != nil {
+        return err
+    }
+
+    if err := c.validate(config); err != nil {
+        return err
+    }
+
+    c.setConfig(config, config, c.fileOrigins(config, nil, "", ""))
+    c.logEvent(slog.LevelInfo, "config_loaded",
+        slog.String("format", c.getConfigType()), slog.Int("keys", c.countKeys(config)), slog.String("op", "read"))
+
+    return nil
+}
+
+// The config file name that reads the config from standard input.
+const StdinConfigFile = "-"
+
+// Reads a document of the type set with SetConfigType from standard input and replaces
+// the current config with it, for pipelines such as generate-config | app.
+func ReadFromStdin() error { return c.ReadFromStdin() }
+func (c *Config) ReadFromStdin() error {
+    return c.ReadConfig(os.Stdin)
+}
+
+// Reads the config file and deep-merges it into the current config, rather than
+// replacing it as ReadInConfig does, so a base file and an overlay can both be loaded.
+// Maps are merged recursively and any other value in the new file replaces the old one,
+// unless opts or SetMergeOptions select other strategies.
+func MergeInConfig(opts ...MergeOption) error { return c.MergeInConfig(opts...) }
+func (c *Config) MergeInConfig(opts ...MergeOption) error {
+    return c.MergeInConfigContext(context.Background(), opts...)
+}
+
+// Like MergeInConfig, but gives up with ctx's error once ctx is done.
+func MergeInConfigContext(ctx context.Context, opts ...MergeOption) error {
+    return c.MergeInConfigContext(ctx, opts...)
+}
+func (c *Config) MergeInConfigContext(ctx context.Context, opts ...MergeOption) (err error) {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    ctx, span := c.startSpan(ctx, "cfg.MergeInConfig")
+    defer func() { endSpan(span, err) }()
+
+    c.logInfo("Attempting to merge in config file")
+    file, cf, err := c.readConfigFile(ctx)
+    if c.missingOptional(err) {
+        return nil
+    }
     if err != nil {
         return err
     }
+    span.SetAttributes(
+        attribute.String("cfg.file", cf),
+        attribute.String("cfg.format", c.getConfigType()),
+        attribute.Int("cfg.size", len(file)),
+    )
+
+    src := make(map[string]interface{})
+    if err := c.unmarshalReader(bytes.NewReader(file), src); err != nil {
+        return parseErrorIn(err, cf)
+    }
+    origins := c.fileOrigins(src, file, c.getConfigType(), cf)
+    own := copyMap(src)
+    if c.extendsFiles {
+        if src, origins, err = c.applyExtends(ctx, src, origins, cf, nil); err != nil {
+            return err
+        }
+    }
+    keys := c.countKeys(src)
+    span.SetAttributes(attribute.Int("cfg.keys", keys))
 
-    c.config = make(map[string]interface{})
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    if err := c.mergeIntoConfig(src, own, origins, opts); err != nil {
+        return err
+    }
+    c.logEvent(slog.LevelInfo, "config_loaded",
+        slog.String("file", cf), slog.String("format", c.getConfigType()), slog.Int("keys", keys), slog.String("op", "merge"))
+    c.recordChange(AuditMergeConfig, "", nil, cf)
+
+    return nil
+}
+
+// Parses a document of the configured type from in and deep-merges it into the current
+// config, like MergeInConfig.
+func MergeConfig(in io.Reader, opts ...MergeOption) error { return c.MergeConfig(in, opts...) }
+func (c *Config) MergeConfig(in io.Reader, opts ...MergeOption) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    if !stringInSlice(c.getConfigType(), SupportedExts) {
+        return UnsupportedConfigError(c.getConfigType())
+    }
+
+    src := make(map[string]interface{})
+    if err := c.unmarshalReader(in, src); err != nil {
+        return err
+    }
+
+    return c.mergeIntoConfig(src, src, c.fileOrigins(src, nil, "", ""), opts)
+}
+
+// mergeIntoConfig merges src into a copy of the config layer, validates the result and
+// swaps it in. own is the part of src WriteConfig writes back, without extended files,
+// and srcOrigins tells where the keys of src were read from.
+func (c *Config) mergeIntoConfig(src, own map[string]interface{}, srcOrigins map[string]fileOrigin, opts []MergeOption) error {
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    config := c.copyConfig()
+    mergeMapsWith(config, src, c.mergeOptions(opts))
 
-    return c.unmarshalReader(bytes.NewReader(file), c.config)
+    c.mu.RLock()
+    file := copyMap(c.fileConfig)
+    c.mu.RUnlock()
+    mergeMapsWith(file, own, c.mergeOptions(opts))
+
+    origins := c.copyOrigins()
+    for k, v := range srcOrigins {
+        origins[k] = v
+    }
+
+    if err := c.validate(config); err != nil {
+        return err
+    }
+
+    c.setConfig(config, file, origins)
+    return nil
 }
 
-func unmarshalReader(in io.Reader, v map[string]interface{}) error {
-    return c.unmarshalReader(in, v)
+// copyConfig returns a copy of the config layer sharing its nested maps, which
+// mergeMapsWith and deleteKey copy before they change them.
+func (c *Config) copyConfig() map[string]interface{} {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    return copyMap(c.config)
 }
-func (c *Config) unmarshalReader(in io.Reader, v map[string]interface{}) error {
-    return unmarshallConfigReader(in, v, c.getConfigType())
+
+// setConfig replaces the config layer, along with the part of it WriteConfig writes
+// back and where its keys were read from, and updates everything bound to it. A nil
+// file keeps the part written back as it is.
+func (c *Config) setConfig(config, file map[string]interface{}, origins map[string]fileOrigin) {
+    c.mu.Lock()
+    c.config = config
+    if file != nil {
+        c.fileConfig = file
+    }
+    c.configOrigins = origins
+    c.invalidate()
+    c.mu.Unlock()
+
+    c.changed()
 }
 
-func (c *Config) insensitiviseMaps() {
-    insensitiviseMap(c.config)
-    insensitiviseMap(c.defaults)
-    insensitiviseMap(c.overrides)
+// Deep-merges m into the current config, like MergeConfig, without going through a
+// document. Keys are lowercased unless keys are case sensitive, m itself is left
+// untouched.
+func MergeConfigMap(m map[string]interface{}, opts ...MergeOption) error {
+    return c.MergeConfigMap(m, opts...)
 }
+func (c *Config) MergeConfigMap(m map[string]interface{}, opts ...MergeOption) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
 
-func AllKeys() []string { return c.AllKeys() }
-func (c *Config) AllKeys() []string {
-    m := map[string]struct{}{}
+    src := normalizeMaps(m).(map[string]interface{})
+    c.normalizeKeys(src)
+
+    return c.mergeIntoConfig(src, src, c.fileOrigins(src, nil, "", ""), opts)
+}
+
+// Makes the config file optional. When it is, ReadInConfig and MergeInConfig leave the
+// config as it is instead of failing when no config file is found, so a Config can be
+// populated with SetDefault, Set and MergeConfigMap alone.
+func SetConfigFileOptional(optional bool) { c.SetConfigFileOptional(optional) }
+func (c *Config) SetConfigFileOptional(optional bool) {
+    c.configOptional = optional
+}
+
+// missingOptional reports whether err is the config file being missing while it is
+// optional.
+func (c *Config) missingOptional(err error) bool {
+    if err == nil || !c.configOptional {
+        return false
+    }
+
+    var notFound ConfigFileNotFoundError
+    if errors.As(err, &notFound) || errors.Is(err, fs.ErrNotExist) {
+        c.logInfo("No config file found, continuing without one")
+        return true
+    }
 
-    for key := range c.defaults {
-        m[key] = struct{}{}
+    return false
+}
+
+// readConfigFile returns the contents of the config file along with its name.
+func (c *Config) readConfigFile(ctx context.Context) ([]byte, string, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, "", err
     }
 
-    for key := range c.config {
-        m[key] = struct{}{}
+    cf, err := c.getConfigFile()
+    if err != nil {
+        return nil, "", err
     }
 
-    for key := range c.overrides {
-        m[key] = struct{}{}
+    if !stringInSlice(c.getConfigType(), SupportedExts) {
+        return nil, cf, UnsupportedConfigError(c.getConfigType())
     }
 
-    a := []string{}
+    file, sum, err := c.loadConfigFile(ctx, cf)
+    if err != nil {
+        return nil, cf, err
+    }
+
+    c.mu.Lock()
+    c.configChecksum = sum
+    c.mu.Unlock()
+
+    return file, cf, nil
+}
+
+// loadConfigFile reads name, a path, URL or standard input, and returns it ready to be
+// parsed along with its checksum: its checksum and signature verified, decrypted and
+// rendered. The config file, drop-ins, profile overlays and extended files are all
+// read this way.
+func (c *Config) loadConfigFile(ctx context.Context, name string) ([]byte, string, error) {
+    var (
+        file []byte
+        err  error
+    )
+    if isURL(name) {
+        file, err = c.fetchURL(ctx, name)
+    } else if name == StdinConfigFile {
+        file, err = c.readConfigFrom(name, os.Stdin, 0)
+    } else {
+        var unlock func()
+        if unlock, err = c.lockForRead(ctx, name); err != nil {
+            return nil, "", err
+        }
+        file, err = c.readConfigPath(name)
+        unlock()
+    }
+    if err != nil {
+        return nil, "", fmt.Errorf("Cannot read config file %q: %w", name, err)
+    }
+
+    // Checksums and signatures cover the file as stored, before it is decrypted.
+    sum, err := c.verifyFileChecksum(ctx, name, file)
+    if err != nil {
+        return nil, "", err
+    }
+    if err := c.verifyFile(ctx, name, file); err != nil {
+        return nil, "", err
+    }
+
+    if file, err = c.decryptFile(name, file); err != nil {
+        return nil, "", err
+    }
+    if file, err = c.renderFile(name, file); err != nil {
+        return nil, "", err
+    }
+
+    return file, sum, nil
+}
+
+// Parses a configuration document of the given type into a new map.
+func Decode(in io.Reader, configType string) (map[string]interface{}, error) {
+    m, err := parseConfig(in, configType)
+    if err != nil {
+        return nil, err
+    }
+
+    insensitiviseMap(m)
+    return m, nil
+}
+
+// decodeConfig parses a configuration document like Decode, normalizing keys the way
+// c stores them.
+func (c *Config) decodeConfig(in io.Reader, configType string) (map[string]interface{}, error) {
+    m, err := c.parseLimited(in, configType)
+    if err != nil {
+        return nil, err
+    }
+
+    c.normalizeFileKeys(m)
+    return m, nil
+}
+
+// parseConfig parses a configuration document, leaving its keys as written.
+func parseConfig(in io.Reader, configType string) (map[string]interface{}, error) {
+    if !stringInSlice(strings.ToLower(configType), SupportedExts) {
+        return nil, UnsupportedConfigError(configType)
+    }
+
+    m := make(map[string]interface{})
+    if err := unmarshallConfigReader(in, m, configType); err != nil {
+        return nil, err
+    }
+
+    return m, nil
+}
+
+func (c *Config) unmarshalReader(in io.Reader, v map[string]interface{}) error {
+    m, err := c.parseLimited(in, c.getConfigType())
+    if err != nil {
+        return err
+    }
+    for k, val := range m {
+        v[k] = val
+    }
+
+    c.normalizeFileKeys(v)
+    return nil
+}
+
+// Returns every key set in any layer in order, the keys of nested maps joined to the
+// keys above them by the key delimiter, so db.host is listed rather than db.
+func AllKeys() []string { return c.AllKeys() }
+func (c *Config) AllKeys() []string {
+    m := map[string]struct{}{}
+
+    c.mu.RLock()
+    for _, l := range c.layers() {
+        c.flatten(l.values, "", func(key string, _ interface{}) {
+            m[c.unalias(key)] = struct{}{}
+        })
+    }
+    c.mu.RUnlock()
+
+    a := make([]string, 0, len(m))
     for x := range m {
         a = append(a, x)
     }
+    sort.Strings(a)
 
     return a
 }
 
-func AllSettings() map[string]interface{} { return c.AllSettings() }
-func (c *Config) AllSettings() map[string]interface{} {
+// Returns the keys AllKeys lists that lie beneath prefix, in order, so the keys of
+// sections with names only known at runtime, such as one per upstream under upstreams,
+// can be listed. Every key is listed in full, upstreams.a.host rather than a.host. An
+// empty prefix lists every key.
+func KeysUnder(prefix string) []string { return c.KeysUnder(prefix) }
+func (c *Config) KeysUnder(prefix string) []string {
+    if prefix == "" {
+        return c.AllKeys()
+    }
+
+    c.mu.RLock()
+    prefix = c.realKey(c.normalizeKey(prefix)) + c.keyDelm
+    c.mu.RUnlock()
+
+    var keys []string
+    for _, key := range c.AllKeys() {
+        if strings.HasPrefix(key, prefix) {
+            keys = append(keys, key)
+        }
+    }
+
+    return keys
+}
+
+// Calls fn with every key AllKeys lists, its effective value and where that comes from,
+// walking the merged settings depth-first with the keys of every map in order, so
+// exporters, linters and documentation generators need not build AllSettings first. The
+// walk stops at the first error fn returns, which Walk returns. Keys unset while the
+// walk is underway are skipped.
+func Walk(fn func(key string, value interface{}, origin KeyOrigin) error) error {
+    return c.Walk(fn)
+}
+func (c *Config) Walk(fn func(key string, value interface{}, origin KeyOrigin) error) error {
+    // Sorting keeps the keys beneath a map together, right after the keys before it.
+    for _, key := range c.AllKeys() {
+        origin, ok := c.Origin(key)
+        if !ok {
+            continue
+        }
+
+        // Walking the settings is not reading them, see UnusedKeys.
+        val, _ := c.lookup(key, false)
+        if err := fn(key, val, origin); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Returns the value of every key AllKeys lists, by key. With WithRedaction, the values
+// of secret keys are replaced, see MarkSecret.
+func AllSettings(opts ...SettingsOption) map[string]interface{} { return c.AllSettings(opts...) }
+func (c *Config) AllSettings(opts ...SettingsOption) map[string]interface{} {
+    var o settingsOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
     m := map[string]interface{}{}
     for _, x := range c.AllKeys() {
-        m[x] = c.Get(x)
+        // Listing every setting is not reading it, see UnusedKeys.
+        m[x], _ = c.lookup(x, false)
+    }
+
+    if o.redact {
+        c.mu.RLock()
+        m = c.redact(m, "")
+        c.mu.RUnlock()
     }
 
     return m
 }
 
-// Prints all configuration registries for debugging
-// purposes.
-func Debug() { c.Debug() }
-func (c *Config) Debug() {
-    fmt.Println("Aliases:")
-    pretty.Println(c.aliases)
-    // fmt.Println("Override:")
-    // pretty.Println(c.override)
-    // fmt.Println("PFlags")
-    // pretty.Println(c.pflags)
-    // fmt.Println("Env:")
-    // pretty.Println(c.env)
-    // fmt.Println("Key/Value Store:")
-    // pretty.Println(c.kvstore)
-    fmt.Println("Config:")
-    pretty.Println(c.config)
-    fmt.Println("Defaults:")
-    pretty.Println(c.defaults)
+// Returns the settings of AllSettings nested back into maps, the fully merged tree that
+// a YAML or TOML document of the config would hold. Takes the same options as
+// AllSettings.
+func AllSettingsNested(opts ...SettingsOption) map[string]interface{} {
+    return c.AllSettingsNested(opts...)
 }
+func (c *Config) AllSettingsNested(opts ...SettingsOption) map[string]interface{} {
+    flat := c.AllSettings(opts...)
+
+    keys := make([]string, 0, len(flat))
+    for key := range flat {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+
+    tree := make(map[string]interface{})
+    for _, key := range keys {
+        setNested(tree, strings.Split(key, c.keyDelm), flat[key])
+    }
+
+    return tree
+}
+
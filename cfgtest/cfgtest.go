@@ -0,0 +1,145 @@
+// Package cfgtest helps unit test code that depends on a cfg.Config. Every helper works on
+// a Config of its own, never the global one, and only FromFile touches the disk.
+package cfgtest
+
+import (
+    "io/ioutil"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+    "testing"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Returns a Config whose config layer holds values, as if read from a config file.
+func New(t testing.TB, values map[string]interface{}) *cfg.Config {
+    t.Helper()
+
+    c := cfg.New()
+    if err := c.MergeConfigMap(values); err != nil {
+        t.Fatalf("cfgtest: merging values: %v", err)
+    }
+
+    return c
+}
+
+// Returns a Config whose config layer is read from the YAML document doc. Leading tabs
+// common to all lines are stripped, so doc can be indented along with the test.
+func FromYAML(t testing.TB, doc string) *cfg.Config {
+    t.Helper()
+    return fromDoc(t, doc, "yaml")
+}
+
+// Returns a Config whose config layer is read from the TOML document doc, dedented like
+// the document of FromYAML.
+func FromTOML(t testing.TB, doc string) *cfg.Config {
+    t.Helper()
+    return fromDoc(t, doc, "toml")
+}
+
+func fromDoc(t testing.TB, doc, configType string) *cfg.Config {
+    t.Helper()
+
+    c := cfg.New()
+    c.SetConfigType(configType)
+    if err := c.ReadConfig(strings.NewReader(dedent(doc))); err != nil {
+        t.Fatalf("cfgtest: reading %s: %v", configType, err)
+    }
+
+    return c
+}
+
+// Writes doc to a file called name in a temporary directory removed when the test ends,
+// and returns its path. Use it for code that reads config files itself.
+func TempFile(t testing.TB, name, doc string) string {
+    t.Helper()
+
+    path := filepath.Join(t.TempDir(), name)
+    if err := ioutil.WriteFile(path, []byte(dedent(doc)), 0644); err != nil {
+        t.Fatalf("cfgtest: writing %s: %v", name, err)
+    }
+
+    return path
+}
+
+// Returns a Config that read doc from a temporary config file called name, see TempFile.
+// The extension of name selects the format.
+func FromFile(t testing.TB, name, doc string) *cfg.Config {
+    t.Helper()
+
+    c := cfg.New()
+    c.SetConfigFile(TempFile(t, name, doc))
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatalf("cfgtest: reading %s: %v", name, err)
+    }
+
+    return c
+}
+
+// Stops the test unless key is set and converts to want, converting like the typed
+// getters, so RequireKey(t, c, "port", 8080) holds for a port read as a string.
+func RequireKey[T any](t testing.TB, c *cfg.Config, key string, want T) {
+    t.Helper()
+
+    if !c.IsSet(key) {
+        t.Fatalf("cfgtest: key %q is not set", key)
+    }
+
+    got, err := cfg.GetAs[T](c, key)
+    if err != nil {
+        t.Fatalf("cfgtest: key %q: %v", key, err)
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Fatalf("cfgtest: key %q is %#v, want %#v", key, got, want)
+    }
+}
+
+// Fails the test unless the keys of the config file that were never read are exactly
+// want, see cfg.Config.UnusedKeys. With no want, every key must have been read.
+func AssertUnused(t testing.TB, c *cfg.Config, want ...string) {
+    t.Helper()
+
+    got := c.UnusedKeys()
+    want = append([]string(nil), want...)
+    sort.Strings(want)
+
+    if len(got) == 0 && len(want) == 0 {
+        return
+    }
+    if !reflect.DeepEqual(got, want) {
+        t.Errorf("cfgtest: unused keys are %q, want %q", got, want)
+    }
+}
+
+// dedent removes the leading blank line and the tabs all other lines start with, as
+// left by a raw string literal indented with the code around it.
+func dedent(doc string) string {
+    doc = strings.TrimPrefix(doc, "\n")
+
+    lines := strings.Split(doc, "\n")
+    indent := -1
+    for _, line := range lines {
+        if strings.TrimSpace(line) == "" {
+            continue
+        }
+        n := len(line) - len(strings.TrimLeft(line, "\t"))
+        if indent < 0 || n < indent {
+            indent = n
+        }
+    }
+    if indent <= 0 {
+        return doc
+    }
+
+    for i, line := range lines {
+        if len(line) >= indent {
+            lines[i] = line[indent:]
+        } else {
+            lines[i] = strings.TrimLeft(line, "\t")
+        }
+    }
+
+    return strings.Join(lines, "\n")
+}
@@ -0,0 +1,70 @@
+package cfg
+
+import (
+    "errors"
+)
+
+// Returned by Rollback for a checkpoint taken of another config, or a zero Checkpoint.
+var ErrForeignCheckpoint = errors.New("Checkpoint was not taken of this config")
+
+// Holds the values of the config file, defaults and overrides of a config at the time
+// it was taken, see Checkpoint.
+type Checkpoint struct {
+    c *Config
+
+    config    map[string]interface{}
+    file      map[string]interface{}
+    origins   map[string]fileOrigin
+    defaults  map[string]interface{}
+    overrides map[string]interface{}
+}
+
+// Returns a checkpoint Rollback can restore the values read from the config file, the
+// defaults and the overrides to, so a batch of Set calls can be applied and reverted
+// if validation or a canary fails. Sources, layers and aliases are not part of it.
+func TakeCheckpoint() Checkpoint { return c.Checkpoint() }
+func (c *Config) Checkpoint() Checkpoint {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    return Checkpoint{
+        c:         c,
+        config:    normalizeMaps(c.config).(map[string]interface{}),
+        file:      normalizeMaps(c.fileConfig).(map[string]interface{}),
+        origins:   c.configOrigins,
+        defaults:  normalizeMaps(c.defaults).(map[string]interface{}),
+        overrides: normalizeMaps(c.overrides).(map[string]interface{}),
+    }
+}
+
+// Restores the values read from the config file, the defaults and the overrides to
+// what they were when cp was taken, undoing every Set, SetDefault, read and merge
+// since. Bound structs are updated. A checkpoint can be rolled back to any number of
+// times. Rolling back to a checkpoint of another config fails with
+// ErrForeignCheckpoint.
+func Rollback(cp Checkpoint) error { return c.Rollback(cp) }
+func (c *Config) Rollback(cp Checkpoint) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+    if cp.c != c {
+        return ErrForeignCheckpoint
+    }
+
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    c.mu.Lock()
+    c.config = normalizeMaps(cp.config).(map[string]interface{})
+    c.fileConfig = normalizeMaps(cp.file).(map[string]interface{})
+    c.configOrigins = cp.origins
+    c.defaults = normalizeMaps(cp.defaults).(map[string]interface{})
+    c.overrides = normalizeMaps(cp.overrides).(map[string]interface{})
+    c.invalidate()
+    c.mu.Unlock()
+
+    c.recordChange(AuditRollback, "", nil, nil)
+    c.changed()
+
+    return nil
+}
@@ -0,0 +1,124 @@
+package cfg
+
+import (
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "fmt"
+    "net/url"
+    "strings"
+)
+
+// Extension of the file holding the SHA-256 checksum of a config file, in the format of
+// sha256sum, config.yaml.sha256 for config.yaml.
+const ChecksumExt = ".sha256"
+
+// Denotes a config file whose contents do not match their expected checksum.
+type ChecksumError struct {
+    File string
+
+    // Expected and actual SHA-256 checksums, hex encoded. Want is empty when the
+    // checksum file could not be read.
+    Want, Got string
+
+    Err error
+}
+
+// Returns the formatted checksum error.
+func (ce ChecksumError) Error() string {
+    if ce.Err != nil {
+        return fmt.Sprintf("Cannot verify checksum of config file %q: %v", ce.File, ce.Err)
+    }
+    return fmt.Sprintf("Checksum of config file %q is %s, expected %s", ce.File, ce.Got, ce.Want)
+}
+
+// Returns the reason the checksum could not be verified.
+func (ce ChecksumError) Unwrap() error {
+    return ce.Err
+}
+
+// Pins the SHA-256 checksum, hex encoded, of the config file or remote config at
+// location, the path or URL it is read from. Its contents are verified before they are
+// parsed and rejected with a ChecksumError when they differ, so a tampered or
+// half-deployed document is never loaded.
+func PinChecksum(location, sum string) error { return c.PinChecksum(location, sum) }
+func (c *Config) PinChecksum(location, sum string) error {
+    sum = strings.ToLower(strings.TrimSpace(sum))
+    if b, err := hex.DecodeString(sum); err != nil || len(b) != sha256.Size {
+        return fmt.Errorf("Checksum %q is not a hex encoded SHA-256", sum)
+    }
+
+    c.checksums[location] = sum
+    return nil
+}
+
+// Requires config files, local or fetched from a URL, to come with a checksum file next
+// to them, see ChecksumExt, which their contents are verified against like a pinned
+// checksum. A checksum pinned with PinChecksum takes precedence. Drop-ins, profile
+// overlays and extended files need one as well. Remote configs are only verified against
+// pinned checksums.
+func RequireChecksumFile(require bool) { c.RequireChecksumFile(require) }
+func (c *Config) RequireChecksumFile(require bool) {
+    c.checksumFile = require
+}
+
+// Returns the SHA-256 checksum, hex encoded, of the config file last read or merged, as
+// stored before any decryption, for audit logs. Empty until a config file was read.
+func ConfigChecksum() string { return c.ConfigChecksum() }
+func (c *Config) ConfigChecksum() string {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    return c.configChecksum
+}
+
+// verifyChecksum checks data, read from location, against its pinned checksum, or the
+// checksum read by readSidecar if checksum files are required and readSidecar is not
+// nil. Returns the checksum of data.
+func (c *Config) verifyChecksum(location string, data []byte, readSidecar func() ([]byte, error)) (string, error) {
+    digest := sha256.Sum256(data)
+    got := hex.EncodeToString(digest[:])
+
+    want, pinned := c.checksums[location]
+    if !pinned && c.checksumFile && readSidecar != nil {
+        sidecar, err := readSidecar()
+        if err != nil {
+            return got, ChecksumError{File: location, Got: got, Err: err}
+        }
+
+        // sha256sum writes the checksum followed by the file name.
+        fields := strings.Fields(string(sidecar))
+        if len(fields) == 0 {
+            return got, ChecksumError{File: location, Got: got, Err: fmt.Errorf("Checksum file is empty")}
+        }
+        want, pinned = strings.ToLower(fields[0]), true
+    }
+
+    if pinned && want != got {
+        return got, ChecksumError{File: location, Want: want, Got: got}
+    }
+
+    return got, nil
+}
+
+// verifyFileChecksum checks data, read from the config file file, against its checksum.
+func (c *Config) verifyFileChecksum(ctx context.Context, file string, data []byte) (string, error) {
+    var readSidecar func() ([]byte, error)
+    switch {
+    case isURL(file):
+        readSidecar = func() ([]byte, error) {
+            u, err := url.Parse(file)
+            if err != nil {
+                return nil, err
+            }
+            u.Path += ChecksumExt
+            return c.fetchURL(ctx, u.String())
+        }
+    case file != StdinConfigFile:
+        readSidecar = func() ([]byte, error) {
+            return c.readFile(file + ChecksumExt)
+        }
+    }
+
+    return c.verifyChecksum(file, data, readSidecar)
+}
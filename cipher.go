@@ -0,0 +1,82 @@
+package cfg
+
+import (
+    "fmt"
+    "io/ioutil"
+    "path/filepath"
+    "strings"
+)
+
+// Decrypts and encrypts config files wrapped in an encryption format, see SetFileCipher.
+type FileCipher interface {
+    Decrypt(ciphertext []byte) ([]byte, error)
+    Encrypt(plaintext []byte) ([]byte, error)
+}
+
+// Registers fc for config files with the extension ext, such as "age" for
+// config.yaml.age. Such files are decrypted before they are parsed, as the type given by
+// the extension before ext, and encrypted again when written. Config files are searched
+// for with ext appended too. A nil fc removes the cipher of ext.
+func SetFileCipher(ext string, fc FileCipher) { c.SetFileCipher(ext, fc) }
+func (c *Config) SetFileCipher(ext string, fc FileCipher) {
+    ext = strings.ToLower(strings.TrimPrefix(ext, "."))
+    if fc == nil {
+        delete(c.ciphers, ext)
+        return
+    }
+
+    c.ciphers[ext] = fc
+}
+
+// Returns the cipher registered for the extension ext, nil if there is none.
+func GetFileCipher(ext string) FileCipher { return c.FileCipher(ext) }
+func (c *Config) FileCipher(ext string) FileCipher {
+    return c.ciphers[strings.ToLower(strings.TrimPrefix(ext, "."))]
+}
+
+// cipherFor returns filename without the extension of its cipher along with the cipher,
+// or filename as is and nil if no cipher is registered for its extension.
+func (c *Config) cipherFor(filename string) (string, FileCipher) {
+    ext := filepath.Ext(filename)
+    if fc, ok := c.ciphers[strings.ToLower(strings.TrimPrefix(ext, "."))]; ok && ext != "" {
+        return strings.TrimSuffix(filename, ext), fc
+    }
+
+    return filename, nil
+}
+
+// decryptFile returns data, read from filename, decrypted if filename is encrypted.
+func (c *Config) decryptFile(filename string, data []byte) ([]byte, error) {
+    if _, fc := c.cipherFor(filename); fc != nil {
+        plain, err := fc.Decrypt(data)
+        if err != nil {
+            return nil, fmt.Errorf("Cannot decrypt config file %q: %w", filename, err)
+        }
+        return plain, nil
+    }
+
+    return data, nil
+}
+
+// encryptFile returns data encrypted for filename if it is to be encrypted.
+func (c *Config) encryptFile(filename string, data []byte) ([]byte, error) {
+    if _, fc := c.cipherFor(filename); fc != nil {
+        sealed, err := fc.Encrypt(data)
+        if err != nil {
+            return nil, fmt.Errorf("Cannot encrypt config file %q: %w", filename, err)
+        }
+        return sealed, nil
+    }
+
+    return data, nil
+}
+
+// readPlainFile reads filename, decrypting it if it is encrypted.
+func (c *Config) readPlainFile(filename string) ([]byte, error) {
+    data, err := ioutil.ReadFile(filename)
+    if err != nil {
+        return nil, err
+    }
+
+    return c.decryptFile(filename, data)
+}
@@ -0,0 +1,140 @@
+package cfg
+
+// Returns a deep copy of the config. The copy starts out with the same settings,
+// values, aliases, sources and layers, and changes to either side are not seen by the
+// other. Sources are not refreshed in the copy, and watches, remotes, bindings and
+// change handlers stay with the original. The copy shares the logger, a log file set
+// with SetLogFile is closed when the original replaces it.
+func Clone() *Config { return c.Clone() }
+func (c *Config) Clone() *Config {
+    clone := New()
+    c.copySettingsTo(clone)
+
+    clone.configName = c.configName
+    clone.configNames = append([]string(nil), c.configNames...)
+    clone.configFile = c.configFile
+    clone.configType = c.configType
+    clone.configOptional = c.configOptional
+    clone.configPaths = append([]string(nil), c.configPaths...)
+    for path, depth := range c.configPathDepths {
+        if clone.configPathDepths == nil {
+            clone.configPathDepths = make(map[string]int)
+        }
+        clone.configPathDepths[path] = depth
+    }
+    clone.resolveSymlinks = c.resolveSymlinks
+    clone.searchUp = c.searchUp
+    clone.searchStops = append([]string(nil), c.searchStops...)
+    clone.configDirs = append([]string(nil), c.configDirs...)
+    clone.profiles = append([]string(nil), c.profiles...)
+    clone.profileEnv = c.profileEnv
+    clone.role = c.role
+    clone.roleEnv = c.roleEnv
+    clone.hostOverlay = c.hostOverlay
+
+    c.mu.RLock()
+    clone.config = normalizeMaps(c.config).(map[string]interface{})
+    clone.fileConfig = normalizeMaps(c.fileConfig).(map[string]interface{})
+    clone.configOrigins = c.configOrigins
+    clone.defaults = normalizeMaps(c.defaults).(map[string]interface{})
+    clone.overrides = normalizeMaps(c.overrides).(map[string]interface{})
+    clone.computed = copyMap(c.computed)
+    for k, v := range c.aliases {
+        clone.aliases[k] = v
+    }
+    clone.deprecations = append([]*deprecation(nil), c.deprecations...)
+    clone.secrets = append([]string(nil), c.secrets...)
+    c.mu.RUnlock()
+
+    c.keySpellings.Range(func(k, spelling interface{}) bool {
+        clone.keySpellings.Store(k, spelling)
+        return true
+    })
+
+    clone.httpHeaders = c.httpHeaders.Clone()
+    clone.httpTimeout = c.httpTimeout
+    clone.httpTLS = c.httpTLS
+
+    clone.validators = append(clone.validators, c.validators...)
+    clone.required = append(clone.required, c.required...)
+    clone.onDeprecated = append(clone.onDeprecated, c.onDeprecated...)
+    clone.allowedKeys = append(clone.allowedKeys, c.allowedKeys...)
+    clone.onUnknownKeys = append(clone.onUnknownKeys, c.onUnknownKeys...)
+    clone.strictKeys = c.strictKeys
+    clone.watchDebounce = c.watchDebounce
+    clone.mergeOpts = append(clone.mergeOpts, c.mergeOpts...)
+    clone.verbose = c.verbose
+    clone.logger = c.logger
+    clone.tracer.Store(c.tracer.Load())
+
+    c.sourceMu.RLock()
+    for _, src := range c.sources {
+        clone.sources = append(clone.sources, &source{
+            name:   src.name,
+            seq:    src.seq,
+            fetch:  src.fetch,
+            values: normalizeMaps(src.values).(map[string]interface{}),
+        })
+    }
+    for _, l := range c.customLayers {
+        clone.customLayers = append(clone.customLayers, &NamedLayer{
+            name:     l.name,
+            priority: l.priority,
+            seq:      l.seq,
+            config:   clone,
+            values:   l.snapshot(),
+        })
+    }
+    clone.layerSeq = c.layerSeq
+    c.sourceMu.RUnlock()
+
+    return clone
+}
+
+// Returns a read-only view of the effective settings as they are now. Later changes
+// to the config, including reloads, do not show through it, so a request can be
+// served from one consistent config. Taking a snapshot copies the whole config like
+// Clone, so take one per request or reload rather than per read.
+func TakeSnapshot() Snapshot { return c.Snapshot() }
+func (c *Config) Snapshot() Snapshot {
+    return Snapshot{c.Clone()}
+}
+
+// copySettingsTo copies the settings that decide how keys are resolved and values
+// converted to dst.
+func (c *Config) copySettingsTo(dst *Config) {
+    dst.keyDelm = c.keyDelm
+    dst.caseSensitive = c.caseSensitive
+    dst.keyNormalization = c.keyNormalization
+    dst.typeByDefValue = c.typeByDefValue
+    dst.strictTypes = c.strictTypes
+    dst.interpolate = c.interpolate
+    dst.templating = c.templating
+    dst.includeFiles = c.includeFiles
+    dst.extendedDurations = c.extendedDurations
+    dst.extendsFiles = c.extendsFiles
+    dst.timeLayouts = c.timeLayouts
+    dst.timeLocation = c.timeLocation
+    dst.tagName = c.tagName
+    dst.decodeHooks = c.decodeHooks
+    dst.structValidator = c.structValidator
+    dst.verifiers = append([]signatureVerifier(nil), c.verifiers...)
+    for location, sum := range c.checksums {
+        dst.checksums[location] = sum
+    }
+    dst.checksumFile = c.checksumFile
+    dst.maxConfigSize = c.maxConfigSize
+    dst.maxConfigDepth = c.maxConfigDepth
+    dst.maxConfigKeys = c.maxConfigKeys
+    dst.maxAliasExpansion = c.maxAliasExpansion
+    dst.fileLocking = c.fileLocking
+    dst.fsys = c.fsys
+    for ext, fc := range c.ciphers {
+        dst.ciphers[ext] = fc
+    }
+    c.resolverMu.RLock()
+    for scheme, r := range c.resolvers {
+        dst.resolvers[scheme] = r
+    }
+    c.resolverMu.RUnlock()
+}
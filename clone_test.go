@@ -0,0 +1,48 @@
+package cfg
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestCloneKeepsKeySpellings(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(file, []byte("serverPort: 8080\nlogLevel: info\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c := New()
+    c.SetKeyNormalization(true)
+    c.SetConfigFile(file)
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name string
+        cfg  *Config
+    }{
+        {"original", c},
+        {"clone", c.Clone()},
+        {"snapshot", c.Snapshot().c},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := tt.cfg.GetInt("server_port"); got != 8080 {
+                t.Errorf("GetInt(server_port) = %d, want 8080", got)
+            }
+
+            var buf bytes.Buffer
+            if err := tt.cfg.WriteConfigTo(&buf, "yaml"); err != nil {
+                t.Fatal(err)
+            }
+            if out := buf.String(); !strings.Contains(out, "serverPort:") || !strings.Contains(out, "logLevel:") {
+                t.Errorf("WriteConfigTo() =\n%s\nwant the keys spelled serverPort and logLevel", out)
+            }
+        })
+    }
+}
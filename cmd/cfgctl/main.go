@@ -0,0 +1,241 @@
+// Command cfgctl reads, edits, checks and converts config files with the same parsing
+// and precedence code as the cfg library, so operators and CI see configs exactly as
+// the applications built on it do.
+//
+// Usage:
+//
+//     cfgctl get KEY FILE...          print the value of KEY
+//     cfgctl set FILE KEY VALUE       set KEY in FILE, VALUE is parsed as YAML
+//     cfgctl validate FILE...         check that every FILE parses
+//     cfgctl merge [-o TYPE] FILE...  print the FILEs merged in order
+//     cfgctl convert -o TYPE FILE     print FILE as TYPE, one of json, toml or yaml
+//     cfgctl diff FILE FILE           print the keys whose values differ
+//     cfgctl precedence               print the precedence rules as Markdown
+//
+// FILEs after the first are merged into it like MergeInConfig. A FILE of - is read from
+// standard input, its type given with -t.
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "sort"
+    "strings"
+
+    yaml3 "gopkg.in/yaml.v3"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Exit status of a command line that cannot be run.
+const exitUsage = 2
+
+func main() {
+    flags := flag.NewFlagSet("cfgctl", flag.ExitOnError)
+    configType := flags.String("t", "", "type of files read from standard input")
+    outType := flags.String("o", "", "type of the document printed by merge and convert")
+    flags.Usage = usage
+
+    if len(os.Args) < 2 {
+        usage()
+        os.Exit(exitUsage)
+    }
+    cmd := os.Args[1]
+    flags.Parse(os.Args[2:])
+    args := flags.Args()
+
+    var err error
+    switch {
+    case cmd == "get" && len(args) >= 2:
+        err = get(args[0], args[1:], *configType)
+    case cmd == "set" && len(args) == 3:
+        err = set(args[0], args[1], args[2])
+    case cmd == "validate" && len(args) >= 1:
+        err = validate(args, *configType)
+    case cmd == "merge" && len(args) >= 1:
+        err = merge(args, *configType, *outType)
+    case cmd == "convert" && len(args) == 1 && *outType != "":
+        err = merge(args, *configType, *outType)
+    case cmd == "precedence" && len(args) == 0:
+        err = cfg.New().WritePrecedenceDoc(os.Stdout)
+    case cmd == "diff" && len(args) == 2:
+        var differ bool
+        differ, err = diff(args[0], args[1], *configType)
+        if err == nil && differ {
+            os.Exit(1)
+        }
+    default:
+        usage()
+        os.Exit(exitUsage)
+    }
+
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "cfgctl:", err)
+        os.Exit(1)
+    }
+}
+
+func usage() {
+    fmt.Fprint(os.Stderr, `Usage:
+  cfgctl get KEY FILE...
+  cfgctl set FILE KEY VALUE
+  cfgctl validate FILE...
+  cfgctl merge [-o TYPE] FILE...
+  cfgctl convert -o TYPE FILE
+  cfgctl diff FILE FILE
+  cfgctl precedence
+
+Flags:
+  -t TYPE   type of files read from standard input
+  -o TYPE   type of the document printed, json, toml or yaml
+`)
+}
+
+// load reads the first of files and merges the others into it.
+func load(files []string, configType string) (*cfg.Config, error) {
+    c := cfg.New()
+    if configType != "" {
+        c.SetConfigType(configType)
+    }
+
+    for i, file := range files {
+        c.SetConfigFile(file)
+
+        var err error
+        if i == 0 {
+            err = c.ReadInConfig()
+        } else {
+            err = c.MergeInConfig()
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return c, nil
+}
+
+func get(key string, files []string, configType string) error {
+    c, err := load(files, configType)
+    if err != nil {
+        return err
+    }
+
+    if !c.IsSet(key) {
+        return fmt.Errorf("Key %q is not set", key)
+    }
+
+    // Maps are merged from every layer, as Sub sees them.
+    if sub := c.Sub(key); sub != nil {
+        return sub.WriteConfigTo(os.Stdout, "yaml")
+    }
+
+    switch v := c.Get(key).(type) {
+    case []interface{}:
+        b, err := yaml3.Marshal(v)
+        if err != nil {
+            return err
+        }
+        _, err = os.Stdout.Write(b)
+        return err
+    default:
+        fmt.Println(v)
+    }
+
+    return nil
+}
+
+func set(file, key, value string) error {
+    c, err := load([]string{file}, "")
+    if err != nil {
+        return err
+    }
+
+    var v interface{} = value
+    if value != "" {
+        if err := yaml3.Unmarshal([]byte(value), &v); err != nil {
+            return fmt.Errorf("Parsing value: %v", err)
+        }
+    }
+    c.Set(key, v)
+
+    return c.WriteConfig()
+}
+
+func validate(files []string, configType string) error {
+    failed := 0
+    for _, file := range files {
+        if _, err := load([]string{file}, configType); err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            failed++
+        }
+    }
+
+    if failed > 0 {
+        return fmt.Errorf("%d of %d files are invalid", failed, len(files))
+    }
+
+    return nil
+}
+
+func merge(files []string, configType, outType string) error {
+    c, err := load(files, configType)
+    if err != nil {
+        return err
+    }
+
+    if outType == "" {
+        outType = configType
+    }
+    if outType == "" {
+        outType = strings.TrimPrefix(filepath.Ext(files[0]), ".")
+    }
+
+    return c.WriteConfigTo(os.Stdout, outType)
+}
+
+// diff prints the keys set in only one of the files or to different values in each,
+// and reports whether there were any.
+func diff(a, b, configType string) (bool, error) {
+    ca, err := load([]string{a}, configType)
+    if err != nil {
+        return false, err
+    }
+    cb, err := load([]string{b}, configType)
+    if err != nil {
+        return false, err
+    }
+
+    sa, sb := ca.AllSettings(), cb.AllSettings()
+
+    keys := ca.AllKeys()
+    for _, key := range cb.AllKeys() {
+        if _, ok := sa[key]; !ok {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+
+    differ := false
+    for _, key := range keys {
+        va, inA := sa[key]
+        vb, inB := sb[key]
+
+        switch {
+        case !inB:
+            fmt.Printf("- %s: %v\n", key, va)
+        case !inA:
+            fmt.Printf("+ %s: %v\n", key, vb)
+        case !reflect.DeepEqual(va, vb):
+            fmt.Printf("- %s: %v\n+ %s: %v\n", key, va, key, vb)
+        default:
+            continue
+        }
+        differ = true
+    }
+
+    return differ, nil
+}
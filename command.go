@@ -0,0 +1,159 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "os/exec"
+    "strings"
+    "time"
+)
+
+// Time a command added with AddCommandSource may run unless set otherwise.
+const DefaultCommandTimeout = 30 * time.Second
+
+// Denotes a command of a command source that could not be run, failed or timed out.
+type CommandError struct {
+    Name string
+    Argv []string
+    Err  error
+
+    // The end of what the command wrote to standard error
+    Stderr string
+}
+
+// Returns the formatted command error.
+func (ce CommandError) Error() string {
+    s := fmt.Sprintf("Command source %q, %s: %v", ce.Name, strings.Join(ce.Argv, " "), ce.Err)
+    if ce.Stderr != "" {
+        s += ": " + ce.Stderr
+    }
+    return s
+}
+
+// Returns the underlying error.
+func (ce CommandError) Unwrap() error {
+    return ce.Err
+}
+
+// Adjusts how AddCommandSource runs its command.
+type CommandOption func(*commandOptions)
+
+type commandOptions struct {
+    timeout time.Duration
+    refresh time.Duration
+}
+
+// Kills the command and fails the fetch when it runs longer than d instead of
+// DefaultCommandTimeout. Zero lets it run for as long as it takes.
+func WithCommandTimeout(d time.Duration) CommandOption {
+    return func(o *commandOptions) {
+        o.timeout = d
+    }
+}
+
+// Runs the command again every d, see AddSource.
+func WithCommandRefresh(d time.Duration) CommandOption {
+    return func(o *commandOptions) {
+        o.refresh = d
+    }
+}
+
+// Adds the output of a command as a named source, for wrappers such as
+// aws ssm get-parameters or password manager CLIs that print config.
+//
+// argv is run directly, not through a shell, and what it writes to standard output is
+// parsed as a document of format, one of SupportedExts. A command that cannot be
+// started, exits with a non-zero status or outlives its timeout fails with a
+// CommandError carrying the end of its standard error. Output is limited like config
+// files, see SetMaxConfigSize.
+func AddCommandSource(name string, argv []string, format string, opts ...CommandOption) error {
+    return c.AddCommandSource(name, argv, format, opts...)
+}
+func (c *Config) AddCommandSource(name string, argv []string, format string, opts ...CommandOption) error {
+    if len(argv) == 0 {
+        return CommandError{Name: name, Err: errors.New("No command given")}
+    }
+    if !stringInSlice(strings.ToLower(format), SupportedExts) {
+        return UnsupportedConfigError(format)
+    }
+
+    o := commandOptions{timeout: DefaultCommandTimeout}
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    argv = append([]string(nil), argv...)
+    fetch := func(ctx context.Context) (map[string]interface{}, error) {
+        return c.runCommand(ctx, name, argv, format, o.timeout)
+    }
+
+    return c.addSourceContext(context.Background(), name, fetch, o.refresh)
+}
+
+// runCommand runs argv and parses its output as a document of format.
+func (c *Config) runCommand(ctx context.Context, name string, argv []string, format string, timeout time.Duration) (map[string]interface{}, error) {
+    if timeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+        defer cancel()
+    }
+    ctx, kill := context.WithCancel(ctx)
+    defer kill()
+
+    cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+    stderr := &tailBuffer{max: 1024}
+    cmd.Stderr = stderr
+    stdout, err := cmd.StdoutPipe()
+    if err != nil {
+        return nil, CommandError{Name: name, Argv: argv, Err: err}
+    }
+
+    c.logInfo("Running command source", name)
+    if err := cmd.Start(); err != nil {
+        return nil, CommandError{Name: name, Argv: argv, Err: err}
+    }
+
+    out, readErr := c.readConfigFrom(name, stdout, 0)
+    if readErr != nil {
+        // Output too large, there is no point in letting the command finish.
+        kill()
+    }
+    err = cmd.Wait()
+
+    switch {
+    case errors.Is(ctx.Err(), context.DeadlineExceeded):
+        return nil, CommandError{Name: name, Argv: argv, Err: ctx.Err(), Stderr: stderr.String()}
+    case readErr != nil:
+        return nil, readErr
+    case err != nil:
+        return nil, CommandError{Name: name, Argv: argv, Err: err, Stderr: stderr.String()}
+    }
+
+    values, err := c.parseLimited(bytes.NewReader(out), format)
+    if err != nil {
+        return nil, parseErrorIn(err, name)
+    }
+
+    return values, nil
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+    max int
+    buf []byte
+}
+
+func (tb *tailBuffer) Write(p []byte) (int, error) {
+    tb.buf = append(tb.buf, p...)
+    if len(tb.buf) > tb.max {
+        tb.buf = tb.buf[len(tb.buf)-tb.max:]
+    }
+    return len(p), nil
+}
+
+// Returns the bytes kept, trimmed of surrounding whitespace.
+func (tb *tailBuffer) String() string {
+    return strings.TrimSpace(string(tb.buf))
+}
@@ -0,0 +1,67 @@
+package cfg
+
+// Computes the value of a key when it is read, see SetComputed.
+type ComputeFunc func(c *Config) interface{}
+
+// Value of a computed key, stored in the computed layer.
+type computedValue struct {
+    fn ComputeFunc
+}
+
+// Makes the value of key computed by fn each time it is read, so it can be derived from
+// other keys or the environment, like an address from a host and a port:
+//
+//     SetComputed("addr", func(c *Config) interface{} {
+//         return net.JoinHostPort(c.GetString("host"), c.GetString("port"))
+//     })
+//
+// Computed keys take precedence over every layer but the overrides, so Set still
+// replaces them. fn must not read key itself, and a nil result leaves key to the lower
+// layers. Computed keys are left out of the maps returned for the keys above them, such
+// as by Sub and GetStringMap. A nil fn removes the computed key.
+func SetComputed(key string, fn ComputeFunc) { c.SetComputed(key, fn) }
+func (c *Config) SetComputed(key string, fn ComputeFunc) {
+    c.mustNotBeFrozen()
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    defer c.invalidate()
+
+    key = c.realKey(c.normalizeKey(key))
+    if fn == nil {
+        delete(c.computed, key)
+        return
+    }
+
+    c.computed[key] = computedValue{fn}
+}
+
+// compute returns val, found for key in layer l, or its computed value and the layer
+// below l holding key if the computed value is nil.
+func (c *Config) compute(key string, val interface{}, l layer) (interface{}, layer) {
+    cv, ok := val.(computedValue)
+    if !ok {
+        return val, l
+    }
+
+    if val := cv.fn(c); val != nil {
+        return val, l
+    }
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    key = c.realKey(key)
+    aliases := c.aliasNames(key)
+    for _, below := range c.layers() {
+        if below.priority >= PriorityComputed {
+            continue
+        }
+        if val, exists := c.searchAliased(below.values, key, aliases); exists {
+            return val, below
+        }
+    }
+
+    return nil, layer{}
+}
@@ -0,0 +1,86 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "path/filepath"
+    "strings"
+)
+
+// Adds a drop-in directory, conf.d style, and merges it into the current config.
+//
+// Every supported file in the directory is read in lexical order and deep-merged over
+// the config, so 20-db.yaml overrides 10-base.yaml. Hidden files are skipped. Drop-ins
+// are checked, decrypted and rendered like the config file, so 30-secrets.yaml.age is
+// read too when a cipher is registered for age, see SetFileCipher. The
+// directory is merged again over the config file on every ReadInConfig, so drop-ins
+// survive reloads. Directories added later take precedence.
+func AddConfigDir(path string) error { return c.AddConfigDir(path) }
+func (c *Config) AddConfigDir(path string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    dir := c.absPathify(path)
+
+    c.updateMu.Lock()
+    defer c.updateMu.Unlock()
+
+    config, origins := c.copyConfig(), c.copyOrigins()
+    if err := c.mergeConfigDir(context.Background(), config, origins, dir); err != nil {
+        return err
+    }
+
+    if err := c.validate(config); err != nil {
+        return err
+    }
+
+    if !stringInSlice(dir, c.configDirs) {
+        c.configDirs = append(c.configDirs, dir)
+    }
+    c.setConfig(config, nil, origins)
+
+    return nil
+}
+
+// mergeConfigDir deep-merges every supported file in dir into config, recording where
+// their keys come from in origins.
+func (c *Config) mergeConfigDir(ctx context.Context, config map[string]interface{}, origins map[string]fileOrigin, dir string) error {
+    files, err := c.readDir(dir)
+    if err != nil {
+        return fmt.Errorf("Cannot read config dir %q: %w", dir, err)
+    }
+
+    // ReadDir returns entries sorted by name.
+    for _, f := range files {
+        plain, _ := c.cipherFor(f.Name())
+        ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(plain), "."))
+        if strings.HasPrefix(f.Name(), ".") || f.IsDir() || !stringInSlice(ext, SupportedExts) {
+            continue
+        }
+
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        name := filepath.Join(dir, f.Name())
+        file, _, err := c.loadConfigFile(ctx, name)
+        if err != nil {
+            return err
+        }
+
+        c.logInfo("Merging drop-in config", name)
+        src, err := c.decodeConfig(bytes.NewReader(file), ext)
+        if err != nil {
+            return parseErrorIn(err, name)
+        }
+
+        mergeMapsWith(config, src, c.mergeOptions(nil))
+        for k, v := range c.fileOrigins(src, file, ext, name) {
+            origins[k] = v
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,130 @@
+package cfg
+
+import (
+    "time"
+
+    "github.com/spf13/cast"
+)
+
+// The typed getters convert values of the type they return with a type assertion, and
+// only hand other values to cast, which inspects them through reflection.
+
+func toString(v interface{}) string {
+    if s, ok := v.(string); ok {
+        return s
+    }
+    return cast.ToString(v)
+}
+
+func toBool(v interface{}) bool {
+    if b, ok := v.(bool); ok {
+        return b
+    }
+    return cast.ToBool(v)
+}
+
+func toInt(v interface{}) int {
+    if i, ok := v.(int); ok {
+        return i
+    }
+    return cast.ToInt(v)
+}
+
+func toInt32(v interface{}) int32 {
+    switch i := v.(type) {
+    case int32:
+        return i
+    case int:
+        return int32(i)
+    }
+    return cast.ToInt32(v)
+}
+
+func toInt64(v interface{}) int64 {
+    switch i := v.(type) {
+    case int64:
+        return i
+    case int:
+        return int64(i)
+    }
+    return cast.ToInt64(v)
+}
+
+func toUint(v interface{}) uint {
+    if u, ok := v.(uint); ok {
+        return u
+    }
+    return cast.ToUint(v)
+}
+
+func toUint32(v interface{}) uint32 {
+    if u, ok := v.(uint32); ok {
+        return u
+    }
+    return cast.ToUint32(v)
+}
+
+func toUint64(v interface{}) uint64 {
+    if u, ok := v.(uint64); ok {
+        return u
+    }
+    return cast.ToUint64(v)
+}
+
+func toFloat64(v interface{}) float64 {
+    switch f := v.(type) {
+    case float64:
+        return f
+    case int:
+        return float64(f)
+    }
+    return cast.ToFloat64(v)
+}
+
+func toStringSlice(v interface{}) []string {
+    if s, ok := v.([]string); ok {
+        return s
+    }
+    return cast.ToStringSlice(v)
+}
+
+// isConverted reports whether val already has the type lookup converts values to when
+// their type is taken from valType, so it is returned as is.
+func isConverted(val, valType interface{}) bool {
+    var ok bool
+
+    switch valType.(type) {
+    case bool:
+        _, ok = val.(bool)
+    case string:
+        _, ok = val.(string)
+    case int64:
+        _, ok = val.(int64)
+    case int32:
+        _, ok = val.(int32)
+    case int16, int8, int:
+        _, ok = val.(int)
+    case uint64:
+        _, ok = val.(uint64)
+    case uint32:
+        _, ok = val.(uint32)
+    case uint16, uint8, uint:
+        _, ok = val.(uint)
+    case float64, float32:
+        _, ok = val.(float64)
+    case time.Duration:
+        _, ok = val.(time.Duration)
+    case []string:
+        _, ok = val.([]string)
+    case []int:
+        _, ok = val.([]int)
+    case []interface{}:
+        _, ok = val.([]interface{})
+    case map[string]interface{}:
+        _, ok = val.(map[string]interface{})
+    case map[string]string:
+        _, ok = val.(map[string]string)
+    }
+
+    return ok
+}
@@ -0,0 +1,181 @@
+package cfg
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// Returns a Config holding a flat key of every type with a typed getter.
+func typedConfig() *Config {
+    c := New()
+    c.SetDefault("string", "value")
+    c.SetDefault("bool", true)
+    c.SetDefault("int", 42)
+    c.SetDefault("int64", int64(42))
+    c.SetDefault("float64", 4.2)
+    c.SetDefault("duration", time.Second)
+    c.SetDefault("strings", []string{"a", "b"})
+
+    return c
+}
+
+// Typed getters reading values of the type they return must not allocate.
+func TestTypedGettersDoNotAllocate(t *testing.T) {
+    c := typedConfig()
+
+    getters := map[string]func(){
+        "GetString":      func() { c.GetString("string") },
+        "GetBool":        func() { c.GetBool("bool") },
+        "GetInt":         func() { c.GetInt("int") },
+        "GetInt64":       func() { c.GetInt64("int64") },
+        "GetFloat64":     func() { c.GetFloat64("float64") },
+        "GetDuration":    func() { c.GetDuration("duration") },
+        "GetStringSlice": func() { c.GetStringSlice("strings") },
+    }
+
+    for name, get := range getters {
+        get()
+        if allocs := testing.AllocsPerRun(100, get); allocs != 0 {
+            t.Errorf("%s allocates %v times per call, want 0", name, allocs)
+        }
+    }
+}
+
+func BenchmarkGetters(b *testing.B) {
+    c := typedConfig()
+
+    benchmarks := []struct {
+        name string
+        get  func()
+    }{
+        {"String", func() { c.GetString("string") }},
+        {"Bool", func() { c.GetBool("bool") }},
+        {"Int", func() { c.GetInt("int") }},
+        {"Int64", func() { c.GetInt64("int64") }},
+        {"Float64", func() { c.GetFloat64("float64") }},
+        {"Duration", func() { c.GetDuration("duration") }},
+        {"StringSlice", func() { c.GetStringSlice("strings") }},
+    }
+
+    for _, bm := range benchmarks {
+        b.Run(bm.name, func(b *testing.B) {
+            b.ReportAllocs()
+            for i := 0; i < b.N; i++ {
+                bm.get()
+            }
+        })
+    }
+}
+
+// Reads a string key converted to the type of its default, as typeByDefValue does.
+func BenchmarkGetIntByDefault(b *testing.B) {
+    c := typedConfig()
+    c.typeByDefValue = true
+    c.Set("int", "42")
+    b.ReportAllocs()
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        c.GetInt("int")
+    }
+}
+
+type level int
+
+// Decodes level names into levels.
+func levelHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+    if from.Kind() != reflect.String || to != reflect.TypeOf(level(0)) {
+        return data, nil
+    }
+
+    switch data.(string) {
+    case "debug":
+        return level(0), nil
+    case "info":
+        return level(1), nil
+    }
+
+    return nil, fmt.Errorf("unknown level %q", data)
+}
+
+// Values are converted to the type of the default of their key.
+func TestTypeByDefaultValue(t *testing.T) {
+    tests := []struct {
+        name string
+        def  interface{}
+        val  interface{}
+        want interface{}
+    }{
+        {"string", "x", 42, "42"},
+        {"bool", false, "true", true},
+        {"int", 0, "42", 42},
+        {"int32", int32(0), "42", int32(42)},
+        {"int64", int64(0), "42", int64(42)},
+        {"uint", uint(0), "42", uint(42)},
+        {"float64", 0.0, "4.2", 4.2},
+        {"duration", time.Second, "5s", 5 * time.Second},
+        {"time", time.Time{}, "2024-01-02T03:04:05Z", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+        {"strings", []string{}, "a b", []string{"a", "b"}},
+        {"ints", []int{}, []interface{}{"1", 2}, []int{1, 2}},
+        {"floats", []float64{}, []interface{}{"1.5", 2}, []float64{1.5, 2}},
+        {"bools", []bool{}, []interface{}{"true", false}, []bool{true, false}},
+        {"durations", []time.Duration{}, []interface{}{"1s", "1m"}, []time.Duration{time.Second, time.Minute}},
+        {"slice", []interface{}{}, []interface{}{1, "a"}, []interface{}{1, "a"}},
+        {"map", map[string]interface{}{}, `{"a": 1}`, map[string]interface{}{"a": float64(1)}},
+        {"string map", map[string]string{}, map[string]interface{}{"a": 1}, map[string]string{"a": "1"}},
+        {"string slice map", map[string][]string{}, map[string]interface{}{"a": "x"}, map[string][]string{"a": {"x"}}},
+        {"int map", map[string]int{}, map[string]interface{}{"a": "1"}, map[string]int{"a": 1}},
+        {"int64 map", map[string]int64{}, map[string]interface{}{"a": "1"}, map[string]int64{"a": 1}},
+        {"bool map", map[string]bool{}, map[string]interface{}{"a": "true"}, map[string]bool{"a": true}},
+        {"hook", level(0), "info", level(1)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.RegisterDecodeHook(levelHook)
+            c.SetDefault("key", tt.def)
+            c.Set("key", tt.val)
+
+            if got := c.Get("key"); !reflect.DeepEqual(got, tt.val) {
+                t.Errorf("Get without SetTypeByDefaultValue = %#v, want %#v", got, tt.val)
+            }
+
+            c.SetTypeByDefaultValue(true)
+            if got := c.Get("key"); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("Get = %#v, want %#v", got, tt.want)
+            }
+        })
+    }
+}
+
+// Values that cannot be converted to the type of their default are reported by GetE.
+func TestTypeByDefaultValueMismatch(t *testing.T) {
+    tests := []struct {
+        name string
+        def  interface{}
+        val  interface{}
+    }{
+        {"int", 0, "many"},
+        {"ints", []int{}, []interface{}{"one"}},
+        {"int map", map[string]int{}, "a=1"},
+        {"hook", level(0), "loud"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.RegisterDecodeHook(levelHook)
+            c.SetStrictTypes(true)
+            c.SetDefault("key", tt.def)
+            c.Set("key", tt.val)
+
+            if _, err := c.GetE("key"); !errors.As(err, new(TypeMismatchError)) {
+                t.Errorf("GetE error = %v, want a TypeMismatchError", err)
+            }
+        })
+    }
+}
@@ -0,0 +1,146 @@
+package cfg
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strings"
+
+    "github.com/kr/pretty"
+    "gopkg.in/yaml.v2"
+)
+
+// Formats DebugTo writes in.
+const (
+    DebugPretty = "pretty"
+    DebugJSON   = "json"
+    DebugYAML   = "yaml"
+)
+
+// What DebugTo writes.
+type debugReport struct {
+    Aliases  map[string]string `json:"aliases" yaml:"aliases"`
+    Layers   []debugLayer      `json:"layers" yaml:"layers"`
+    Settings []debugSetting    `json:"settings" yaml:"settings"`
+}
+
+type debugLayer struct {
+    Kind     LayerKind              `json:"kind" yaml:"kind"`
+    Name     string                 `json:"name" yaml:"name"`
+    Priority int                    `json:"priority" yaml:"priority"`
+    Values   map[string]interface{} `json:"values" yaml:"values"`
+}
+
+type debugSetting struct {
+    Key   string      `json:"key" yaml:"key"`
+    Value interface{} `json:"value" yaml:"value"`
+    Kind  LayerKind   `json:"kind" yaml:"kind"`
+    Layer string      `json:"layer" yaml:"layer"`
+    File  string      `json:"file,omitempty" yaml:"file,omitempty"`
+    Line  int         `json:"line,omitempty" yaml:"line,omitempty"`
+}
+
+// Prints the aliases, the values of every layer and the effective value of every key
+// with its origin to standard output, for debugging purposes, see DebugTo.
+func Debug() { c.Debug() }
+func (c *Config) Debug() {
+    c.DebugTo(os.Stdout, DebugPretty)
+}
+
+// Writes the aliases, the values of every layer in lookup order and the effective value
+// of every key along with where it comes from, see Origin, to w, so diagnostics can go
+// to a log or an HTTP response. format is DebugPretty for people, or DebugJSON or
+// DebugYAML for tools. The values of secret keys are redacted, see MarkSecret.
+func DebugTo(w io.Writer, format string) error { return c.DebugTo(w, format) }
+func (c *Config) DebugTo(w io.Writer, format string) error {
+    var report debugReport
+
+    c.mu.RLock()
+    report.Aliases = make(map[string]string, len(c.aliases))
+    for alias, key := range c.aliases {
+        report.Aliases[alias] = key
+    }
+    for _, l := range c.layers() {
+        values := normalizeMaps(c.redact(l.values, "")).(map[string]interface{})
+        if l.kind == LayerComputed {
+            for key := range values {
+                values[key] = "(computed)"
+            }
+        }
+        report.Layers = append(report.Layers, debugLayer{l.kind, l.name, l.priority, values})
+    }
+    c.mu.RUnlock()
+
+    c.Walk(func(key string, value interface{}, origin KeyOrigin) error {
+        report.Settings = append(report.Settings, debugSetting{
+            Key:   key,
+            Value: c.redactKey(key, value),
+            Kind:  origin.Kind,
+            Layer: origin.Layer,
+            File:  origin.File,
+            Line:  origin.Line,
+        })
+        return nil
+    })
+
+    switch strings.ToLower(format) {
+    case DebugPretty, "":
+        return report.writePretty(w)
+    case DebugJSON:
+        enc := json.NewEncoder(w)
+        enc.SetIndent("", "  ")
+        return enc.Encode(report)
+    case DebugYAML, "yml":
+        b, err := yaml.Marshal(report)
+        if err != nil {
+            return err
+        }
+        _, err = w.Write(b)
+        return err
+    }
+
+    return fmt.Errorf("Unsupported debug format %q", format)
+}
+
+// writePretty writes the report as indented text.
+func (r debugReport) writePretty(w io.Writer) error {
+    var b strings.Builder
+
+    b.WriteString("Aliases:\n")
+    for _, alias := range sortedAliases(r.Aliases) {
+        fmt.Fprintf(&b, "  %s -> %s\n", alias, r.Aliases[alias])
+    }
+
+    b.WriteString("Layers:\n")
+    for _, l := range r.Layers {
+        fmt.Fprintf(&b, "  %s %q, priority %d:\n", l.Kind, l.Name, l.Priority)
+        for _, line := range strings.Split(pretty.Sprint(l.Values), "\n") {
+            fmt.Fprintf(&b, "    %s\n", line)
+        }
+    }
+
+    b.WriteString("Settings:\n")
+    for _, s := range r.Settings {
+        o := KeyOrigin{Kind: s.Kind, Layer: s.Layer, File: s.File, Line: s.Line}
+        if str, ok := s.Value.(string); ok {
+            fmt.Fprintf(&b, "  %s = %q (%s)\n", s.Key, str, o)
+        } else {
+            fmt.Fprintf(&b, "  %s = %v (%s)\n", s.Key, s.Value, o)
+        }
+    }
+
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+func sortedAliases(aliases map[string]string) []string {
+    names := make([]string, 0, len(aliases))
+    for alias := range aliases {
+        names = append(names, alias)
+    }
+    sort.Strings(names)
+
+    return names
+}
@@ -0,0 +1,183 @@
+package cfg
+
+import (
+    "errors"
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/mitchellh/mapstructure"
+)
+
+// Sets the struct tag Unmarshal and its variants read field names from, "mapstructure"
+// by default, so structs already tagged for another encoding such as json or yaml can be
+// reused.
+func SetTagName(name string) { c.SetTagName(name) }
+func (c *Config) SetTagName(name string) {
+    c.tagName = name
+}
+
+func (c *Config) getTagName() string {
+    if c.tagName == "" {
+        return "mapstructure"
+    }
+    return c.tagName
+}
+
+// Registers a mapstructure decode hook, run before the built-in ones, so Unmarshal and its
+// variants can decode values into types of the application, such as a log level parsed
+// from its name. With SetTypeByDefaultValue, values of keys whose default has a type no
+// getter converts to are decoded into that type with the registered hooks too.
+func RegisterDecodeHook(hook mapstructure.DecodeHookFunc) { c.RegisterDecodeHook(hook) }
+func (c *Config) RegisterDecodeHook(hook mapstructure.DecodeHookFunc) {
+    c.decodeHooks = append(c.decodeHooks, hook)
+    c.invalidate()
+}
+
+// decodeHook returns the registered decode hooks followed by builtin, nil if there are
+// none.
+func (c *Config) decodeHook(builtin ...mapstructure.DecodeHookFunc) mapstructure.DecodeHookFunc {
+    hooks := append(append([]mapstructure.DecodeHookFunc(nil), c.decodeHooks...), builtin...)
+    if len(hooks) == 0 {
+        return nil
+    }
+
+    return mapstructure.ComposeDecodeHookFunc(hooks...)
+}
+
+// decoderConfig returns the configuration every decode into a struct starts from.
+func (c *Config) decoderConfig(rawVal interface{}, weak bool) *mapstructure.DecoderConfig {
+    config := &mapstructure.DecoderConfig{
+        Result:           rawVal,
+        WeaklyTypedInput: weak,
+        TagName:          c.getTagName(),
+        DecodeHook:       c.decodeHook(),
+    }
+    if c.extendedDurations {
+        config.DecodeHook = c.decodeHook(c.durationHook())
+    }
+
+    return config
+}
+
+// decodeLike decodes val into a value of the type of like, with the registered decode
+// hooks. Values of that type already are returned as they are.
+func (c *Config) decodeLike(val, like interface{}) (interface{}, error) {
+    t := reflect.TypeOf(like)
+    if reflect.TypeOf(val) == t {
+        return val, nil
+    }
+
+    out := reflect.New(t)
+    if err := c.decode(val, out.Interface(), true); err != nil {
+        return nil, err
+    }
+
+    return out.Elem().Interface(), nil
+}
+
+// decode decodes input into rawVal, weakly typed if weak is set.
+func (c *Config) decode(input interface{}, rawVal interface{}, weak bool) error {
+    decoder, err := mapstructure.NewDecoder(c.decoderConfig(rawVal, weak))
+    if err != nil {
+        return err
+    }
+
+    return decoder.Decode(input)
+}
+
+// Denotes values that do not have the type of the field they were decoded into, one
+// message per field.
+type DecodeErrors []string
+
+// Returns the formatted decode errors, one per line.
+func (de DecodeErrors) Error() string {
+    return fmt.Sprintf("Cannot decode config:\n%s", strings.Join(de, "\n"))
+}
+
+// Like Unmarshal, but without weak typing: "true" is not turned into 1 nor numbers into
+// strings. Every field whose value has the wrong type is reported in a DecodeErrors.
+// Durations may still be given as strings such as "5s".
+func UnmarshalStrict(rawVal interface{}) error { return c.UnmarshalStrict(rawVal) }
+func (c *Config) UnmarshalStrict(rawVal interface{}) error {
+    if err := c.decodeStrict(c.AllSettingsNested(), rawVal); err != nil {
+        return err
+    }
+    c.recordFields(rawVal)
+
+    return c.validateStruct(rawVal, "")
+}
+
+// Like UnmarshalKey, but reports every field whose value has the wrong type in a
+// DecodeErrors, see UnmarshalStrict.
+func UnmarshalKeyStrict(key string, rawVal interface{}) error {
+    return c.UnmarshalKeyStrict(key, rawVal)
+}
+func (c *Config) UnmarshalKeyStrict(key string, rawVal interface{}) error {
+    if err := c.decodeStrict(c.effectiveValue(key), rawVal); err != nil {
+        return err
+    }
+
+    return c.validateStruct(rawVal, c.normalizeKey(key))
+}
+
+func (c *Config) decodeStrict(input interface{}, rawVal interface{}) error {
+    config := c.decoderConfig(rawVal, false)
+    config.DecodeHook = c.decodeHook(c.durationHook())
+
+    decoder, err := mapstructure.NewDecoder(config)
+    if err != nil {
+        return err
+    }
+
+    err = decoder.Decode(input)
+
+    var me *mapstructure.Error
+    if errors.As(err, &me) {
+        return DecodeErrors(me.Errors)
+    }
+
+    return err
+}
+
+// walkStruct calls fn with the key path and field of every leaf field of t, following
+// the tags Unmarshal reads. Nested structs other than time.Time are descended into,
+// squashed ones without adding to the path.
+func (c *Config) walkStruct(t reflect.Type, prefix []string, fn func(path []string, f reflect.StructField)) {
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        if f.PkgPath != "" {
+            continue
+        }
+
+        name, opts := f.Name, ""
+        if tag := f.Tag.Get(c.getTagName()); tag != "" {
+            parts := strings.SplitN(tag, ",", 2)
+            if parts[0] == "-" {
+                continue
+            }
+            if parts[0] != "" {
+                name = parts[0]
+            }
+            if len(parts) > 1 {
+                opts = parts[1]
+            }
+        }
+
+        ft := f.Type
+        for ft.Kind() == reflect.Ptr {
+            ft = ft.Elem()
+        }
+
+        if ft.Kind() == reflect.Struct && ft.String() != "time.Time" {
+            if strings.Contains(opts, "squash") {
+                c.walkStruct(ft, prefix, fn)
+            } else {
+                c.walkStruct(ft, append(append([]string{}, prefix...), c.normalizeKey(name)), fn)
+            }
+            continue
+        }
+
+        fn(append(append([]string{}, prefix...), c.normalizeKey(name)), f)
+    }
+}
@@ -0,0 +1,86 @@
+package cfg
+
+import (
+    "fmt"
+    "reflect"
+    "strings"
+
+    "github.com/mitchellh/mapstructure"
+)
+
+// Registers a default for every field of the struct v, or the struct it points to, that
+// has a default tag. Keys are derived from the tags Unmarshal reads and the nesting of
+// the struct, and the tag is converted to the type of the field, so
+//
+//     Timeout time.Duration `default:"5s"`
+//     Hosts   []string      `default:"a,b"`
+//
+// registers a time.Duration and a []string. Keeping defaults next to the struct the
+// config is unmarshalled into avoids scattering SetDefault calls.
+func SetDefaultsFromStruct(v interface{}) error { return c.SetDefaultsFromStruct(v) }
+func (c *Config) SetDefaultsFromStruct(v interface{}) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    t := reflect.TypeOf(v)
+    for t != nil && t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    if t == nil || t.Kind() != reflect.Struct {
+        return fmt.Errorf("Defaults must come from a struct, got %T", v)
+    }
+
+    var errs []string
+    defaults := make(map[string]interface{})
+
+    c.walkStruct(t, nil, func(path []string, f reflect.StructField) {
+        tag, ok := f.Tag.Lookup("default")
+        if !ok {
+            return
+        }
+
+        key := strings.Join(path, c.keyDelm)
+        val, err := c.convertDefault(tag, f.Type)
+        if err != nil {
+            errs = append(errs, fmt.Sprintf("%s: %s", key, err.Error()))
+            return
+        }
+        defaults[key] = val
+    })
+
+    if len(errs) > 0 {
+        return fmt.Errorf("Invalid default tags:\n%s", strings.Join(errs, "\n"))
+    }
+
+    for key, val := range defaults {
+        c.SetDefault(key, val)
+    }
+
+    return nil
+}
+
+// convertDefault converts the text of a default tag to a value of type t.
+func (c *Config) convertDefault(tag string, t reflect.Type) (interface{}, error) {
+    for t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+
+    out := reflect.New(t)
+    config := c.decoderConfig(out.Interface(), true)
+    config.DecodeHook = c.decodeHook(
+        c.durationHook(),
+        mapstructure.StringToSliceHookFunc(","),
+        mapstructure.StringToTimeHookFunc("2006-01-02T15:04:05Z07:00"),
+    )
+
+    decoder, err := mapstructure.NewDecoder(config)
+    if err != nil {
+        return nil, err
+    }
+    if err := decoder.Decode(tag); err != nil {
+        return nil, err
+    }
+
+    return out.Elem().Interface(), nil
+}
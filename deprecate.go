@@ -0,0 +1,148 @@
+package cfg
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+)
+
+// Describes a key that has been renamed.
+type Deprecation struct {
+    // The deprecated key.
+    Key string
+
+    // The key that replaces it.
+    NewKey string
+
+    Message string
+}
+
+// Returns the formatted deprecation warning.
+func (d Deprecation) String() string {
+    s := fmt.Sprintf("Config key %q is deprecated, use %q instead", d.Key, d.NewKey)
+    if d.Message != "" {
+        s += ": " + d.Message
+    }
+    return s
+}
+
+type deprecation struct {
+    Deprecation
+    warn sync.Once
+}
+
+// Deprecates oldKey in favour of newKey. Like an alias, reading oldKey resolves newKey,
+// and values still stored under oldKey, or beneath it, are found when newKey is read.
+// Either use warns once per deprecated key, see OnDeprecatedKey. Deprecating a key in
+// favour of one that resolves back to it, through aliases or other deprecations, fails
+// with an AliasCycleError.
+func Deprecate(oldKey, newKey, message string) error { return c.Deprecate(oldKey, newKey, message) }
+func (c *Config) Deprecate(oldKey, newKey, message string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    oldKey, newKey = c.normalizeKey(oldKey), c.normalizeKey(newKey)
+
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    if chain := c.keyCycle(oldKey, newKey); chain != nil {
+        return AliasCycleError{oldKey, newKey, chain}
+    }
+
+    c.deprecations = append(c.deprecations, &deprecation{
+        Deprecation: Deprecation{
+            Key:     oldKey,
+            NewKey:  newKey,
+            Message: message,
+        },
+    })
+    c.invalidate()
+
+    return nil
+}
+
+// Registers a function called the first time each deprecated key is used, instead of
+// logging the warning.
+func OnDeprecatedKey(run func(d Deprecation)) { c.OnDeprecatedKey(run) }
+func (c *Config) OnDeprecatedKey(run func(d Deprecation)) {
+    c.onDeprecated = append(c.onDeprecated, run)
+}
+
+// Returns the deprecated keys that still hold a value in any layer, so users can be told
+// which renames they have yet to apply.
+func DeprecatedKeysInUse() []string { return c.DeprecatedKeysInUse() }
+func (c *Config) DeprecatedKeysInUse() []string {
+    var keys []string
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    layers := c.layers()
+    for _, d := range c.deprecations {
+        for _, l := range layers {
+            if _, exists := c.searchLayer(l.values, d.Key); exists {
+                keys = append(keys, d.Key)
+                break
+            }
+        }
+    }
+
+    return keys
+}
+
+// undeprecate maps a key at or beneath a deprecated key to its replacement. Caller must
+// hold mu.
+func (c *Config) undeprecate(key string) (string, *deprecation) {
+    for _, d := range c.deprecations {
+        if rest, ok := c.trimKeyPrefix(key, d.Key); ok {
+            return d.NewKey + rest, d
+        }
+    }
+
+    return key, nil
+}
+
+// deprecatedNames returns the deprecated keys values of key may still be stored under.
+// Caller must hold mu.
+func (c *Config) deprecatedNames(key string) ([]string, []*deprecation) {
+    var (
+        names []string
+        deps  []*deprecation
+    )
+
+    for _, d := range c.deprecations {
+        if rest, ok := c.trimKeyPrefix(key, c.realKey(d.NewKey)); ok {
+            names = append(names, d.Key+rest)
+            deps = append(deps, d)
+        }
+    }
+
+    return names, deps
+}
+
+// trimKeyPrefix reports whether key is prefix or lies beneath it, returning the rest of
+// the key including its leading delimiter.
+func (c *Config) trimKeyPrefix(key, prefix string) (string, bool) {
+    if key == prefix {
+        return "", true
+    }
+    if strings.HasPrefix(key, prefix+c.keyDelm) {
+        return key[len(prefix):], true
+    }
+    return "", false
+}
+
+func (c *Config) warnDeprecated(d *deprecation) {
+    d.warn.Do(func() {
+        if len(c.onDeprecated) == 0 {
+            c.logWarn(d.Deprecation.String())
+            return
+        }
+
+        for _, run := range c.onDeprecated {
+            run(d.Deprecation)
+        }
+    })
+}
@@ -0,0 +1,72 @@
+package cfg
+
+import (
+    "errors"
+    "testing"
+)
+
+func TestDeprecateCycle(t *testing.T) {
+    tests := []struct {
+        name  string
+        setup func(c *Config) error
+    }{
+        {"deprecations", func(c *Config) error {
+            return c.Deprecate("a", "b", "")
+        }},
+        {"alias and deprecation", func(c *Config) error {
+            return c.RegisterAlias("a", "b")
+        }},
+        {"nested", func(c *Config) error {
+            return c.Deprecate("a", "b.c", "")
+        }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            if err := tt.setup(c); err != nil {
+                t.Fatal(err)
+            }
+
+            var cycle AliasCycleError
+            if err := c.Deprecate("b", "a", ""); !errors.As(err, &cycle) {
+                t.Fatalf("Deprecate(b, a) = %v, want AliasCycleError", err)
+            }
+
+            c.Set("a", "value")
+            if got := c.GetString("a"); got != "value" {
+                t.Errorf("GetString(a) = %q, want value", got)
+            }
+        })
+    }
+}
+
+func TestDeprecateFrozen(t *testing.T) {
+    c := New()
+    c.Freeze()
+
+    if err := c.Deprecate("a", "b", ""); !errors.Is(err, ErrFrozen) {
+        t.Errorf("Deprecate on frozen config = %v, want ErrFrozen", err)
+    }
+}
+
+func TestDeprecateResolves(t *testing.T) {
+    c := New()
+    c.config["old"] = map[string]interface{}{"host": "localhost"}
+    if err := c.Deprecate("old", "new", "renamed"); err != nil {
+        t.Fatal(err)
+    }
+
+    var warned []Deprecation
+    c.OnDeprecatedKey(func(d Deprecation) { warned = append(warned, d) })
+
+    if got := c.GetString("new.host"); got != "localhost" {
+        t.Errorf("GetString(new.host) = %q, want localhost", got)
+    }
+    if got := c.DeprecatedKeysInUse(); len(got) != 1 || got[0] != "old" {
+        t.Errorf("DeprecatedKeysInUse() = %v, want [old]", got)
+    }
+    if len(warned) != 1 || warned[0].NewKey != "new" {
+        t.Errorf("warned %v, want one warning for old", warned)
+    }
+}
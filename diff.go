@@ -0,0 +1,91 @@
+package cfg
+
+import (
+    "sort"
+)
+
+// Describes a key whose value differs between two configs.
+type KeyChange struct {
+    Key string
+
+    // Value before, nil for an added key
+    Old interface{}
+
+    // Value after, nil for a removed key
+    New interface{}
+}
+
+// Lists the keys that differ between two configs, each list sorted by key. Keys are
+// those AllKeys lists, so a changed map shows up as the keys beneath it that changed.
+type ConfigDiff struct {
+    Added   []KeyChange
+    Removed []KeyChange
+    Changed []KeyChange
+}
+
+// Returns whether no key differs.
+func (d ConfigDiff) Empty() bool {
+    return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Returns the keys that differ, added, removed or changed, in order.
+func (d ConfigDiff) Keys() []string {
+    keys := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+    for _, changes := range [][]KeyChange{d.Added, d.Removed, d.Changed} {
+        for _, change := range changes {
+            keys = append(keys, change.Key)
+        }
+    }
+    sort.Strings(keys)
+
+    return keys
+}
+
+// Returns the keys added, removed and changed going from the effective settings of a
+// to those of b. Values are compared once maps and numbers are normalized, so 1 read
+// from YAML equals 1 read from JSON. With WithRedaction the values of keys secret in
+// either config are replaced in the result, while still being compared as they are.
+func Diff(a, b *Config, opts ...SettingsOption) ConfigDiff {
+    var o settingsOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    before, after := a.AllSettings(), b.AllSettings()
+
+    keys := sortedKeys(after)
+    for key := range before {
+        if _, exists := after[key]; !exists {
+            keys = append(keys, key)
+        }
+    }
+    sort.Strings(keys)
+
+    var d ConfigDiff
+    for _, key := range keys {
+        oldVal, inBefore := before[key]
+        newVal, inAfter := after[key]
+        if o.redact && (a.IsSecret(key) || b.IsSecret(key)) {
+            oldVal, newVal = redactedValue, redactedValue
+        }
+
+        switch {
+        case !inBefore:
+            d.Added = append(d.Added, KeyChange{Key: key, New: newVal})
+        case !inAfter:
+            d.Removed = append(d.Removed, KeyChange{Key: key, Old: oldVal})
+        case !sameValue(before[key], after[key]):
+            d.Changed = append(d.Changed, KeyChange{Key: key, Old: oldVal, New: newVal})
+        }
+    }
+
+    return d
+}
+
+// Returns the keys added, removed and changed since prev was taken, see Diff.
+func DiffSnapshot(prev Snapshot, opts ...SettingsOption) ConfigDiff {
+    return c.DiffSnapshot(prev, opts...)
+}
+func (c *Config) DiffSnapshot(prev Snapshot, opts ...SettingsOption) ConfigDiff {
+    return Diff(prev.c, c, opts...)
+}
@@ -0,0 +1,209 @@
+package cfg
+
+import (
+    "fmt"
+    "math"
+    "reflect"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/mitchellh/mapstructure"
+    "github.com/spf13/cast"
+)
+
+// Lengths of the units ParseDuration accepts beyond those of time.ParseDuration.
+const (
+    Day  = 24 * time.Hour
+    Week = 7 * Day
+)
+
+// Makes GetDuration, the other duration getters and Unmarshal accept durations in days
+// and weeks and ISO 8601 durations, see ParseDuration, so retention and TTL settings
+// can be written as "30d" or "P1W".
+func SetExtendedDurations(enable bool) { c.SetExtendedDurations(enable) }
+func (c *Config) SetExtendedDurations(enable bool) {
+    c.extendedDurations = enable
+    c.invalidate()
+}
+
+// Parses a duration like time.ParseDuration, additionally accepting days (d) and weeks
+// (w) as units, as in "2d" or "1w2d12h", and ISO 8601 durations such as "P3DT4H",
+// "PT1.5S" or "-P2W". A day is always 24 hours and a week 7 days. Years and months have
+// no fixed length, so ISO 8601 durations using them are rejected with an error.
+func ParseDuration(s string) (time.Duration, error) {
+    s = strings.TrimSpace(s)
+
+    body := strings.TrimLeft(s, "+-")
+    if len(s)-len(body) <= 1 && strings.HasPrefix(strings.ToUpper(body), "P") {
+        return parseISODuration(s)
+    }
+    if !strings.ContainsAny(body, "dw") {
+        return time.ParseDuration(s)
+    }
+
+    neg := strings.HasPrefix(s, "-")
+    s = strings.TrimLeft(s, "+-")
+
+    var total time.Duration
+    for s != "" {
+        i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+        if i <= 0 {
+            return 0, fmt.Errorf("Invalid duration %q", body)
+        }
+        j := strings.IndexFunc(s[i:], func(r rune) bool { return r >= '0' && r <= '9' || r == '.' })
+        if j < 0 {
+            j = len(s) - i
+        }
+        num, unit := s[:i], s[i:i+j]
+        s = s[i+j:]
+
+        var d time.Duration
+        var err error
+        switch unit {
+        case "d":
+            d, err = scaleDuration(num, Day)
+        case "w":
+            d, err = scaleDuration(num, Week)
+        default:
+            d, err = time.ParseDuration(num + unit)
+        }
+        if err != nil {
+            return 0, fmt.Errorf("Invalid duration %q: %v", body, err)
+        }
+        if total > math.MaxInt64-d {
+            return 0, fmt.Errorf("Invalid duration %q: out of range", body)
+        }
+        total += d
+    }
+
+    if neg {
+        total = -total
+    }
+
+    return total, nil
+}
+
+// parseISODuration parses an ISO 8601 duration, P[nW][nD][T[nH][nM][nS]], optionally
+// signed, with fractions allowed in every number.
+func parseISODuration(s string) (time.Duration, error) {
+    orig := s
+
+    neg := strings.HasPrefix(s, "-")
+    s = strings.ToUpper(strings.TrimLeft(s, "+-"))[1:]
+
+    date, clock, hasT := strings.Cut(s, "T")
+    if s == "" || hasT && clock == "" {
+        return 0, fmt.Errorf("Invalid ISO 8601 duration %q", orig)
+    }
+
+    var total time.Duration
+    for _, part := range []struct {
+        s     string
+        units string
+    }{{date, "YMWD"}, {clock, "HMS"}} {
+        last := -1
+        for p := part.s; p != ""; {
+            i := strings.IndexAny(p, part.units)
+            if i <= 0 {
+                return 0, fmt.Errorf("Invalid ISO 8601 duration %q", orig)
+            }
+            num, unit := strings.Replace(p[:i], ",", ".", 1), p[i]
+            p = p[i+1:]
+
+            // Designators must come in order, each at most once.
+            at := strings.IndexByte(part.units, unit)
+            if at <= last {
+                return 0, fmt.Errorf("Invalid ISO 8601 duration %q", orig)
+            }
+            last = at
+
+            var size time.Duration
+            switch {
+            case part.units == "YMWD" && (unit == 'Y' || unit == 'M'):
+                return 0, fmt.Errorf("ISO 8601 duration %q uses years or months, which have no fixed length", orig)
+            case unit == 'W':
+                size = Week
+            case unit == 'D':
+                size = Day
+            case unit == 'H':
+                size = time.Hour
+            case unit == 'M':
+                size = time.Minute
+            case unit == 'S':
+                size = time.Second
+            }
+
+            d, err := scaleDuration(num, size)
+            if err != nil {
+                return 0, fmt.Errorf("Invalid ISO 8601 duration %q: %v", orig, err)
+            }
+            if total > math.MaxInt64-d {
+                return 0, fmt.Errorf("Invalid ISO 8601 duration %q: out of range", orig)
+            }
+            total += d
+        }
+    }
+
+    if neg {
+        total = -total
+    }
+
+    return total, nil
+}
+
+// scaleDuration returns num, a decimal number, times unit.
+func scaleDuration(num string, unit time.Duration) (time.Duration, error) {
+    if strings.HasPrefix(num, "+") || strings.HasPrefix(num, "-") {
+        return 0, fmt.Errorf("invalid number %q", num)
+    }
+
+    n, err := strconv.ParseFloat(num, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid number %q", num)
+    }
+
+    d := n * float64(unit)
+    if d > math.MaxInt64 {
+        return 0, fmt.Errorf("out of range")
+    }
+
+    return time.Duration(d), nil
+}
+
+// toDurationE casts val to a duration, accepting the forms of ParseDuration when
+// extended durations are enabled.
+func (c *Config) toDurationE(val interface{}) (time.Duration, error) {
+    if d, ok := val.(time.Duration); ok {
+        return d, nil
+    }
+
+    if s, ok := val.(string); ok && c.extendedDurations {
+        d, err := ParseDuration(s)
+        if err == nil {
+            return d, nil
+        }
+        // Numbers without a unit, taken as nanoseconds.
+        if d, castErr := cast.ToDurationE(s); castErr == nil {
+            return d, nil
+        }
+        return 0, err
+    }
+
+    return cast.ToDurationE(val)
+}
+
+// durationHook returns the decode hook turning strings into durations the way the
+// duration getters do.
+func (c *Config) durationHook() mapstructure.DecodeHookFunc {
+    if !c.extendedDurations {
+        return mapstructure.StringToTimeDurationHookFunc()
+    }
+
+    return func(from, to reflect.Type, data interface{}) (interface{}, error) {
+        if from.Kind() != reflect.String || to != reflect.TypeOf(time.Duration(0)) {
+            return data, nil
+        }
+        return ParseDuration(data.(string))
+    }
+}
@@ -0,0 +1,144 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "net/url"
+    "path/filepath"
+    "strings"
+
+    "github.com/spf13/cast"
+)
+
+// Top-level key naming the files a config file extends, see SetExtends.
+const ExtendsKey = "extends"
+
+// Denotes config files that extend each other in a cycle.
+type ExtendsCycleError struct {
+    // The files extending each other, starting and ending with the same file
+    Chain []string
+}
+
+// Returns the formatted cycle error.
+func (ece ExtendsCycleError) Error() string {
+    return fmt.Sprintf("Config file extends itself: %s", strings.Join(ece.Chain, " -> "))
+}
+
+// Enables inheritance between config files, docker-compose style. A config file whose
+// top-level extends key names a file, or a list of files, is read on top of them:
+//
+//     extends: base.yaml
+//     server:
+//       port: 8080
+//
+// The files extended are read first, in order, each deep-merged over the one before,
+// and the file extending them is merged over the result, so it only needs to hold what
+// it changes. Relative names are relative to the file naming them, and the files
+// extended may extend other files in turn, in any supported format. Files that end up
+// extending themselves fail the read with an ExtendsCycleError. The extends key itself
+// is dropped from the config.
+func SetExtends(enable bool) { c.SetExtends(enable) }
+func (c *Config) SetExtends(enable bool) {
+    c.extendsFiles = enable
+}
+
+// applyExtends returns config, read from file, merged over the files it extends, and
+// the origins of the result. chain holds the files extending file.
+func (c *Config) applyExtends(ctx context.Context, config map[string]interface{}, origins map[string]fileOrigin, file string, chain []string) (map[string]interface{}, map[string]fileOrigin, error) {
+    refs, exists := config[ExtendsKey]
+    if !exists {
+        return config, origins, nil
+    }
+    delete(config, ExtendsKey)
+    delete(origins, ExtendsKey)
+
+    names, err := cast.ToStringSliceE(refs)
+    if err != nil {
+        return nil, nil, fmt.Errorf("Invalid %s in %q: %v", ExtendsKey, file, err)
+    }
+
+    chain = append(chain, c.extendsID(file))
+
+    base, baseOrigins := make(map[string]interface{}), make(map[string]fileOrigin)
+    for _, name := range names {
+        name = c.extendedFile(file, name)
+        if stringInSlice(c.extendsID(name), chain) {
+            return nil, nil, ExtendsCycleError{append(append([]string(nil), chain...), c.extendsID(name))}
+        }
+
+        src, srcOrigins, err := c.readExtended(ctx, name)
+        if err != nil {
+            return nil, nil, err
+        }
+        if src, srcOrigins, err = c.applyExtends(ctx, src, srcOrigins, name, chain); err != nil {
+            return nil, nil, err
+        }
+
+        mergeMapsWith(base, src, c.mergeOptions(nil))
+        for k, v := range srcOrigins {
+            baseOrigins[k] = v
+        }
+    }
+
+    mergeMapsWith(base, config, c.mergeOptions(nil))
+    for k, v := range origins {
+        baseOrigins[k] = v
+    }
+
+    return base, baseOrigins, nil
+}
+
+// readExtended reads and parses the extended file name, verified, decrypted and rendered
+// like the config file.
+func (c *Config) readExtended(ctx context.Context, name string) (map[string]interface{}, map[string]fileOrigin, error) {
+    if err := ctx.Err(); err != nil {
+        return nil, nil, err
+    }
+
+    file, _, err := c.loadConfigFile(ctx, name)
+    if err != nil {
+        return nil, nil, fmt.Errorf("Cannot read extended config: %w", err)
+    }
+
+    configType := c.typeForFile(name)
+    c.logInfo("Reading extended config", name)
+    src, err := c.decodeConfig(bytes.NewReader(file), configType)
+    if err != nil {
+        return nil, nil, parseErrorIn(err, name)
+    }
+
+    return src, c.fileOrigins(src, file, configType, name), nil
+}
+
+// extendedFile returns the location of the file ref, named by the config file from.
+func (c *Config) extendedFile(from, ref string) string {
+    if isURL(ref) || filepath.IsAbs(ref) {
+        return ref
+    }
+
+    if isURL(from) {
+        base, err := url.Parse(from)
+        if err != nil {
+            return ref
+        }
+        rel, err := url.Parse(filepath.ToSlash(ref))
+        if err != nil {
+            return ref
+        }
+        return base.ResolveReference(rel).String()
+    }
+    if from == StdinConfigFile {
+        return c.absPathify(ref)
+    }
+
+    return filepath.Join(filepath.Dir(from), ref)
+}
+
+// extendsID returns the name cycles are detected by, the absolute path of a local file.
+func (c *Config) extendsID(name string) string {
+    if isURL(name) || name == StdinConfigFile {
+        return name
+    }
+    return c.absPathify(name)
+}
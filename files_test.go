@@ -0,0 +1,130 @@
+package cfg
+
+import (
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+// Writes files, mapping names relative to a temporary directory to their contents,
+// and returns the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+    t.Helper()
+
+    dir := t.TempDir()
+    for name, doc := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    return dir
+}
+
+func TestExtends(t *testing.T) {
+    tests := []struct {
+        name  string
+        files map[string]string
+        want  map[string]interface{}
+        err   error
+    }{
+        {"single", map[string]string{
+            "base.yaml":   "server:\n  host: localhost\n  port: 80\n",
+            "config.yaml": "extends: base.yaml\nserver:\n  port: 8080\n",
+        }, map[string]interface{}{"server.host": "localhost", "server.port": 8080, "extends": nil}, nil},
+        {"list and formats", map[string]string{
+            "base.toml":   "name = \"base\"\nlevel = \"info\"\n",
+            "dev.json":    `{"name": "dev"}`,
+            "config.yaml": "extends: [base.toml, dev.json]\nport: 1\n",
+        }, map[string]interface{}{"name": "dev", "level": "info", "port": 1}, nil},
+        {"nested dir", map[string]string{
+            "shared/base.yaml": "name: base\n",
+            "shared/mid.yaml":  "extends: base.yaml\nlevel: debug\n",
+            "config.yaml":      "extends: shared/mid.yaml\n",
+        }, map[string]interface{}{"name": "base", "level": "debug"}, nil},
+        {"cycle", map[string]string{
+            "a.yaml":      "extends: config.yaml\n",
+            "config.yaml": "extends: a.yaml\n",
+        }, nil, ExtendsCycleError{}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := writeFiles(t, tt.files)
+
+            c := New()
+            c.SetExtends(true)
+            c.SetConfigFile(filepath.Join(dir, "config.yaml"))
+            err := c.ReadInConfig()
+
+            if tt.err != nil {
+                var cycle ExtendsCycleError
+                if !errors.As(err, &cycle) {
+                    t.Errorf("ReadInConfig() = %v, want ExtendsCycleError", err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            for key, want := range tt.want {
+                if got := c.Get(key); !sameValue(got, want) {
+                    t.Errorf("Get(%s) = %#v, want %#v", key, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestConfigDir(t *testing.T) {
+    dir := writeFiles(t, map[string]string{
+        "config.yaml":           "name: api\nport: 80\nlevel: info\n",
+        "conf.d/10-base.yaml":   "port: 8080\nlevel: warn\n",
+        "conf.d/20-local.toml":  "level = \"debug\"\n",
+        "conf.d/.hidden.yaml":   "name: hidden\n",
+        "conf.d/README.txt":     "not a config\n",
+        "later.d/10-later.json": `{"port": 9090}`,
+    })
+
+    c := New()
+    c.SetConfigFile(filepath.Join(dir, "config.yaml"))
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+    if err := c.AddConfigDir(filepath.Join(dir, "conf.d")); err != nil {
+        t.Fatal(err)
+    }
+    if err := c.AddConfigDir(filepath.Join(dir, "later.d")); err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        key  string
+        want interface{}
+    }{
+        {"name", "api"},
+        {"port", 9090.0},
+        {"level", "debug"},
+    }
+
+    check := func() {
+        for _, tt := range tests {
+            if got := c.Get(tt.key); !sameValue(got, tt.want) {
+                t.Errorf("Get(%s) = %#v, want %#v", tt.key, got, tt.want)
+            }
+        }
+    }
+
+    check()
+
+    // Drop-ins survive reading the config file again.
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+    check()
+}
@@ -0,0 +1,239 @@
+// Package flags evaluates feature flags stored in a cfg.Config under the features key.
+//
+// A flag is either a plain boolean,
+//
+//     features:
+//       new_checkout: true
+//
+// or a map that can roll it out to a share of users and restrict it by attributes:
+//
+//     features:
+//       beta_search:
+//         enabled: true
+//         rollout: 25          # percent of users, by the user_id attribute
+//         rollout_by: user_id  # "id" by default
+//         rules:               # any one of them has to match
+//           - country: [DE, FR]
+//             plan: pro
+//           - staff: "true"
+//
+// Flags are read from the config every time they are evaluated, so reloads and
+// overrides take effect immediately.
+package flags
+
+import (
+    "fmt"
+    "hash/fnv"
+    "sort"
+    "strings"
+    "sync"
+
+    "github.com/nwlucas/cfg"
+    "github.com/spf13/cast"
+)
+
+// Key the flags are stored beneath.
+const Prefix = "features"
+
+// Attribute percentage rollouts are computed from unless rollout_by is set.
+const DefaultRolloutBy = "id"
+
+// Describes the subject a flag is evaluated for, such as the id, country or plan of a
+// user. Names are matched case-insensitively.
+type Attributes map[string]string
+
+// Describes a declared flag.
+type Flag struct {
+    Name        string
+    Description string
+
+    // Whether the flag is enabled when the config does not say
+    Default bool
+}
+
+// Holds the flags declared for a config.
+type Registry struct {
+    c *cfg.Config
+
+    mu    sync.RWMutex
+    flags map[string]Flag
+}
+
+// Returns a registry evaluating flags stored in c.
+func New(c *cfg.Config) *Registry {
+    return &Registry{c: c, flags: make(map[string]Flag)}
+}
+
+// Declares the flag name, enabled by default when def is set. Declaring a flag again
+// replaces it.
+func (r *Registry) Declare(name string, def bool, description string) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+
+    name = strings.ToLower(name)
+    r.flags[name] = Flag{Name: name, Description: description, Default: def}
+}
+
+// Returns the declared flags sorted by name.
+func (r *Registry) Flags() []Flag {
+    r.mu.RLock()
+    defer r.mu.RUnlock()
+
+    flags := make([]Flag, 0, len(r.flags))
+    for _, f := range r.flags {
+        flags = append(flags, f)
+    }
+    sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+
+    return flags
+}
+
+// Returns whether the flag name is enabled for the subject described by attrs. A flag
+// the config does not mention takes its declared default, an undeclared one is off.
+// Invalid flag settings are reported by Evaluate and turn the flag off here.
+func (r *Registry) IsEnabled(name string, attrs Attributes) bool {
+    enabled, _ := r.Evaluate(name, attrs)
+    return enabled
+}
+
+// Like IsEnabled, but returns the error describing invalid flag settings.
+func (r *Registry) Evaluate(name string, attrs Attributes) (bool, error) {
+    name = strings.ToLower(name)
+
+    r.mu.RLock()
+    flag := r.flags[name]
+    r.mu.RUnlock()
+
+    key := Prefix + "." + name
+    val := r.c.Get(key)
+    if val == nil {
+        return flag.Default, nil
+    }
+
+    if _, err := cast.ToStringMapE(val); err != nil {
+        enabled, err := cast.ToBoolE(val)
+        if err != nil {
+            return false, fmt.Errorf("Feature %q: %v", name, err)
+        }
+        return enabled, nil
+    }
+
+    // Settings are read one by one so values set in different layers combine.
+    setting := func(s string) (interface{}, bool) {
+        v := r.c.Get(key + "." + s)
+        return v, v != nil
+    }
+
+    enabled := flag.Default
+    if v, ok := setting("enabled"); ok {
+        var err error
+        if enabled, err = cast.ToBoolE(v); err != nil {
+            return false, fmt.Errorf("Feature %q: enabled: %v", name, err)
+        }
+    }
+    if !enabled {
+        return false, nil
+    }
+
+    attrs = lowerKeys(attrs)
+
+    if v, ok := setting("rules"); ok {
+        matched, err := matchRules(v, attrs)
+        if err != nil {
+            return false, fmt.Errorf("Feature %q: rules: %v", name, err)
+        }
+        if !matched {
+            return false, nil
+        }
+    }
+
+    if v, ok := setting("rollout"); ok {
+        percent, err := cast.ToFloat64E(v)
+        if err != nil || percent < 0 || percent > 100 {
+            return false, fmt.Errorf("Feature %q: rollout must be a percentage, got %v", name, v)
+        }
+
+        by := DefaultRolloutBy
+        if v, ok := setting("rollout_by"); ok {
+            by = strings.ToLower(cast.ToString(v))
+        }
+
+        return inRollout(name, attrs[by], percent), nil
+    }
+
+    return true, nil
+}
+
+// matchRules reports whether attrs match any of rules, a list of maps from attribute
+// names to a value or a list of values one of which the attribute must have.
+func matchRules(rules interface{}, attrs Attributes) (bool, error) {
+    list, err := cast.ToSliceE(rules)
+    if err != nil {
+        return false, err
+    }
+
+    for _, rule := range list {
+        conds, err := cast.ToStringMapE(rule)
+        if err != nil {
+            return false, err
+        }
+        if matchRule(conds, attrs) {
+            return true, nil
+        }
+    }
+
+    return false, nil
+}
+
+func matchRule(conds map[string]interface{}, attrs Attributes) bool {
+    for name, want := range conds {
+        got, ok := attrs[strings.ToLower(name)]
+        if !ok {
+            return false
+        }
+
+        values, err := cast.ToStringSliceE(want)
+        if _, isList := want.([]interface{}); !isList || err != nil {
+            values = []string{cast.ToString(want)}
+        }
+        if !contains(values, got) {
+            return false
+        }
+    }
+
+    return true
+}
+
+// inRollout reports whether the subject id falls within the first percent of users,
+// hashing it together with the flag name so every flag rolls out to different users.
+// Subjects without an id are only included in a complete rollout.
+func inRollout(name, id string, percent float64) bool {
+    if percent >= 100 {
+        return true
+    }
+    if id == "" {
+        return false
+    }
+
+    h := fnv.New32a()
+    h.Write([]byte(name + ":" + id))
+
+    return float64(h.Sum32()%10000) < percent*100
+}
+
+func lowerKeys(attrs Attributes) Attributes {
+    lowered := make(Attributes, len(attrs))
+    for k, v := range attrs {
+        lowered[strings.ToLower(k)] = v
+    }
+    return lowered
+}
+
+func contains(values []string, s string) bool {
+    for _, v := range values {
+        if v == s {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,41 @@
+package cfg
+
+import (
+    "errors"
+)
+
+// Returned by the calls that change a frozen config.
+var ErrFrozen = errors.New("Config is frozen")
+
+// Makes the config read-only. Afterwards the calls that change its values, such as
+// ReadInConfig, MergeConfig or AddSource, return ErrFrozen, and those without an
+// error to return, such as Set and SetDefault, panic with it. Periodic source refreshes
+// and watched reloads stop being applied. A frozen config cannot be thawed, but Clone
+// returns a copy that is not frozen.
+func Freeze() { c.Freeze() }
+func (c *Config) Freeze() {
+    c.mu.Lock()
+    c.frozen.Store(true)
+    c.mu.Unlock()
+}
+
+// Returns whether the config has been frozen.
+func Frozen() bool { return c.Frozen() }
+func (c *Config) Frozen() bool {
+    return c.frozen.Load()
+}
+
+// checkFrozen returns ErrFrozen once the config has been frozen.
+func (c *Config) checkFrozen() error {
+    if c.frozen.Load() {
+        return ErrFrozen
+    }
+    return nil
+}
+
+// mustNotBeFrozen panics with ErrFrozen once the config has been frozen.
+func (c *Config) mustNotBeFrozen() {
+    if c.frozen.Load() {
+        panic(ErrFrozen)
+    }
+}
@@ -0,0 +1,107 @@
+package cfg
+
+import (
+    "io"
+    "io/fs"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+)
+
+// Reads the config file, the drop-in files and profiles merged into it, and their
+// checksums and signatures from fsys instead of the OS filesystem, such as an embed.FS
+// or an fstest.MapFS in tests. Paths are looked up in fsys with any leading slash
+// removed, and config paths are used as given rather than made absolute, so give it
+// before WithConfigPaths. Watching the config file is not supported on fsys.
+func WithFS(fsys fs.FS) Option {
+    return func(c *Config) {
+        c.fsys = fsys
+    }
+}
+
+// fsPath returns name as a path in the filesystem config files are read from.
+func (c *Config) fsPath(name string) string {
+    if c.fsys == nil {
+        return name
+    }
+
+    name = strings.TrimLeft(filepath.ToSlash(strings.TrimPrefix(name, filepath.VolumeName(name))), "/")
+    if name == "" {
+        return "."
+    }
+    return path.Clean(name)
+}
+
+// openFile opens the file name, on the filesystem set with WithFS if any.
+func (c *Config) openFile(name string) (fs.File, error) {
+    if c.fsys == nil {
+        return os.Open(name)
+    }
+    return c.fsys.Open(c.fsPath(name))
+}
+
+// readFile reads the file name, on the filesystem set with WithFS if any.
+func (c *Config) readFile(name string) ([]byte, error) {
+    f, err := c.openFile(name)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    return io.ReadAll(f)
+}
+
+// readDir returns the entries of the directory name sorted by name, on the filesystem
+// set with WithFS if any.
+func (c *Config) readDir(name string) ([]fs.DirEntry, error) {
+    if c.fsys == nil {
+        return os.ReadDir(name)
+    }
+    return fs.ReadDir(c.fsys, c.fsPath(name))
+}
+
+// fileExists reports whether the file name exists, on the filesystem set with WithFS if
+// any.
+func (c *Config) fileExists(name string) (bool, error) {
+    if c.fsys == nil {
+        return exists(name)
+    }
+
+    _, err := fs.Stat(c.fsys, c.fsPath(name))
+    if err == nil {
+        return true, nil
+    }
+    if os.IsNotExist(err) {
+        return false, nil
+    }
+    return false, err
+}
+
+// isFile reports whether name is a regular file, on the filesystem set with WithFS if
+// any.
+func (c *Config) isFile(name string) bool {
+    var (
+        fi  fs.FileInfo
+        err error
+    )
+    if c.fsys == nil {
+        fi, err = os.Stat(name)
+    } else {
+        fi, err = fs.Stat(c.fsys, c.fsPath(name))
+    }
+
+    return err == nil && fi.Mode().IsRegular()
+}
+
+// glob returns the names of the files matching pattern in lexical order, on the
+// filesystem set with WithFS if any.
+func (c *Config) glob(pattern string) []string {
+    if c.fsys == nil {
+        matches, _ := filepath.Glob(pattern)
+        return matches
+    }
+
+    matches, _ := fs.Glob(c.fsys, c.fsPath(pattern))
+    return matches
+}
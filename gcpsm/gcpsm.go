@@ -0,0 +1,135 @@
+// Package gcpsm provides cfg sources backed by Google Secret Manager.
+package gcpsm
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    secretmanager "cloud.google.com/go/secretmanager/apiv1"
+    "cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+    "google.golang.org/api/option"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Accesses secret versions and caches their payloads in memory.
+type Provider struct {
+    client *secretmanager.Client
+    ttl    time.Duration
+
+    mu    sync.Mutex
+    cache map[string]cachedSecret
+}
+
+type cachedSecret struct {
+    data    []byte
+    expires time.Time
+}
+
+// Returns a Provider authenticated with Application Default Credentials unless
+// opts say otherwise. Payloads are cached for ttl, a zero ttl disables caching.
+func New(ctx context.Context, ttl time.Duration, opts ...option.ClientOption) (*Provider, error) {
+    client, err := secretmanager.NewClient(ctx, opts...)
+    if err != nil {
+        return nil, err
+    }
+
+    return &Provider{client: client, ttl: ttl, cache: make(map[string]cachedSecret)}, nil
+}
+
+// Closes the underlying client.
+func (p *Provider) Close() error {
+    return p.client.Close()
+}
+
+// Returns the payload of the secret version referenced by ref.
+//
+// ref is either a full resource name (projects/p/secrets/s/versions/v) or the short
+// forms project/secret/version and project/secret, the latter meaning the latest version.
+func (p *Provider) Access(ctx context.Context, ref string) ([]byte, error) {
+    name, err := resourceName(ref)
+    if err != nil {
+        return nil, err
+    }
+
+    p.mu.Lock()
+    cached, ok := p.cache[name]
+    p.mu.Unlock()
+    if ok && time.Now().Before(cached.expires) {
+        return cached.data, nil
+    }
+
+    resp, err := p.client.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+    if err != nil {
+        return nil, err
+    }
+
+    data := resp.GetPayload().GetData()
+    if p.ttl > 0 {
+        p.mu.Lock()
+        p.cache[name] = cachedSecret{data: data, expires: time.Now().Add(p.ttl)}
+        p.mu.Unlock()
+    }
+
+    return data, nil
+}
+
+// Adds a source named name to c in which each key takes the value of the secret it maps to.
+func (p *Provider) AddKeySource(c *cfg.Config, name string, keys map[string]string, refresh time.Duration) error {
+    fetch := func() (map[string]interface{}, error) {
+        m := make(map[string]interface{}, len(keys))
+        for key, ref := range keys {
+            data, err := p.Access(context.Background(), ref)
+            if err != nil {
+                return nil, fmt.Errorf("Accessing secret %q for %q: %v", ref, key, err)
+            }
+            m[key] = string(data)
+        }
+        return m, nil
+    }
+
+    return c.AddSource("gcpsm:"+name, fetch, refresh)
+}
+
+// Adds the secret ref, a complete config document of the given format, as a source on c.
+func (p *Provider) AddDocumentSource(c *cfg.Config, ref, format string, refresh time.Duration) error {
+    fetch := func() (map[string]interface{}, error) {
+        data, err := p.Access(context.Background(), ref)
+        if err != nil {
+            return nil, err
+        }
+
+        if strings.ToLower(format) == "json" {
+            m := make(map[string]interface{})
+            if err := json.Unmarshal(data, &m); err != nil {
+                return nil, fmt.Errorf("Secret %q is not a JSON object: %v", ref, err)
+            }
+            return m, nil
+        }
+
+        return cfg.Decode(bytes.NewReader(data), format)
+    }
+
+    return c.AddSource("gcpsm:"+ref, fetch, refresh)
+}
+
+func resourceName(ref string) (string, error) {
+    if strings.HasPrefix(ref, "projects/") {
+        return ref, nil
+    }
+
+    parts := strings.Split(ref, "/")
+    switch len(parts) {
+    case 2:
+        return fmt.Sprintf("projects/%s/secrets/%s/versions/latest", parts[0], parts[1]), nil
+    case 3:
+        return fmt.Sprintf("projects/%s/secrets/%s/versions/%s", parts[0], parts[1], parts[2]), nil
+    }
+
+    return "", fmt.Errorf("Invalid secret reference %q", ref)
+}
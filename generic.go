@@ -0,0 +1,168 @@
+package cfg
+
+import (
+    "net"
+    "net/netip"
+    "net/url"
+    "regexp"
+    "time"
+
+    "github.com/mitchellh/mapstructure"
+    "github.com/spf13/cast"
+)
+
+// Returns the value associated with the key converted to T, or a ValueError if it cannot
+// be converted. Scalars are cast like the typed getters, URLs, addresses and regular
+// expressions are parsed like GetURLE, GetIPE and friends, and any other type, such as
+// a struct, slice or map, is decoded with mapstructure. An unset key yields a
+// KeyNotFoundError.
+func GetAs[T any](c *Config, key string) (T, error) {
+    var out T
+
+    val, err := c.lookupE(key)
+    if err != nil {
+        return out, err
+    }
+    if v, ok := val.(T); ok {
+        return v, nil
+    }
+
+    var converted interface{}
+
+    switch any(out).(type) {
+    case string:
+        converted, err = cast.ToStringE(val)
+    case bool:
+        converted, err = cast.ToBoolE(val)
+    case int:
+        converted, err = cast.ToIntE(val)
+    case int8:
+        converted, err = cast.ToInt8E(val)
+    case int16:
+        converted, err = cast.ToInt16E(val)
+    case int32:
+        converted, err = cast.ToInt32E(val)
+    case int64:
+        converted, err = cast.ToInt64E(val)
+    case uint:
+        converted, err = cast.ToUintE(val)
+    case uint8:
+        converted, err = cast.ToUint8E(val)
+    case uint16:
+        converted, err = cast.ToUint16E(val)
+    case uint32:
+        converted, err = cast.ToUint32E(val)
+    case uint64:
+        converted, err = cast.ToUint64E(val)
+    case float32:
+        converted, err = cast.ToFloat32E(val)
+    case float64:
+        converted, err = cast.ToFloat64E(val)
+    case time.Time:
+        converted, err = c.toTimeE(val)
+    case time.Duration:
+        converted, err = c.toDurationE(val)
+    case []string:
+        converted, err = cast.ToStringSliceE(val)
+    case []int:
+        converted, err = cast.ToIntSliceE(val)
+    case *url.URL:
+        return asT[T](c.GetURLE(key))
+    case net.IP:
+        return asT[T](c.GetIPE(key))
+    case *net.IPNet:
+        return asT[T](c.GetCIDRE(key))
+    case netip.Addr:
+        return asT[T](c.GetAddrE(key))
+    case netip.Prefix:
+        return asT[T](c.GetPrefixE(key))
+    case *regexp.Regexp:
+        return asT[T](c.GetRegexp(key))
+    case *time.Location:
+        return asT[T](c.GetLocation(key))
+    default:
+        err = c.decodeAs(val, &out)
+        converted = out
+    }
+
+    if err != nil {
+        return out, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return converted.(T), nil
+}
+
+// asT converts the result of a typed getter to T.
+func asT[T any](v interface{}, err error) (T, error) {
+    var out T
+    if err != nil {
+        return out, err
+    }
+
+    out, _ = v.(T)
+    return out, nil
+}
+
+func (c *Config) decodeAs(val interface{}, out interface{}) error {
+    config := c.decoderConfig(out, true)
+    config.DecodeHook = c.decodeHook(
+        c.durationHook(),
+        mapstructure.StringToSliceHookFunc(","),
+    )
+
+    decoder, err := mapstructure.NewDecoder(config)
+    if err != nil {
+        return err
+    }
+
+    return decoder.Decode(val)
+}
+
+// Returns the value associated with the key converted to T, see GetAs, or fallback if
+// the key is not set in any layer. The key is looked up once, so there is no window
+// between checking and reading it. A value that is set but cannot be converted yields
+// the zero value of T, like the typed getters.
+func GetOr[T any](c *Config, key string, fallback T) T {
+    v, err := GetAs[T](c, key)
+    if _, missing := err.(KeyNotFoundError); missing {
+        return fallback
+    }
+
+    return v
+}
+
+// Returns the value associated with the key as a string, or fallback if it is not set
+func GetStringOr(key string, fallback string) string { return c.GetStringOr(key, fallback) }
+func (c *Config) GetStringOr(key string, fallback string) string { return GetOr(c, key, fallback) }
+
+// Returns the value associated with the key as a boolean, or fallback if it is not set
+func GetBoolOr(key string, fallback bool) bool { return c.GetBoolOr(key, fallback) }
+func (c *Config) GetBoolOr(key string, fallback bool) bool { return GetOr(c, key, fallback) }
+
+// Returns the value associated with the key as an integer, or fallback if it is not set
+func GetIntOr(key string, fallback int) int { return c.GetIntOr(key, fallback) }
+func (c *Config) GetIntOr(key string, fallback int) int { return GetOr(c, key, fallback) }
+
+// Returns the value associated with the key as a 64-bit integer, or fallback if it is not set
+func GetInt64Or(key string, fallback int64) int64 { return c.GetInt64Or(key, fallback) }
+func (c *Config) GetInt64Or(key string, fallback int64) int64 { return GetOr(c, key, fallback) }
+
+// Returns the value associated with the key as a float64, or fallback if it is not set
+func GetFloat64Or(key string, fallback float64) float64 { return c.GetFloat64Or(key, fallback) }
+func (c *Config) GetFloat64Or(key string, fallback float64) float64 { return GetOr(c, key, fallback) }
+
+// Returns the value associated with the key as a duration, or fallback if it is not set
+func GetDurationOr(key string, fallback time.Duration) time.Duration {
+    return c.GetDurationOr(key, fallback)
+}
+func (c *Config) GetDurationOr(key string, fallback time.Duration) time.Duration {
+    return GetOr(c, key, fallback)
+}
+
+// Returns the value associated with the key as a slice of strings, or fallback if it is not set
+func GetStringSliceOr(key string, fallback []string) []string {
+    return c.GetStringSliceOr(key, fallback)
+}
+func (c *Config) GetStringSliceOr(key string, fallback []string) []string {
+    return GetOr(c, key, fallback)
+}
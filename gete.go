@@ -0,0 +1,382 @@
+package cfg
+
+import (
+    "time"
+
+    "github.com/spf13/cast"
+)
+
+// The E variants of the getters tell a missing key apart from a value that happens to be
+// the zero value: they return a KeyNotFoundError if the key is not set, and a ValueError
+// if its value cannot be converted.
+
+// lookupE returns the value of key as Get does, along with a KeyNotFoundError if it is
+// not set or a TypeMismatchError if it could not be converted to the type of its default.
+func (c *Config) lookupE(key string) (interface{}, error) {
+    val, err := c.get(key)
+    if val == nil {
+        // Set to a reference that cannot be resolved or interpolated
+        if err != nil {
+            return nil, err
+        }
+        return nil, KeyNotFoundError(c.normalizeKey(key))
+    }
+
+    return val, err
+}
+
+// Returns the value associated with the key as a string, or an error
+func GetStringE(key string) (string, error) { return c.GetStringE(key) }
+func (c *Config) GetStringE(key string) (string, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return "", err
+    }
+
+    v, err := cast.ToStringE(val)
+    if err != nil {
+        return "", ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a boolean, or an error
+func GetBoolE(key string) (bool, error) { return c.GetBoolE(key) }
+func (c *Config) GetBoolE(key string) (bool, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return false, err
+    }
+
+    v, err := cast.ToBoolE(val)
+    if err != nil {
+        return false, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as an integer, or an error
+func GetIntE(key string) (int, error) { return c.GetIntE(key) }
+func (c *Config) GetIntE(key string) (int, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToIntE(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a 32-bit integer, or an error
+func GetInt32E(key string) (int32, error) { return c.GetInt32E(key) }
+func (c *Config) GetInt32E(key string) (int32, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToInt32E(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a 64-bit integer, or an error
+func GetInt64E(key string) (int64, error) { return c.GetInt64E(key) }
+func (c *Config) GetInt64E(key string) (int64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToInt64E(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as an unsigned integer, or an error
+func GetUintE(key string) (uint, error) { return c.GetUintE(key) }
+func (c *Config) GetUintE(key string) (uint, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToUintE(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a 32-bit unsigned integer, or an error
+func GetUint32E(key string) (uint32, error) { return c.GetUint32E(key) }
+func (c *Config) GetUint32E(key string) (uint32, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToUint32E(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a 64-bit unsigned integer, or an error
+func GetUint64E(key string) (uint64, error) { return c.GetUint64E(key) }
+func (c *Config) GetUint64E(key string) (uint64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToUint64E(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a float64, or an error
+func GetFloat64E(key string) (float64, error) { return c.GetFloat64E(key) }
+func (c *Config) GetFloat64E(key string) (float64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := cast.ToFloat64E(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as time, or an error
+func GetTimeE(key string) (time.Time, error) { return c.GetTimeE(key) }
+func (c *Config) GetTimeE(key string) (time.Time, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return time.Time{}, err
+    }
+
+    v, err := c.toTimeE(val)
+    if err != nil {
+        return time.Time{}, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a duration, or an error
+func GetDurationE(key string) (time.Duration, error) { return c.GetDurationE(key) }
+func (c *Config) GetDurationE(key string) (time.Duration, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    v, err := c.toDurationE(val)
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a slice of strings, or an error
+func GetStringSliceE(key string) ([]string, error) { return c.GetStringSliceE(key) }
+func (c *Config) GetStringSliceE(key string) ([]string, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringSliceE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a slice of integers, or an error
+func GetIntSliceE(key string) ([]int, error) { return c.GetIntSliceE(key) }
+func (c *Config) GetIntSliceE(key string) ([]int, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToIntSliceE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a slice of float64s, or an error
+func GetFloat64SliceE(key string) ([]float64, error) { return c.GetFloat64SliceE(key) }
+func (c *Config) GetFloat64SliceE(key string) ([]float64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := toFloat64SliceE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a slice of durations, or an error naming
+// the first element that is not a duration
+func GetDurationSliceE(key string) ([]time.Duration, error) { return c.GetDurationSliceE(key) }
+func (c *Config) GetDurationSliceE(key string) ([]time.Duration, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := toDurationSliceE(val, c.toDurationE)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of interfaces, or an error
+func GetStringMapE(key string) (map[string]interface{}, error) { return c.GetStringMapE(key) }
+func (c *Config) GetStringMapE(key string) (map[string]interface{}, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of strings, or an error
+func GetStringMapStringE(key string) (map[string]string, error) { return c.GetStringMapStringE(key) }
+func (c *Config) GetStringMapStringE(key string) (map[string]string, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapStringE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of string slices, or an error
+func GetStringMapStringSliceE(key string) (map[string][]string, error) { return c.GetStringMapStringSliceE(key) }
+func (c *Config) GetStringMapStringSliceE(key string) (map[string][]string, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapStringSliceE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of integers, or an error
+func GetStringMapIntE(key string) (map[string]int, error) { return c.GetStringMapIntE(key) }
+func (c *Config) GetStringMapIntE(key string) (map[string]int, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapIntE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of 64-bit integers, or an error
+func GetStringMapInt64E(key string) (map[string]int64, error) { return c.GetStringMapInt64E(key) }
+func (c *Config) GetStringMapInt64E(key string) (map[string]int64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapInt64E(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the value associated with the key as a map of booleans, or an error
+func GetStringMapBoolE(key string) (map[string]bool, error) { return c.GetStringMapBoolE(key) }
+func (c *Config) GetStringMapBoolE(key string) (map[string]bool, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return nil, err
+    }
+
+    v, err := cast.ToStringMapBoolE(val)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return v, nil
+}
+
+// Returns the size of the value associated with the given key in bytes, or a ValueError
+// if it is malformed, negative or overflows a uint64. Sizes are a number followed by
+// an optional unit, SI units (KB, MB, GB, TB, PB, EB) are powers of 1000 and IEC units
+// (KiB, MiB, GiB, TiB, PiB, EiB) powers of 1024.
+func GetSizeInBytesE(key string) (uint64, error) { return c.GetSizeInBytesE(key) }
+func (c *Config) GetSizeInBytesE(key string) (uint64, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return 0, err
+    }
+
+    size, err := parseSizeInBytesE(cast.ToString(val))
+    if err != nil {
+        return 0, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return size, nil
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    gogrpc "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/status"
+    "google.golang.org/protobuf/types/known/emptypb"
+    "google.golang.org/protobuf/types/known/structpb"
+    "google.golang.org/protobuf/types/known/wrapperspb"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Delay before a broken WatchChanges stream is opened again.
+const rewatchDelay = time.Second
+
+// Controls how config is read from a config server.
+type Options struct {
+    // Address of the server, in any form grpc.NewClient accepts.
+    Target string
+
+    // Options of the connection. Without any, the connection is made without transport
+    // security, which only suits a server on the same host or a trusted network.
+    DialOptions []gogrpc.DialOption
+
+    // Reloads the source whenever the server streams a change.
+    Watch bool
+
+    // Interval at which all settings are re-read, zero reads them only on start and,
+    // with Watch, on changes.
+    Refresh time.Duration
+}
+
+// A config server backed source registered on a Config.
+type Source struct {
+    conn   *gogrpc.ClientConn
+    cancel context.CancelFunc
+    done   chan struct{}
+}
+
+// Adds the settings served at opts.Target as a source on c.
+func AddSource(c *cfg.Config, opts Options) (*Source, error) {
+    dialOpts := opts.DialOptions
+    if len(dialOpts) == 0 {
+        dialOpts = []gogrpc.DialOption{gogrpc.WithTransportCredentials(insecure.NewCredentials())}
+    }
+
+    conn, err := gogrpc.NewClient(opts.Target, dialOpts...)
+    if err != nil {
+        return nil, err
+    }
+
+    name := "grpc:" + opts.Target
+    if err := c.AddSource(name, fetcher(conn), opts.Refresh); err != nil {
+        conn.Close()
+        return nil, err
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    s := &Source{conn: conn, cancel: cancel, done: make(chan struct{})}
+    if opts.Watch {
+        go s.watch(ctx, c, name)
+    } else {
+        close(s.done)
+    }
+
+    return s, nil
+}
+
+// Stops watching for changes and closes the connection.
+func (s *Source) Close() error {
+    s.cancel()
+    <-s.done
+    return s.conn.Close()
+}
+
+// Returns the value of key on the server, and false if it is not set there.
+func (s *Source) GetKey(ctx context.Context, key string) (interface{}, bool, error) {
+    out := new(structpb.Value)
+    if err := s.conn.Invoke(ctx, methodGetKey, wrapperspb.String(key), out); err != nil {
+        if status.Code(err) == codes.NotFound {
+            return nil, false, nil
+        }
+        return nil, false, err
+    }
+
+    return out.AsInterface(), true, nil
+}
+
+// Returns every key set on the server, sorted.
+func (s *Source) ListKeys(ctx context.Context) ([]string, error) {
+    out := new(structpb.ListValue)
+    if err := s.conn.Invoke(ctx, methodListKeys, new(emptypb.Empty), out); err != nil {
+        return nil, err
+    }
+
+    keys := make([]string, len(out.GetValues()))
+    for i, v := range out.GetValues() {
+        keys[i] = v.GetStringValue()
+    }
+
+    return keys, nil
+}
+
+// watch reloads the source for every streamed change, opening the stream again when it
+// breaks, until ctx is done.
+func (s *Source) watch(ctx context.Context, c *cfg.Config, name string) {
+    defer close(s.done)
+
+    for {
+        err := s.stream(ctx, c, name)
+        if ctx.Err() != nil {
+            return
+        }
+        c.Logger().Warn(fmt.Sprintf("Lost %s, watching again: %v", name, err))
+
+        select {
+        case <-time.After(rewatchDelay):
+        case <-ctx.Done():
+            return
+        }
+
+        // Changes made while the stream was down would otherwise go unnoticed.
+        if err := c.RefreshSource(name); err != nil {
+            c.Logger().Error(fmt.Sprintf("Failed to reload %s: %v", name, err))
+        }
+    }
+}
+
+func (s *Source) stream(ctx context.Context, c *cfg.Config, name string) error {
+    stream, err := s.conn.NewStream(ctx, &watchChangesDesc, methodWatchChanges)
+    if err != nil {
+        return err
+    }
+    if err := stream.SendMsg(new(emptypb.Empty)); err != nil {
+        return err
+    }
+    if err := stream.CloseSend(); err != nil {
+        return err
+    }
+
+    for {
+        if err := stream.RecvMsg(new(structpb.Struct)); err != nil {
+            return err
+        }
+        if err := c.RefreshSource(name); err != nil {
+            c.Logger().Error(fmt.Sprintf("Failed to reload %s: %v", name, err))
+        }
+    }
+}
+
+func fetcher(conn gogrpc.ClientConnInterface) cfg.SourceFunc {
+    return func() (map[string]interface{}, error) {
+        out := new(structpb.Struct)
+        if err := conn.Invoke(context.Background(), methodGetAll, new(emptypb.Empty), out); err != nil {
+            return nil, err
+        }
+
+        return out.AsMap(), nil
+    }
+}
@@ -0,0 +1,304 @@
+// Package grpc serves a cfg.Config over gRPC and provides a cfg source consuming it, so a
+// central config daemon can feed many services through the same layer machinery.
+//
+// The service, cfg.v1.Config, uses the well-known protobuf types so no generated code is
+// needed on either side:
+//
+//     rpc GetKey(google.protobuf.StringValue) returns (google.protobuf.Value)
+//     rpc ListKeys(google.protobuf.Empty) returns (google.protobuf.ListValue)
+//     rpc GetAll(google.protobuf.Empty) returns (google.protobuf.Struct)
+//     rpc WatchChanges(google.protobuf.Empty) returns (stream google.protobuf.Struct)
+//
+// Each message of WatchChanges describes one changed key with the fields key, value and
+// deleted.
+package grpc
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "sync"
+    "time"
+
+    gogrpc "google.golang.org/grpc"
+    "google.golang.org/grpc/codes"
+    "google.golang.org/grpc/status"
+    "google.golang.org/protobuf/encoding/protojson"
+    "google.golang.org/protobuf/types/known/emptypb"
+    "google.golang.org/protobuf/types/known/structpb"
+    "google.golang.org/protobuf/types/known/wrapperspb"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Full name of the config service.
+const ServiceName = "cfg.v1.Config"
+
+// Number of changes buffered for a watcher before it is dropped as too slow.
+const watchBuffer = 64
+
+// Controls what a Server exposes.
+type ServerOptions struct {
+    // Serves the values of secret keys as they are instead of "***", see cfg.MarkSecret.
+    RevealSecrets bool
+
+    // Interval at which the config is checked for changes to stream, one second when
+    // zero. Reloads of watched files and remotes are streamed right away.
+    Interval time.Duration
+}
+
+// Serves a Config over gRPC.
+type Server struct {
+    cfg  *cfg.Config
+    opts ServerOptions
+
+    mu       sync.Mutex
+    last     map[string]interface{}
+    watchers map[chan *structpb.Struct]struct{}
+
+    poke chan struct{}
+    stop chan struct{}
+    once sync.Once
+}
+
+// Registers the config service for c on s. Changes are looked for until Close is called.
+func Register(s *gogrpc.Server, c *cfg.Config, opts ServerOptions) *Server {
+    if opts.Interval <= 0 {
+        opts.Interval = time.Second
+    }
+
+    srv := &Server{
+        cfg:      c,
+        opts:     opts,
+        watchers: make(map[chan *structpb.Struct]struct{}),
+        poke:     make(chan struct{}, 1),
+        stop:     make(chan struct{}),
+    }
+    srv.last = srv.settings()
+
+    c.OnConfigChange(func(cfg.ChangeEvent) {
+        select {
+        case srv.poke <- struct{}{}:
+        default:
+        }
+    })
+
+    s.RegisterService(&serviceDesc, srv)
+    go srv.watch()
+
+    return srv
+}
+
+// Stops looking for changes and ends all WatchChanges streams.
+func (s *Server) Close() {
+    s.once.Do(func() {
+        close(s.stop)
+    })
+}
+
+// Returns the value of a key, NotFound if no layer holds it.
+func (s *Server) GetKey(ctx context.Context, in *wrapperspb.StringValue) (*structpb.Value, error) {
+    var (
+        v     interface{}
+        found bool
+    )
+
+    if s.opts.RevealSecrets {
+        v, found = s.cfg.Get(in.GetValue()), s.cfg.IsSet(in.GetValue())
+        if sub := s.cfg.Sub(in.GetValue()); sub != nil {
+            v, found = sub.AllSettingsNested(), true
+        }
+    } else {
+        v, found = s.cfg.GetRedacted(in.GetValue())
+    }
+    if !found {
+        return nil, status.Errorf(codes.NotFound, "Key %q is not set", in.GetValue())
+    }
+
+    return toValue(v)
+}
+
+// Returns every key holding a value, sorted.
+func (s *Server) ListKeys(ctx context.Context, in *emptypb.Empty) (*structpb.ListValue, error) {
+    keys := s.cfg.AllKeys()
+
+    values := make([]*structpb.Value, len(keys))
+    for i, key := range keys {
+        values[i] = structpb.NewStringValue(key)
+    }
+
+    return &structpb.ListValue{Values: values}, nil
+}
+
+// Returns all settings as a tree.
+func (s *Server) GetAll(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error) {
+    v, err := toValue(s.cfg.AllSettingsNested(s.settingsOptions()...))
+    if err != nil {
+        return nil, err
+    }
+
+    return v.GetStructValue(), nil
+}
+
+// Streams a message for each key that changes until the client goes away or the
+// server is closed. A client too slow to keep up is dropped with Unavailable.
+func (s *Server) WatchChanges(in *emptypb.Empty, stream gogrpc.ServerStream) error {
+    ch := make(chan *structpb.Struct, watchBuffer)
+
+    s.mu.Lock()
+    s.watchers[ch] = struct{}{}
+    s.mu.Unlock()
+
+    defer func() {
+        s.mu.Lock()
+        delete(s.watchers, ch)
+        s.mu.Unlock()
+    }()
+
+    for {
+        select {
+        case msg, ok := <-ch:
+            if !ok {
+                return status.Error(codes.Unavailable, "Watcher fell behind")
+            }
+            if err := stream.SendMsg(msg); err != nil {
+                return err
+            }
+        case <-stream.Context().Done():
+            return nil
+        case <-s.stop:
+            return nil
+        }
+    }
+}
+
+func (s *Server) settingsOptions() []cfg.SettingsOption {
+    if s.opts.RevealSecrets {
+        return nil
+    }
+    return []cfg.SettingsOption{cfg.WithRedaction()}
+}
+
+func (s *Server) settings() map[string]interface{} {
+    return s.cfg.AllSettings(s.settingsOptions()...)
+}
+
+// watch compares the settings on every interval and reload, and sends the changed keys
+// to the watchers.
+func (s *Server) watch() {
+    ticker := time.NewTicker(s.opts.Interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+        case <-s.poke:
+        case <-s.stop:
+            return
+        }
+
+        s.broadcast(s.changes())
+    }
+}
+
+// changes returns a message for each key that differs from the last check.
+func (s *Server) changes() []*structpb.Struct {
+    settings := s.settings()
+
+    s.mu.Lock()
+    last := s.last
+    s.last = settings
+    s.mu.Unlock()
+
+    var msgs []*structpb.Struct
+    for key, v := range settings {
+        if old, ok := last[key]; ok && reflect.DeepEqual(old, v) {
+            continue
+        }
+
+        value, err := toValue(v)
+        if err != nil {
+            s.cfg.Logger().Error(fmt.Sprintf("Failed to encode %s: %v", key, err))
+            continue
+        }
+        msgs = append(msgs, &structpb.Struct{Fields: map[string]*structpb.Value{
+            "key":   structpb.NewStringValue(key),
+            "value": value,
+        }})
+    }
+    for key := range last {
+        if _, ok := settings[key]; !ok {
+            msgs = append(msgs, &structpb.Struct{Fields: map[string]*structpb.Value{
+                "key":     structpb.NewStringValue(key),
+                "deleted": structpb.NewBoolValue(true),
+            }})
+        }
+    }
+
+    return msgs
+}
+
+func (s *Server) broadcast(msgs []*structpb.Struct) {
+    if len(msgs) == 0 {
+        return
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for ch := range s.watchers {
+        for _, msg := range msgs {
+            select {
+            case ch <- msg:
+                continue
+            default:
+            }
+
+            close(ch)
+            delete(s.watchers, ch)
+            break
+        }
+    }
+}
+
+// toValue converts a config value to a protobuf value through its JSON form.
+func toValue(v interface{}) (*structpb.Value, error) {
+    b, err := json.Marshal(plain(v))
+    if err != nil {
+        return nil, status.Errorf(codes.Internal, "Encoding value: %v", err)
+    }
+
+    value := new(structpb.Value)
+    if err := protojson.Unmarshal(b, value); err != nil {
+        return nil, status.Errorf(codes.Internal, "Encoding value: %v", err)
+    }
+
+    return value, nil
+}
+
+// plain returns v with the maps decoded from YAML keyed by strings, which JSON needs.
+func plain(v interface{}) interface{} {
+    switch val := v.(type) {
+    case map[interface{}]interface{}:
+        m := make(map[string]interface{}, len(val))
+        for k, e := range val {
+            m[fmt.Sprint(k)] = plain(e)
+        }
+        return m
+    case map[string]interface{}:
+        m := make(map[string]interface{}, len(val))
+        for k, e := range val {
+            m[k] = plain(e)
+        }
+        return m
+    case []interface{}:
+        s := make([]interface{}, len(val))
+        for i, e := range val {
+            s[i] = plain(e)
+        }
+        return s
+    }
+
+    return v
+}
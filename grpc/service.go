@@ -0,0 +1,76 @@
+package grpc
+
+import (
+    "context"
+
+    gogrpc "google.golang.org/grpc"
+    "google.golang.org/protobuf/proto"
+    "google.golang.org/protobuf/types/known/emptypb"
+    "google.golang.org/protobuf/types/known/structpb"
+    "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// The methods a registered config service implements.
+type configService interface {
+    GetKey(ctx context.Context, in *wrapperspb.StringValue) (*structpb.Value, error)
+    ListKeys(ctx context.Context, in *emptypb.Empty) (*structpb.ListValue, error)
+    GetAll(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error)
+    WatchChanges(in *emptypb.Empty, stream gogrpc.ServerStream) error
+}
+
+// Full method names of the config service.
+const (
+    methodGetKey       = "/" + ServiceName + "/GetKey"
+    methodListKeys     = "/" + ServiceName + "/ListKeys"
+    methodGetAll       = "/" + ServiceName + "/GetAll"
+    methodWatchChanges = "/" + ServiceName + "/WatchChanges"
+)
+
+var serviceDesc = gogrpc.ServiceDesc{
+    ServiceName: ServiceName,
+    HandlerType: (*configService)(nil),
+    Methods: []gogrpc.MethodDesc{
+        {MethodName: "GetKey", Handler: unary(methodGetKey, (*Server).GetKey)},
+        {MethodName: "ListKeys", Handler: unary(methodListKeys, (*Server).ListKeys)},
+        {MethodName: "GetAll", Handler: unary(methodGetAll, (*Server).GetAll)},
+    },
+    Streams: []gogrpc.StreamDesc{
+        {StreamName: "WatchChanges", Handler: watchChangesHandler, ServerStreams: true},
+    },
+}
+
+var watchChangesDesc = gogrpc.StreamDesc{StreamName: "WatchChanges", ServerStreams: true}
+
+// unary returns the handler of a unary method calling call on the registered Server.
+func unary[Req proto.Message, Resp proto.Message](method string, call func(*Server, context.Context, Req) (Resp, error)) gogrpc.MethodHandler {
+    return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+        in := newMessage[Req]()
+        if err := dec(in); err != nil {
+            return nil, err
+        }
+
+        handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+            return call(srv.(*Server), ctx, req.(Req))
+        }
+        if interceptor == nil {
+            return handler(ctx, in)
+        }
+
+        return interceptor(ctx, in, &gogrpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+    }
+}
+
+// newMessage returns an empty message of the pointer type M.
+func newMessage[M proto.Message]() M {
+    var m M
+    return m.ProtoReflect().Type().New().Interface().(M)
+}
+
+func watchChangesHandler(srv interface{}, stream gogrpc.ServerStream) error {
+    in := new(emptypb.Empty)
+    if err := stream.RecvMsg(in); err != nil {
+        return err
+    }
+
+    return srv.(*Server).WatchChanges(in, stream)
+}
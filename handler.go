@@ -0,0 +1,170 @@
+package cfg
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "net/http"
+    "strings"
+
+    yaml3 "gopkg.in/yaml.v3"
+)
+
+// Largest request body accepted by the admin handler.
+const maxHandlerBody = 1 << 20
+
+// Options of the admin handler, see Handler.
+type HandlerOptions struct {
+    // Whether PUT requests may set keys in the overrides layer.
+    AllowWrites bool
+
+    // Decides whether a request may set keys. Writes are refused when it is nil, so
+    // AllowWrites alone is not enough to open the config up.
+    Authorize func(r *http.Request) bool
+}
+
+// The response of the admin handler for a single key.
+type HandlerKey struct {
+    Key    string         `json:"key" yaml:"key"`
+    Value  interface{}    `json:"value" yaml:"value"`
+    Found  bool           `json:"found" yaml:"found"`
+    Origin *HandlerOrigin `json:"origin,omitempty" yaml:"origin,omitempty"`
+}
+
+// Where a value served by the admin handler comes from, see KeyOrigin.
+type HandlerOrigin struct {
+    Kind  LayerKind `json:"kind" yaml:"kind"`
+    Layer string    `json:"layer" yaml:"layer"`
+    Key   string    `json:"key" yaml:"key"`
+    File  string    `json:"file,omitempty" yaml:"file,omitempty"`
+    Line  int       `json:"line,omitempty" yaml:"line,omitempty"`
+}
+
+// Returns an http.Handler serving the effective settings of cfg, the global config when
+// cfg is nil, with secret keys redacted. Requests are
+//
+//     GET                 all settings as a tree
+//     GET ?origins        the origin of every key
+//     GET ?key=a.b        the value and origin of a key
+//     PUT ?key=a.b        sets the key in the overrides layer to the body
+//
+// Responses are JSON, or YAML when the format query parameter is yaml or the Accept
+// header asks for it. PUT bodies are JSON or YAML and are only accepted when
+// opts.AllowWrites is set and opts.Authorize approves the request.
+func Handler(cfg *Config, opts HandlerOptions) http.Handler {
+    if cfg == nil {
+        cfg = c
+    }
+
+    return &adminHandler{cfg: cfg, opts: opts}
+}
+
+type adminHandler struct {
+    cfg  *Config
+    opts HandlerOptions
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    q := r.URL.Query()
+
+    switch r.Method {
+    case http.MethodGet, http.MethodHead:
+        switch {
+        case q.Get("key") != "":
+            h.write(w, r, http.StatusOK, h.key(q.Get("key")))
+        case q.Has("origins"):
+            h.write(w, r, http.StatusOK, h.origins())
+        default:
+            h.write(w, r, http.StatusOK, h.cfg.AllSettingsNested(WithRedaction()))
+        }
+
+    case http.MethodPut:
+        h.put(w, r, q.Get("key"))
+
+    default:
+        w.Header().Set("Allow", "GET, HEAD, PUT")
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    }
+}
+
+// put sets key in the overrides layer to the value in the request body.
+func (h *adminHandler) put(w http.ResponseWriter, r *http.Request, key string) {
+    if !h.opts.AllowWrites || h.opts.Authorize == nil || !h.opts.Authorize(r) {
+        http.Error(w, "Writes are not allowed", http.StatusForbidden)
+        return
+    }
+    if key == "" {
+        http.Error(w, "Missing key", http.StatusBadRequest)
+        return
+    }
+    body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHandlerBody))
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+        return
+    }
+
+    // YAML is a superset of JSON, so one decoder reads both.
+    var value interface{}
+    if err := yaml3.Unmarshal(body, &value); err != nil {
+        http.Error(w, "Invalid value: "+err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    if err := h.cfg.set(key, value); err != nil {
+        http.Error(w, err.Error(), http.StatusConflict)
+        return
+    }
+    h.cfg.logInfo("Admin handler set " + key + " from " + r.RemoteAddr)
+
+    h.write(w, r, http.StatusOK, h.key(key))
+}
+
+// key returns the redacted value of key along with its origin.
+func (h *adminHandler) key(key string) HandlerKey {
+    out := HandlerKey{Key: key}
+
+    // Keys holding a subtree spread over several layers have no single origin.
+    if o, ok := h.cfg.Origin(key); ok {
+        out.Origin = &HandlerOrigin{Kind: o.Kind, Layer: o.Layer, Key: o.Key, File: o.File, Line: o.Line}
+    }
+    out.Value, out.Found = h.cfg.GetRedacted(key)
+    out.Value = normalizeMaps(out.Value)
+
+    return out
+}
+
+// origins returns the origin of every key.
+func (h *adminHandler) origins() map[string]HandlerOrigin {
+    out := make(map[string]HandlerOrigin)
+    for _, key := range h.cfg.AllKeys() {
+        if o, ok := h.cfg.Origin(key); ok {
+            out[key] = HandlerOrigin{Kind: o.Kind, Layer: o.Layer, Key: o.Key, File: o.File, Line: o.Line}
+        }
+    }
+
+    return out
+}
+
+// write encodes v in the format asked for by the request.
+func (h *adminHandler) write(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+    var (
+        b   []byte
+        err error
+    )
+
+    if r.URL.Query().Get("format") == "yaml" || strings.Contains(r.Header.Get("Accept"), "yaml") {
+        w.Header().Set("Content-Type", "application/yaml")
+        b, err = yaml3.Marshal(v)
+    } else {
+        w.Header().Set("Content-Type", "application/json")
+        b, err = json.MarshalIndent(normalizeMaps(v), "", "  ")
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.WriteHeader(status)
+    if r.Method != http.MethodHead {
+        w.Write(b)
+    }
+}
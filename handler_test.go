@@ -0,0 +1,70 @@
+package cfg
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestHandlerPut(t *testing.T) {
+    allow := func(r *http.Request) bool { return true }
+
+    tests := []struct {
+        name   string
+        opts   HandlerOptions
+        frozen bool
+        target string
+        want   int
+    }{
+        {"set", HandlerOptions{AllowWrites: true, Authorize: allow}, false, "/?key=db.port", http.StatusOK},
+        {"frozen", HandlerOptions{AllowWrites: true, Authorize: allow}, true, "/?key=db.port", http.StatusConflict},
+        {"no writes", HandlerOptions{Authorize: allow}, false, "/?key=db.port", http.StatusForbidden},
+        {"no authorize", HandlerOptions{AllowWrites: true}, false, "/?key=db.port", http.StatusForbidden},
+        {"missing key", HandlerOptions{AllowWrites: true, Authorize: allow}, false, "/", http.StatusBadRequest},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.SetDefault("db.port", 5432)
+            if tt.frozen {
+                c.Freeze()
+            }
+
+            rec := httptest.NewRecorder()
+            Handler(c, tt.opts).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader("6432")))
+
+            if rec.Code != tt.want {
+                t.Fatalf("PUT %s = %d %s, want %d", tt.target, rec.Code, rec.Body, tt.want)
+            }
+
+            want := 5432
+            if tt.want == http.StatusOK {
+                want = 6432
+            }
+            if got := c.GetInt("db.port"); got != want {
+                t.Errorf("GetInt(db.port) = %d, want %d", got, want)
+            }
+        })
+    }
+}
+
+func TestHandlerGetRedacts(t *testing.T) {
+    c := New()
+    c.Set("db.user", "admin")
+    c.Set("db.password", "hunter2")
+    c.MarkSecret("db.password")
+
+    rec := httptest.NewRecorder()
+    Handler(c, HandlerOptions{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?key=db.password", nil))
+
+    var got HandlerKey
+    if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+        t.Fatal(err)
+    }
+    if !got.Found || got.Value != redactedValue {
+        t.Errorf("GET db.password = %+v, want %q", got, redactedValue)
+    }
+}
@@ -0,0 +1,94 @@
+package cfg
+
+import (
+    "io/ioutil"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Prefix of values included from a file, see SetFileInclusion.
+const includePrefix = "@file:"
+
+// Enables including the contents of files as values, written as "@file:/etc/ssl/cert.pem"
+// or "file:///etc/ssl/cert.pem", so certificates and scripts need not be inlined in the
+// config. Relative @file: paths are relative to the directory of the config file. Files
+// are read when their key is first read, and again only once they change. A file that
+// cannot be read makes its key read as unset, and its ResolveError is returned by GetE
+// and the other E getters. A resolver registered for file:// takes precedence, see
+// RegisterResolver.
+func SetFileInclusion(enable bool) { c.SetFileInclusion(enable) }
+func (c *Config) SetFileInclusion(enable bool) {
+    c.includeFiles = enable
+}
+
+// Contents of an included file as of its modification time and size.
+type includedFile struct {
+    modTime  time.Time
+    size     int64
+    contents string
+}
+
+// Caches included files by path.
+type includeCache struct {
+    mu    sync.Mutex
+    files map[string]includedFile
+}
+
+// includedPath returns the path of the file val includes, and false if val does not
+// include one.
+func (c *Config) includedPath(val string) (string, bool) {
+    if !c.includeFiles {
+        return "", false
+    }
+
+    if strings.HasPrefix(val, includePrefix) {
+        path := strings.TrimPrefix(val, includePrefix)
+        if !filepath.IsAbs(path) && c.configFile != "" && !isURL(c.configFile) && c.configFile != StdinConfigFile {
+            path = filepath.Join(filepath.Dir(c.configFile), path)
+        }
+        return path, true
+    }
+
+    if strings.HasPrefix(strings.ToLower(val), "file://") {
+        if u, err := url.Parse(val); err == nil {
+            return u.Path, true
+        }
+    }
+
+    return "", false
+}
+
+// includeFile returns the contents of the file at path, read again only if it changed
+// since it was cached.
+func (c *Config) includeFile(path string) (string, error) {
+    fi, err := os.Stat(path)
+    if err != nil {
+        return "", err
+    }
+
+    ic := &c.included
+    ic.mu.Lock()
+    f, ok := ic.files[path]
+    ic.mu.Unlock()
+    if ok && f.modTime.Equal(fi.ModTime()) && f.size == fi.Size() {
+        return f.contents, nil
+    }
+
+    b, err := ioutil.ReadFile(path)
+    if err != nil {
+        return "", err
+    }
+
+    ic.mu.Lock()
+    if ic.files == nil {
+        ic.files = make(map[string]includedFile)
+    }
+    ic.files[path] = includedFile{fi.ModTime(), fi.Size(), string(b)}
+    ic.mu.Unlock()
+
+    return string(b), nil
+}
@@ -0,0 +1,193 @@
+package cfg
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/spf13/cast"
+)
+
+// Denotes a value whose ${} references cannot be interpolated.
+type InterpolationError struct {
+    Key string
+    Err error
+}
+
+// Returns the formatted interpolation error.
+func (ie InterpolationError) Error() string {
+    return fmt.Sprintf("Cannot interpolate key %q: %v", ie.Key, ie.Err)
+}
+
+// Returns the reason the value cannot be interpolated.
+func (ie InterpolationError) Unwrap() error {
+    return ie.Err
+}
+
+// Enables interpolation of other keys into string values, so url can be written as
+// "http://${server.host}:${server.port}". References are replaced each time the key is
+// read, with the values of the referenced keys from whichever layer holds them, and may
+// themselves hold references. A value that is a single reference takes the type of the
+// referenced value. "$${" is written as a literal "${".
+//
+// A reference to a key that is not set takes the value of the environment variable of
+// that name. As in POSIX shells, "${PORT:-8080}" takes the value after ":-", itself
+// interpolated, when neither is set or it is empty, and "${TOKEN:?message}" fails with
+// message instead.
+//
+// A value referencing an unset key, or referencing itself through other keys, reads as
+// unset, and its InterpolationError is returned by GetE and the other E getters.
+func SetInterpolation(enable bool) { c.SetInterpolation(enable) }
+func (c *Config) SetInterpolation(enable bool) {
+    c.interpolate = enable
+}
+
+// expandValue returns s, the value of key, with its references interpolated and
+// resolved, see SetInterpolation and RegisterResolver. seen holds the keys whose values
+// are being interpolated.
+func (c *Config) expandValue(key string, s string, seen []string) (interface{}, error) {
+    if c.interpolate && strings.Contains(s, "${") {
+        v, err := c.interpolateValue(key, s, append(seen[:len(seen):len(seen)], key))
+        if err != nil {
+            return nil, err
+        }
+        if s, ok := v.(string); ok {
+            return c.resolveRef(key, s)
+        }
+        return v, nil
+    }
+
+    return c.resolveRef(key, s)
+}
+
+// interpolateValue replaces the references in s, the value of key.
+func (c *Config) interpolateValue(key string, s string, seen []string) (interface{}, error) {
+    var b strings.Builder
+
+    for {
+        i := strings.Index(s, "${")
+        if i < 0 {
+            b.WriteString(s)
+            break
+        }
+
+        if i > 0 && s[i-1] == '$' {
+            b.WriteString(s[:i-1])
+            b.WriteString("${")
+            s = s[i+2:]
+            continue
+        }
+
+        end := closingBrace(s[i:])
+        if end < 0 {
+            return nil, InterpolationError{key, fmt.Errorf("Unterminated reference in %q", s[i:])}
+        }
+
+        val, err := c.expandReference(key, s[i+2:i+end], seen)
+        if err != nil {
+            return nil, err
+        }
+
+        // A lone reference keeps the type of what it refers to.
+        if i == 0 && end == len(s)-1 && b.Len() == 0 {
+            return val, nil
+        }
+
+        str, err := cast.ToStringE(val)
+        if err != nil {
+            return nil, InterpolationError{key, fmt.Errorf("%q cannot be interpolated into a string", s[i:i+end+1])}
+        }
+        b.WriteString(s[:i])
+        b.WriteString(str)
+        s = s[i+end+1:]
+    }
+
+    return b.String(), nil
+}
+
+// expandReference returns the value of the reference ref, the text between "${" and
+// "}" in the value of key, applying its ":-" default or ":?" message.
+func (c *Config) expandReference(key, ref string, seen []string) (interface{}, error) {
+    name, op, arg := ref, "", ""
+    if i := strings.Index(ref, ":"); i >= 0 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+        name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+    }
+    name = strings.TrimSpace(name)
+    if name == "" {
+        return nil, InterpolationError{key, errors.New("Empty reference")}
+    }
+
+    val, err := c.referencedValue(key, name, seen)
+    if err != nil {
+        return nil, err
+    }
+    if val != nil && val != "" {
+        return val, nil
+    }
+
+    switch op {
+    case ":-":
+        return c.interpolateValue(key, arg, seen)
+    case ":?":
+        if arg == "" {
+            arg = fmt.Sprintf("%s is not set", name)
+        }
+        return nil, InterpolationError{key, errors.New(arg)}
+    }
+    if val == nil {
+        return nil, InterpolationError{key, fmt.Errorf("Key %q is not set", name)}
+    }
+
+    return val, nil
+}
+
+// referencedValue returns the value of ref, referenced from key, itself interpolated,
+// or that of the environment variable ref if the key is not set. It returns nil if
+// neither is set.
+func (c *Config) referencedValue(key, ref string, seen []string) (interface{}, error) {
+    lcaseRef := c.normalizeKey(ref)
+
+    c.mu.RLock()
+    realRef := c.realKey(lcaseRef)
+    val, l, _ := c.find(lcaseRef)
+    c.mu.RUnlock()
+
+    val, _ = c.compute(realRef, val, l)
+
+    if stringInSlice(realRef, seen) {
+        return nil, InterpolationError{key, fmt.Errorf("Cyclic reference %s -> %s", strings.Join(seen, " -> "), realRef)}
+    }
+    if val == nil {
+        if env, ok := os.LookupEnv(ref); ok {
+            return env, nil
+        }
+        return nil, nil
+    }
+
+    if s, ok := val.(string); ok {
+        return c.expandValue(realRef, s, seen)
+    }
+
+    return val, nil
+}
+
+// closingBrace returns the index of the "}" closing the reference s starts with, past
+// any references nested in it, or -1 if it is not closed.
+func closingBrace(s string) int {
+    depth := 0
+    for i := 0; i < len(s); i++ {
+        switch {
+        case strings.HasPrefix(s[i:], "${"):
+            depth++
+            i++
+        case s[i] == '}':
+            depth--
+            if depth == 0 {
+                return i
+            }
+        }
+    }
+
+    return -1
+}
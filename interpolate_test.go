@@ -0,0 +1,59 @@
+package cfg
+
+import (
+    "errors"
+    "strings"
+    "testing"
+)
+
+func TestInterpolation(t *testing.T) {
+    t.Setenv("CFG_TEST_PORT", "9090")
+
+    tests := []struct {
+        name  string
+        value interface{}
+        want  interface{}
+        err   string
+    }{
+        {"reference", "http://${server.host}:${server.port}", "http://localhost:8080", ""},
+        {"typed", "${server.port}", 8080, ""},
+        {"escaped", "$${server.host}", "${server.host}", ""},
+        {"escaped and expanded", "$${x} ${server.host}", "${x} localhost", ""},
+        {"environment", "${CFG_TEST_PORT}", "9090", ""},
+        {"default", "${missing:-fallback}", "fallback", ""},
+        {"default interpolated", "${missing:-${server.host}}", "localhost", ""},
+        {"default unused", "${server.host:-fallback}", "localhost", ""},
+        {"empty uses default", "${empty:-fallback}", "fallback", ""},
+        {"required set", "${server.host:?host is required}", "localhost", ""},
+        {"required unset", "${missing:?missing is required}", nil, "missing is required"},
+        {"unset", "${missing}", nil, "missing"},
+        {"cycle", "${self}", nil, "self"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.SetInterpolation(true)
+            c.Set("server.host", "localhost")
+            c.Set("server.port", 8080)
+            c.Set("empty", "")
+            c.Set("self", "${self}")
+            c.Set("value", tt.value)
+
+            got, err := c.GetE("value")
+            var ie InterpolationError
+            if tt.err != "" {
+                if !errors.As(err, &ie) || !strings.Contains(err.Error(), tt.err) {
+                    t.Errorf("GetE(value) error = %v, want InterpolationError mentioning %q", err, tt.err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got != tt.want {
+                t.Errorf("GetE(value) = %#v, want %#v", got, tt.want)
+            }
+        })
+    }
+}
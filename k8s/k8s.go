@@ -0,0 +1,201 @@
+// Package k8s provides cfg sources backed by Kubernetes ConfigMaps and Secrets, read
+// either through the API server using the in-cluster service account or from a volume mount.
+package k8s
+
+import (
+    "bytes"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "github.com/nwlucas/cfg"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Adds the ConfigMap namespace/name, read through the API server, as a source on c.
+// A non-zero refresh re-reads it on that interval so updates are picked up.
+func AddConfigMapSource(c *cfg.Config, namespace, name string, refresh time.Duration) error {
+    return addAPISource(c, "configmaps", namespace, name, refresh)
+}
+
+// Adds the Secret namespace/name, read through the API server, as a source on c.
+// A non-zero refresh re-reads it on that interval so updates are picked up.
+func AddSecretSource(c *cfg.Config, namespace, name string, refresh time.Duration) error {
+    return addAPISource(c, "secrets", namespace, name, refresh)
+}
+
+// Adds a mounted ConfigMap or Secret volume at dir as a source on c.
+// A non-zero refresh re-reads it on that interval so updates are picked up.
+func AddMountedSource(c *cfg.Config, dir string, refresh time.Duration) error {
+    fetch := func() (map[string]interface{}, error) {
+        files, err := ioutil.ReadDir(dir)
+        if err != nil {
+            return nil, err
+        }
+
+        data := make(map[string][]byte)
+        for _, f := range files {
+            // Kubernetes keeps its atomic-swap bookkeeping in ..data and friends.
+            if strings.HasPrefix(f.Name(), ".") || f.IsDir() {
+                continue
+            }
+
+            b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+            if err != nil {
+                if os.IsNotExist(err) {
+                    continue
+                }
+                return nil, err
+            }
+            data[f.Name()] = b
+        }
+
+        return entries(data)
+    }
+
+    return c.AddSource("k8s:"+dir, fetch, refresh)
+}
+
+// Converts ConfigMap or Secret entries into config values. Entries named with a
+// supported config extension are parsed and merged in, all others become string values.
+func entries(data map[string][]byte) (map[string]interface{}, error) {
+    m := make(map[string]interface{})
+
+    for key, val := range data {
+        ext := strings.TrimPrefix(filepath.Ext(key), ".")
+        if ext != "" && stringInSlice(ext, cfg.SupportedExts) {
+            doc, err := cfg.Decode(bytes.NewReader(val), ext)
+            if err != nil {
+                return nil, fmt.Errorf("Parsing %q: %v", key, err)
+            }
+            for k, v := range doc {
+                m[k] = v
+            }
+            continue
+        }
+
+        m[key] = strings.TrimSpace(string(val))
+    }
+
+    return m, nil
+}
+
+func addAPISource(c *cfg.Config, resource, namespace, name string, refresh time.Duration) error {
+    client, err := inClusterClient()
+    if err != nil {
+        return err
+    }
+
+    fetch := func() (map[string]interface{}, error) {
+        return client.get(resource, namespace, name)
+    }
+
+    return c.AddSource(fmt.Sprintf("k8s:%s/%s/%s", resource, namespace, name), fetch, refresh)
+}
+
+type apiClient struct {
+    host  string
+    token string
+    http  *http.Client
+}
+
+func inClusterClient() (*apiClient, error) {
+    host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+    if host == "" || port == "" {
+        return nil, fmt.Errorf("Not running in a Kubernetes cluster")
+    }
+
+    token, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "token"))
+    if err != nil {
+        return nil, err
+    }
+
+    ca, err := ioutil.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+    if err != nil {
+        return nil, err
+    }
+
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(ca) {
+        return nil, fmt.Errorf("No certificates found in service account CA")
+    }
+
+    return &apiClient{
+        host:  "https://" + net.JoinHostPort(host, port),
+        token: strings.TrimSpace(string(token)),
+        http: &http.Client{
+            Timeout:   30 * time.Second,
+            Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+        },
+    }, nil
+}
+
+func (a *apiClient) get(resource, namespace, name string) (map[string]interface{}, error) {
+    url := fmt.Sprintf("%s/api/v1/namespaces/%s/%s/%s", a.host, namespace, resource, name)
+
+    req, err := http.NewRequest("GET", url, nil)
+    if err != nil {
+        return nil, err
+    }
+    req.Header.Set("Authorization", "Bearer "+a.token)
+    req.Header.Set("Accept", "application/json")
+
+    resp, err := a.http.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("Fetching %s %s/%s: %s", resource, namespace, name, resp.Status)
+    }
+
+    var obj struct {
+        Data       map[string]string `json:"data"`
+        BinaryData map[string]string `json:"binaryData"`
+    }
+    if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+        return nil, err
+    }
+
+    data := make(map[string][]byte)
+    for key, val := range obj.Data {
+        if resource == "secrets" {
+            b, err := base64.StdEncoding.DecodeString(val)
+            if err != nil {
+                return nil, fmt.Errorf("Decoding secret key %q: %v", key, err)
+            }
+            data[key] = b
+        } else {
+            data[key] = []byte(val)
+        }
+    }
+    for key, val := range obj.BinaryData {
+        b, err := base64.StdEncoding.DecodeString(val)
+        if err != nil {
+            return nil, fmt.Errorf("Decoding binary key %q: %v", key, err)
+        }
+        data[key] = b
+    }
+
+    return entries(data)
+}
+
+func stringInSlice(a string, list []string) bool {
+    for _, b := range list {
+        if b == a {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,159 @@
+package cfg
+
+import (
+    "regexp"
+    "strings"
+    "unicode"
+)
+
+// Makes keys case sensitive. By default every key, including the keys of nested maps,
+// is lowercased so lookups ignore case. Case sensitive keys are kept as written, for
+// maps whose keys are significant such as HTTP header names. Set it before reading
+// any config.
+func SetKeysCaseSensitive(sensitive bool) { c.SetKeysCaseSensitive(sensitive) }
+func (c *Config) SetKeysCaseSensitive(sensitive bool) {
+    c.caseSensitive = sensitive
+    c.invalidate()
+}
+
+// Returns whether keys are case sensitive.
+func KeysCaseSensitive() bool { return c.KeysCaseSensitive() }
+func (c *Config) KeysCaseSensitive() bool {
+    return c.caseSensitive
+}
+
+// Makes keys that differ only in naming style the same key, so max-conns, max_conns,
+// maxConns and MaxConns all read and set max_conns, whichever style a config file,
+// source, layer or caller spells them in. Every element of a key is turned into
+// lowercase words joined by underscores, splitting words at hyphens, underscores, spaces
+// and where lowercase turns to uppercase, regardless of SetKeysCaseSensitive.
+//
+// Writing the config spells every element the way the config files read spelled it, so
+// a file written in camelCase stays in camelCase. Set it before reading any config.
+func SetKeyNormalization(enable bool) { c.SetKeyNormalization(enable) }
+func (c *Config) SetKeyNormalization(enable bool) {
+    c.keyNormalization = enable
+    c.invalidate()
+}
+
+// normalizeKey returns key the way it is stored, lowercased unless keys are case
+// sensitive or in snake_case with key normalization, with indexes written as
+// servers[0] turned into path elements.
+func (c *Config) normalizeKey(key string) string {
+    if strings.Contains(key, "[") {
+        key = keyIndex.ReplaceAllString(key, c.keyDelm+"${1}")
+    }
+
+    switch {
+    case c.keyNormalization:
+        path := strings.Split(key, c.keyDelm)
+        for i, elem := range path {
+            path[i] = snakeKey(elem)
+        }
+        return strings.Join(path, c.keyDelm)
+    case c.caseSensitive:
+        return key
+    }
+    return strings.ToLower(key)
+}
+
+// snakeKey returns key as lowercase words joined by underscores, see SetKeyNormalization.
+func snakeKey(key string) string {
+    if strings.IndexFunc(key, func(r rune) bool { return r == '-' || r == ' ' || unicode.IsUpper(r) }) < 0 {
+        return key
+    }
+
+    var b strings.Builder
+    runes := []rune(key)
+    for i, r := range runes {
+        switch {
+        case r == '-' || r == '_' || r == ' ':
+            if i == 0 || !isKeySeparator(runes[i-1]) {
+                b.WriteByte('_')
+            }
+        case unicode.IsUpper(r):
+            // A new word starts after a lowercase letter or digit, and at the last
+            // capital of an acronym followed by a lowercase word, as in HTTPServer.
+            if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+                unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+                b.WriteByte('_')
+            }
+            b.WriteRune(unicode.ToLower(r))
+        default:
+            b.WriteRune(r)
+        }
+    }
+
+    return b.String()
+}
+
+func isKeySeparator(r rune) bool {
+    return r == '-' || r == '_' || r == ' '
+}
+
+// Matches an index written in brackets in a key path.
+var keyIndex = regexp.MustCompile(`\[(\d+)\]`)
+
+// normalizeKeys canonicalizes the maps nested in m, renaming every key the way
+// normalizeKey does.
+func (c *Config) normalizeKeys(m map[string]interface{}) {
+    switch {
+    case c.keyNormalization:
+        canonicalizeMap(m, snakeKey)
+    case c.caseSensitive:
+        canonicalizeMap(m, nil)
+    default:
+        canonicalizeMap(m, strings.ToLower)
+    }
+}
+
+// normalizeFileKeys is normalizeKeys for the maps read from config files, noting how
+// they spelled the keys key normalization renames so writing the config can spell
+// them the same way.
+func (c *Config) normalizeFileKeys(m map[string]interface{}) {
+    if c.keyNormalization {
+        c.noteSpellings(m)
+    }
+    c.normalizeKeys(m)
+}
+
+func (c *Config) noteSpellings(m map[string]interface{}) {
+    for k, v := range m {
+        if sk := snakeKey(k); sk != k {
+            c.keySpellings.Store(sk, k)
+        }
+        if sub, ok := toStringMap(v); ok {
+            c.noteSpellings(sub)
+        }
+    }
+}
+
+// respell renames the keys of m, and of the maps nested in it, back to the way config
+// files spelled them, in place.
+func (c *Config) respell(m map[string]interface{}) {
+    spellings := make(map[string]string)
+    for k, v := range m {
+        if sub, ok := v.(map[string]interface{}); ok {
+            c.respell(sub)
+        }
+        if spelling, ok := c.keySpellings.Load(k); ok {
+            spellings[k] = spelling.(string)
+        }
+    }
+
+    for k, spelling := range spellings {
+        m[spelling] = m[k]
+        delete(m, k)
+    }
+}
+
+// normalizeValue returns a copy of v with the keys of any map in it normalized,
+// leaving v itself untouched.
+func (c *Config) normalizeValue(v interface{}) interface{} {
+    v = normalizeMaps(v)
+    if m, ok := v.(map[string]interface{}); ok {
+        c.normalizeKeys(m)
+    }
+
+    return v
+}
@@ -0,0 +1,246 @@
+package cfg
+
+import (
+    "fmt"
+    "log/slog"
+    "sync"
+)
+
+// Priorities of the built-in layers. Custom layers are placed among them by priority,
+// higher priorities take precedence.
+const (
+    PriorityDefault  = 100
+    PrioritySource   = 200
+    PriorityConfig   = 300
+    PriorityComputed = 350
+    PriorityOverride = 400
+)
+
+// Denotes a layer name that has already been registered.
+type LayerExistsError string
+
+// Returns the error for a duplicate layer.
+func (str LayerExistsError) Error() string {
+    return fmt.Sprintf("Layer %q already registered", string(str))
+}
+
+// Denotes a layer name that has not been registered.
+type LayerNotFoundError string
+
+// Returns the error for a missing layer.
+func (str LayerNotFoundError) Error() string {
+    return fmt.Sprintf("Layer %q not registered", string(str))
+}
+
+// A custom layer of values consulted by Get at a fixed priority.
+//
+// Values are replaced copy-on-write so lookups never observe a partial update.
+type NamedLayer struct {
+    name     string
+    priority int
+    seq      int
+    config   *Config
+
+    mu     sync.RWMutex
+    values map[string]interface{}
+}
+
+// Returns the name the layer was added under.
+func (l *NamedLayer) Name() string {
+    return l.name
+}
+
+// Returns the priority the layer was added at.
+func (l *NamedLayer) Priority() int {
+    return l.priority
+}
+
+// Sets the value for the key in this layer.
+func (l *NamedLayer) Set(key string, value interface{}) {
+    c := l.config
+    c.mustNotBeFrozen()
+
+    value = c.normalizeValue(value)
+
+    auditing := c.auditing()
+
+    // mu serializes the changes to the layer, l.mu only guards the swap from lookups
+    c.mu.Lock()
+    values := copyMap(l.snapshot())
+    key, shadowed, old := c.setIn(values, key, value, auditing)
+    l.swap(values)
+    c.mu.Unlock()
+
+    c.logEvent(slog.LevelDebug, "key_overridden", slog.String("key", key), slog.String("shadowed", string(shadowed.kind)), slog.String("layer", l.name))
+    if auditing {
+        c.recordChange(AuditSet, key, old, value)
+    }
+}
+
+// Removes key, and everything beneath it, from this layer so lower layers are
+// consulted for it again.
+func (l *NamedLayer) Unset(key string) {
+    c := l.config
+    c.mustNotBeFrozen()
+
+    auditing := c.auditing()
+
+    c.mu.Lock()
+    values := copyMap(l.snapshot())
+    key, old := c.unsetIn(values, key, auditing)
+    l.swap(values)
+    c.mu.Unlock()
+
+    if auditing {
+        c.recordChange(AuditUnset, key, old, nil)
+    }
+}
+
+// Replaces the entire contents of the layer. Each value is stored as Set would store
+// it, and the keys the layer no longer holds are audited as unset.
+func (l *NamedLayer) Replace(values map[string]interface{}) {
+    c := l.config
+    c.mustNotBeFrozen()
+
+    normalized := make(map[string]interface{}, len(values))
+    for k, v := range values {
+        normalized[k] = c.normalizeValue(v)
+    }
+
+    auditing := c.auditing()
+
+    var (
+        changes  []AuditEntry
+        shadowed []layer
+    )
+
+    c.mu.Lock()
+    prev := l.snapshot()
+    replaced := make(map[string]interface{}, len(normalized))
+    for _, k := range sortedKeys(normalized) {
+        key, s, _ := c.setIn(replaced, k, normalized[k], false)
+        e := AuditEntry{Op: AuditSet, Key: key, New: normalized[k]}
+        if auditing {
+            e.Old, _ = c.searchLayer(prev, key)
+        }
+        changes, shadowed = append(changes, e), append(shadowed, s)
+    }
+    if auditing {
+        for _, key := range sortedKeys(prev) {
+            if _, exists := replaced[key]; !exists {
+                changes = append(changes, AuditEntry{Op: AuditUnset, Key: key, Old: prev[key]})
+            }
+        }
+    }
+    l.swap(replaced)
+    c.mu.Unlock()
+
+    for i, e := range changes {
+        if e.Op == AuditSet {
+            c.logEvent(slog.LevelDebug, "key_overridden", slog.String("key", e.Key), slog.String("shadowed", string(shadowed[i].kind)), slog.String("layer", l.name))
+        }
+        if auditing {
+            c.recordChange(e.Op, e.Key, e.Old, e.New)
+        }
+    }
+    c.changed()
+}
+
+// swap replaces the values of the layer. Caller must hold the config's mu.
+func (l *NamedLayer) swap(values map[string]interface{}) {
+    l.mu.Lock()
+    l.values = values
+    l.mu.Unlock()
+
+    l.config.invalidate()
+}
+
+// Returns a copy of the values held by the layer.
+func (l *NamedLayer) Values() map[string]interface{} {
+    values := l.snapshot()
+
+    copied := make(map[string]interface{}, len(values))
+    for k, v := range values {
+        copied[k] = v
+    }
+
+    return copied
+}
+
+func (l *NamedLayer) snapshot() map[string]interface{} {
+    l.mu.RLock()
+    defer l.mu.RUnlock()
+
+    return l.values
+}
+
+// Adds an empty custom layer consulted at the given priority, see the Priority
+// constants for where the built-in layers sit. A layer added at the same priority as
+// another takes precedence over it.
+func AddLayer(name string, priority int) (*NamedLayer, error) { return c.AddLayer(name, priority) }
+func (c *Config) AddLayer(name string, priority int) (*NamedLayer, error) {
+    if err := c.checkFrozen(); err != nil {
+        return nil, err
+    }
+
+    c.sourceMu.Lock()
+    defer c.sourceMu.Unlock()
+
+    if _, exists := c.getLayer(name); exists {
+        return nil, LayerExistsError(name)
+    }
+
+    c.layerSeq++
+    l := &NamedLayer{
+        name:     name,
+        priority: priority,
+        seq:      c.layerSeq,
+        config:   c,
+        values:   make(map[string]interface{}),
+    }
+    c.customLayers = append(c.customLayers, l)
+    c.invalidate()
+
+    return l, nil
+}
+
+// Returns the named custom layer.
+func Layer(name string) (*NamedLayer, bool) { return c.Layer(name) }
+func (c *Config) Layer(name string) (*NamedLayer, bool) {
+    c.sourceMu.RLock()
+    defer c.sourceMu.RUnlock()
+
+    return c.getLayer(name)
+}
+
+// Removes the named custom layer.
+func RemoveLayer(name string) error { return c.RemoveLayer(name) }
+func (c *Config) RemoveLayer(name string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.sourceMu.Lock()
+    defer c.sourceMu.Unlock()
+
+    for i, l := range c.customLayers {
+        if l.name == name {
+            c.customLayers = append(c.customLayers[:i], c.customLayers[i+1:]...)
+            c.invalidate()
+            return nil
+        }
+    }
+
+    return LayerNotFoundError(name)
+}
+
+// caller must hold sourceMu
+func (c *Config) getLayer(name string) (*NamedLayer, bool) {
+    for _, l := range c.customLayers {
+        if l.name == name {
+            return l, true
+        }
+    }
+
+    return nil, false
+}
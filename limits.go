@@ -0,0 +1,200 @@
+package cfg
+
+import (
+    "bytes"
+    "fmt"
+    "io"
+    "strings"
+
+    yaml3 "gopkg.in/yaml.v3"
+)
+
+// Maximum nesting depth of a config document unless set otherwise, see
+// SetMaxConfigDepth.
+const DefaultMaxConfigDepth = 100
+
+// Maximum number of nodes aliases may add to a YAML document unless set otherwise, see
+// SetMaxYAMLAliasExpansion.
+const DefaultMaxYAMLAliasExpansion = 100000
+
+// Denotes a config document exceeding one of the limits guarding against hostile input,
+// such as SetMaxConfigDepth.
+type ConfigLimitError struct {
+    File string
+
+    // The limit exceeded: "depth", "number of keys" or "YAML alias expansion"
+    Limit string
+    Max   int
+}
+
+// Returns the formatted limit error.
+func (cle ConfigLimitError) Error() string {
+    if cle.File == "" {
+        return fmt.Sprintf("Config exceeds the maximum %s of %d", cle.Limit, cle.Max)
+    }
+    return fmt.Sprintf("Config file %q exceeds the maximum %s of %d", cle.File, cle.Limit, cle.Max)
+}
+
+// Sets how deeply maps and lists may be nested in a config document,
+// DefaultMaxConfigDepth by default, so a tenant-supplied fragment cannot exhaust the
+// stack of code walking it. A top-level key is at depth 1. Reading a deeper document
+// fails with a ConfigLimitError. Zero or less removes the limit.
+func SetMaxConfigDepth(n int) { c.SetMaxConfigDepth(n) }
+func (c *Config) SetMaxConfigDepth(n int) {
+    c.maxConfigDepth = n
+}
+
+// Sets how many keys a config document may hold, counting the keys of nested maps,
+// those in lists included. Reading a document with more fails with a
+// ConfigLimitError. Zero or less, the default, removes the limit.
+func SetMaxConfigKeys(n int) { c.SetMaxConfigKeys(n) }
+func (c *Config) SetMaxConfigKeys(n int) {
+    c.maxConfigKeys = n
+}
+
+// Sets how many nodes aliases may add to a YAML document once expanded,
+// DefaultMaxYAMLAliasExpansion by default. Aliases of aliases multiply, so a document of
+// a few hundred bytes can expand to billions of values, the billion laughs attack.
+// Such a document fails with a ConfigLimitError before it is decoded. Zero or less
+// removes the limit.
+func SetMaxYAMLAliasExpansion(n int) { c.SetMaxYAMLAliasExpansion(n) }
+func (c *Config) SetMaxYAMLAliasExpansion(n int) {
+    c.maxAliasExpansion = n
+}
+
+// parseLimited parses a configuration document like parseConfig, failing with a
+// ConfigTooLargeError or ConfigLimitError if it exceeds the limits set.
+//
+// The document is not streamed into the decoder: it is read whole, up to the maximum
+// config size, as the YAML alias check has to parse it before it is decoded. Config
+// files arrive here read already, since their checksums, signatures, decryption,
+// templates and the lines of key origins need the whole file as well.
+func (c *Config) parseLimited(in io.Reader, configType string) (map[string]interface{}, error) {
+    var size int64
+    if r, ok := in.(*bytes.Reader); ok {
+        size = int64(r.Len())
+    }
+
+    doc, err := c.readConfigFrom("", in, size)
+    if err != nil {
+        return nil, err
+    }
+
+    if t := strings.ToLower(configType); (t == "yaml" || t == "yml") && c.maxAliasExpansion > 0 {
+        if err := checkYAMLAliases(doc, c.maxAliasExpansion); err != nil {
+            return nil, err
+        }
+    }
+
+    m, err := parseConfig(bytes.NewReader(doc), configType)
+    if err != nil {
+        return nil, err
+    }
+
+    if c.maxConfigDepth > 0 || c.maxConfigKeys > 0 {
+        keys := 0
+        if err := c.checkShape(m, 1, &keys); err != nil {
+            return nil, err
+        }
+    }
+
+    return m, nil
+}
+
+// checkShape fails if v, at depth, nests deeper or holds more keys than allowed. keys
+// counts the keys seen so far.
+func (c *Config) checkShape(v interface{}, depth int, keys *int) error {
+    var children []interface{}
+
+    switch v := v.(type) {
+    case map[string]interface{}:
+        *keys += len(v)
+        for _, child := range v {
+            children = append(children, child)
+        }
+    case map[interface{}]interface{}:
+        *keys += len(v)
+        for _, child := range v {
+            children = append(children, child)
+        }
+    case []interface{}:
+        children = v
+    default:
+        return nil
+    }
+
+    if c.maxConfigKeys > 0 && *keys > c.maxConfigKeys {
+        return ConfigLimitError{Limit: "number of keys", Max: c.maxConfigKeys}
+    }
+    if c.maxConfigDepth > 0 && depth > c.maxConfigDepth && len(children) > 0 {
+        return ConfigLimitError{Limit: "depth", Max: c.maxConfigDepth}
+    }
+
+    for _, child := range children {
+        if err := c.checkShape(child, depth+1, keys); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// checkYAMLAliases fails if expanding the aliases of the YAML document doc adds more
+// than max nodes to it, without expanding them.
+func checkYAMLAliases(doc []byte, max int) error {
+    if !bytes.ContainsRune(doc, '*') {
+        return nil
+    }
+
+    var root yaml3.Node
+    if err := yaml3.Unmarshal(doc, &root); err != nil {
+        // Left for the decoder to report.
+        return nil
+    }
+
+    nodes := countNodes(&root)
+    limit := nodes + max
+    if expandedSize(&root, limit, make(map[*yaml3.Node]int)) > limit {
+        return ConfigLimitError{Limit: "YAML alias expansion", Max: max}
+    }
+
+    return nil
+}
+
+// countNodes returns the number of nodes in the tree beneath n, aliases counting as one.
+func countNodes(n *yaml3.Node) int {
+    count := 1
+    for _, child := range n.Content {
+        count += countNodes(child)
+    }
+    return count
+}
+
+// expandedSize returns the number of nodes in the tree beneath n with every alias
+// replaced by the nodes it refers to, or more than limit once it passes limit. sizes
+// memoizes the sizes found, -1 marking nodes being sized so aliases of an enclosing
+// node count as unbounded.
+func expandedSize(n *yaml3.Node, limit int, sizes map[*yaml3.Node]int) int {
+    if n.Kind == yaml3.AliasNode && n.Alias != nil {
+        return expandedSize(n.Alias, limit, sizes)
+    }
+
+    if size, ok := sizes[n]; ok {
+        if size < 0 {
+            return limit + 1
+        }
+        return size
+    }
+    sizes[n] = -1
+
+    size := 1
+    for _, child := range n.Content {
+        if size += expandedSize(child, limit, sizes); size > limit {
+            size = limit + 1
+            break
+        }
+    }
+    sizes[n] = size
+
+    return size
+}
@@ -0,0 +1,136 @@
+package cfg
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// Extension of the file locked while a config file is read or written,
+// config.yaml.lock for config.yaml. The config file itself cannot carry the lock as
+// writes replace it with a new file.
+const LockExt = ".lock"
+
+// Denotes a failure to lock a config file.
+type ConfigLockError struct {
+    File string
+    Err  error
+}
+
+// Returns the formatted lock error.
+func (le ConfigLockError) Error() string {
+    return fmt.Sprintf("Cannot lock config file %q: %s", le.File, le.Err)
+}
+
+// Returns the underlying error.
+func (le ConfigLockError) Unwrap() error {
+    return le.Err
+}
+
+// Enables advisory locking of the config file, so that processes sharing it, say the
+// application, a CLI and an operator script, never read a half-finished edit or
+// overwrite each other's. Reading the config file takes a shared lock and writing it
+// an exclusive one, on a LockExt file next to it that is left in place afterwards.
+//
+// Locks are flock(2) locks and only keep out processes that lock too. On platforms
+// without flock writers are only serialized within the process. Config files read
+// through WithFS, from standard input or from URLs are never locked.
+func SetFileLocking(enable bool) { c.SetFileLocking(enable) }
+func (c *Config) SetFileLocking(enable bool) {
+    c.fileLocking = enable
+}
+
+// Edits the config file with fn, holding an exclusive lock on the file throughout so
+// concurrent edits from other processes are neither lost nor interleaved. The lock is
+// taken whether or not SetFileLocking is enabled.
+//
+// fn is handed a copy of the config with the file as it is once the lock is taken and
+// without the values set with Set, so it sees the latest contents and what it sets is
+// what gets written, as WriteConfig would with opts. The config is then read in again.
+// The file is left untouched when fn returns an error.
+//
+//     err := cfg.LockedUpdate(func(c *cfg.Config) error {
+//         c.Set("runs", c.GetInt("runs")+1)
+//         return nil
+//     })
+func LockedUpdate(fn func(*Config) error, opts ...WriteOption) error {
+    return c.LockedUpdate(fn, opts...)
+}
+func (c *Config) LockedUpdate(fn func(*Config) error, opts ...WriteOption) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    filename, err := c.writableConfigFile()
+    if err != nil {
+        return err
+    }
+
+    unlock, err := lockConfigFile(filename, true)
+    if err != nil {
+        return err
+    }
+    defer unlock()
+
+    ctx := context.WithValue(context.Background(), heldLockKey{}, filename)
+
+    edit := c.Clone()
+    edit.overrides = make(map[string]interface{})
+    if err := edit.ReadInConfigContext(ctx); err != nil {
+        return err
+    }
+
+    if err := fn(edit); err != nil {
+        return err
+    }
+
+    if err := edit.writeConfigFile(filename, edit.getConfigType(), opts); err != nil {
+        return err
+    }
+
+    return c.ReadInConfigContext(ctx)
+}
+
+// heldLockKey marks a context whose caller already holds the lock on the config file
+// named by its value.
+type heldLockKey struct{}
+
+// lockForRead takes a shared lock on the config file filename if locking is enabled
+// and the caller does not hold the lock already. The returned func releases it.
+func (c *Config) lockForRead(ctx context.Context, filename string) (unlock func(), err error) {
+    if !c.fileLocking || c.fsys != nil || ctx.Value(heldLockKey{}) == filename {
+        return func() {}, nil
+    }
+
+    return lockConfigFile(filename, false)
+}
+
+// lockConfigFile locks the LockExt file of filename, exclusively or shared, blocking
+// until the lock is granted. Symlinks are followed so every path to the same file
+// shares one lock. Readers not permitted to create the lock file go without the lock,
+// so config in read-only directories stays readable.
+func lockConfigFile(filename string, exclusive bool) (unlock func(), err error) {
+    if real, err := filepath.EvalSymlinks(filename); err == nil {
+        filename = real
+    }
+
+    f, err := os.OpenFile(filename+LockExt, os.O_RDONLY|os.O_CREATE, 0644)
+    if err != nil && !exclusive && os.IsPermission(err) {
+        return func() {}, nil
+    }
+    if err != nil {
+        return nil, ConfigLockError{filename, err}
+    }
+
+    release, err := lockFile(f, exclusive)
+    if err != nil {
+        f.Close()
+        return nil, ConfigLockError{filename, err}
+    }
+
+    return func() {
+        release()
+        f.Close()
+    }, nil
+}
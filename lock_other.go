@@ -0,0 +1,24 @@
+//go:build !unix
+
+package cfg
+
+import (
+    "os"
+    "sync"
+)
+
+// Without flock, lock files are locked within the process only, one mutex per file.
+var fileLocks sync.Map
+
+// lockFile locks the mutex of f, returning the func releasing the lock.
+func lockFile(f *os.File, exclusive bool) (unlock func(), err error) {
+    v, _ := fileLocks.LoadOrStore(f.Name(), &sync.RWMutex{})
+    mu := v.(*sync.RWMutex)
+
+    if exclusive {
+        mu.Lock()
+        return mu.Unlock, nil
+    }
+    mu.RLock()
+    return mu.RUnlock, nil
+}
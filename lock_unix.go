@@ -0,0 +1,28 @@
+//go:build unix
+
+package cfg
+
+import (
+    "os"
+    "syscall"
+)
+
+// lockFile flocks f, returning the func releasing the lock.
+func lockFile(f *os.File, exclusive bool) (unlock func(), err error) {
+    how := syscall.LOCK_SH
+    if exclusive {
+        how = syscall.LOCK_EX
+    }
+
+    for {
+        err = syscall.Flock(int(f.Fd()), how)
+        if err != syscall.EINTR {
+            break
+        }
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    return func() { syscall.Flock(int(f.Fd()), syscall.LOCK_UN) }, nil
+}
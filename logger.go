@@ -0,0 +1,176 @@
+package cfg
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log"
+    "log/slog"
+    "strings"
+)
+
+// Receives the messages the package logs, by level. No logger is set by default, so
+// nothing is logged, see SetLogger.
+type Logger interface {
+    Debug(msg string)
+    Info(msg string)
+    Warn(msg string)
+    Error(msg string)
+}
+
+// Sets the logger messages are sent to, closing the file opened by SetLogFile. A nil
+// logger discards them.
+func SetLogger(l Logger) { c.SetLogger(l) }
+func (c *Config) SetLogger(l Logger) {
+    c.closeLogFile()
+    c.logger = l
+}
+
+// Returns the logger messages are sent to, one discarding them if none was set.
+func GetLogger() Logger { return c.Logger() }
+func (c *Config) Logger() Logger {
+    if c.logger == nil {
+        return noopLogger{}
+    }
+    return c.logger
+}
+
+// Returns a Logger writing to l, debug messages at slog.LevelDebug and so on. Events
+// such as a loaded config or a failed reload are logged with their details as attributes,
+// and an event attribute naming them, so they can be queried in the app's log stream:
+//
+//     config_loaded    file, format, keys, op (read or merge)
+//     key_overridden   key, shadowed (the layer kind of the value it hides, if any),
+//                      layer (set on a custom layer)
+//     reload_failed    name, error
+//     config_changed   op, key, old, new, caller (see SetAuditLog)
+func SlogLogger(l *slog.Logger) Logger {
+    return slogLogger{l}
+}
+
+type slogLogger struct {
+    l *slog.Logger
+}
+
+func (s slogLogger) Debug(msg string) { s.l.Log(context.Background(), slog.LevelDebug, msg) }
+func (s slogLogger) Info(msg string)  { s.l.Log(context.Background(), slog.LevelInfo, msg) }
+func (s slogLogger) Warn(msg string)  { s.l.Log(context.Background(), slog.LevelWarn, msg) }
+func (s slogLogger) Error(msg string) { s.l.Log(context.Background(), slog.LevelError, msg) }
+
+// The leveled methods of a *logrus.Logger or *logrus.Entry.
+type logrusLogger interface {
+    Debug(args ...interface{})
+    Info(args ...interface{})
+    Warn(args ...interface{})
+    Error(args ...interface{})
+}
+
+// Returns a Logger writing to a *logrus.Logger or *logrus.Entry, or anything else with
+// the same leveled methods.
+func LogrusLogger(l logrusLogger) Logger {
+    return logrusAdapter{l}
+}
+
+type logrusAdapter struct {
+    l logrusLogger
+}
+
+func (a logrusAdapter) Debug(msg string) { a.l.Debug(msg) }
+func (a logrusAdapter) Info(msg string)  { a.l.Info(msg) }
+func (a logrusAdapter) Warn(msg string)  { a.l.Warn(msg) }
+func (a logrusAdapter) Error(msg string) { a.l.Error(msg) }
+
+type noopLogger struct{}
+
+func (noopLogger) Debug(string) {}
+func (noopLogger) Info(string)  {}
+func (noopLogger) Warn(string)  {}
+func (noopLogger) Error(string) {}
+
+// writerLogger prints messages from a minimum level on to a log.Logger.
+type writerLogger struct {
+    l   *log.Logger
+    min int
+}
+
+// Levels of writerLogger.
+const (
+    levelDebug = iota
+    levelInfo
+    levelWarn
+    levelError
+)
+
+func newWriterLogger(w io.Writer, min int) writerLogger {
+    return writerLogger{log.New(w, "", log.LstdFlags), min}
+}
+
+func (w writerLogger) print(level int, prefix, msg string) {
+    if level >= w.min {
+        w.l.Println(prefix, msg)
+    }
+}
+
+func (w writerLogger) Debug(msg string) { w.print(levelDebug, "DEBUG", msg) }
+func (w writerLogger) Info(msg string)  { w.print(levelInfo, "INFO", msg) }
+func (w writerLogger) Warn(msg string)  { w.print(levelWarn, "WARN", msg) }
+func (w writerLogger) Error(msg string) { w.print(levelError, "ERROR", msg) }
+
+// logMessage joins args like fmt.Println.
+func logMessage(args []interface{}) string {
+    return strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+}
+
+// logEvent logs the event named event with attrs, as attributes when the logger is a
+// SlogLogger and appended to the message as key=value pairs otherwise.
+func (c *Config) logEvent(level slog.Level, event string, attrs ...slog.Attr) {
+    switch l := c.logger.(type) {
+    case nil:
+        return
+    case slogLogger:
+        l.l.LogAttrs(context.Background(), level, event, append([]slog.Attr{slog.String("event", event)}, attrs...)...)
+        return
+    }
+
+    msg := event
+    for _, a := range attrs {
+        msg += " " + a.String()
+    }
+
+    switch {
+    case level >= slog.LevelError:
+        c.logger.Error(msg)
+    case level >= slog.LevelWarn:
+        c.logger.Warn(msg)
+    case level >= slog.LevelInfo:
+        c.logger.Info(msg)
+    default:
+        c.logger.Debug(msg)
+    }
+}
+
+// The log functions format args like fmt.Println, and only when a logger is set.
+
+func (c *Config) logDebug(args ...interface{}) {
+    if c.logger != nil {
+        c.logger.Debug(logMessage(args))
+    }
+}
+
+func (c *Config) logInfo(args ...interface{}) {
+    if c.logger != nil {
+        c.logger.Info(logMessage(args))
+    }
+}
+
+func (c *Config) logWarn(args ...interface{}) {
+    if c.logger != nil {
+        c.logger.Warn(logMessage(args))
+    }
+}
+
+func (c *Config) logError(args ...interface{}) {
+    if c.logger != nil {
+        c.logger.Error(logMessage(args))
+    }
+}
@@ -0,0 +1,146 @@
+package cfg
+
+import (
+    "reflect"
+)
+
+// Decides how a slice being merged in combines with the slice already present.
+type SliceStrategy int
+
+const (
+    // The new slice replaces the old one.
+    SliceReplace SliceStrategy = iota
+
+    // The new elements are appended to the old ones.
+    SliceAppend
+
+    // The new elements not already present are appended to the old ones.
+    SliceUnion
+)
+
+// Decides how a map being merged in combines with the map already present.
+type MapStrategy int
+
+const (
+    // Maps are merged key by key, recursively.
+    MapMerge MapStrategy = iota
+
+    // The new map replaces the old one.
+    MapReplace
+)
+
+// Adjusts how documents are deep-merged into the config.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+    slices SliceStrategy
+    maps   MapStrategy
+}
+
+// Selects how slices present in both documents are combined, SliceReplace by default.
+func WithSliceStrategy(s SliceStrategy) MergeOption {
+    return func(o *mergeOptions) {
+        o.slices = s
+    }
+}
+
+// Selects how maps present in both documents are combined, MapMerge by default.
+func WithMapStrategy(m MapStrategy) MergeOption {
+    return func(o *mergeOptions) {
+        o.maps = m
+    }
+}
+
+// Sets the merge options used when no options are passed to a merge function, and for
+// the drop-in directories and profile overlays merged by ReadInConfig.
+func SetMergeOptions(opts ...MergeOption) { c.SetMergeOptions(opts...) }
+func (c *Config) SetMergeOptions(opts ...MergeOption) {
+    c.mergeOpts = opts
+}
+
+// mergeOptions resolves opts, falling back to those set with SetMergeOptions.
+func (c *Config) mergeOptions(opts []MergeOption) mergeOptions {
+    if len(opts) == 0 {
+        opts = c.mergeOpts
+    }
+
+    var o mergeOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    return o
+}
+
+// mergeMapsWith deep-merges src into dst following o. By default maps present in both
+// are merged recursively, otherwise values from src replace those in dst.
+func mergeMapsWith(dst, src map[string]interface{}, o mergeOptions) {
+    for key, sv := range src {
+        if o.maps == MapMerge {
+            sm, srcIsMap := toStringMap(sv)
+            dm, dstIsMap := toStringMap(dst[key])
+
+            if srcIsMap && dstIsMap {
+                // The maps of dst may be shared with a layer in use, so they are
+                // copied along the merged paths only.
+                dm = copyMap(dm)
+                mergeMapsWith(dm, sm, o)
+                dst[key] = dm
+                continue
+            }
+        }
+
+        if o.slices != SliceReplace {
+            ss, srcIsSlice := toSlice(sv)
+            ds, dstIsSlice := toSlice(dst[key])
+
+            if srcIsSlice && dstIsSlice {
+                dst[key] = mergeSlices(ds, ss, o.slices)
+                continue
+            }
+        }
+
+        dst[key] = sv
+    }
+}
+
+func mergeSlices(dst, src []interface{}, s SliceStrategy) []interface{} {
+    merged := append([]interface{}{}, dst...)
+
+    for _, sv := range src {
+        if s == SliceUnion && containsValue(merged, sv) {
+            continue
+        }
+        merged = append(merged, sv)
+    }
+
+    return merged
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+    for _, e := range list {
+        if reflect.DeepEqual(e, v) {
+            return true
+        }
+    }
+    return false
+}
+
+// toSlice converts any slice to []interface{}, reporting false for other values.
+func toSlice(v interface{}) ([]interface{}, bool) {
+    if s, ok := v.([]interface{}); ok {
+        return s, true
+    }
+
+    rv := reflect.ValueOf(v)
+    if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+        return nil, false
+    }
+
+    s := make([]interface{}, rv.Len())
+    for i := range s {
+        s[i] = rv.Index(i).Interface()
+    }
+
+    return s, true
+}
@@ -0,0 +1,97 @@
+package cfg
+
+import (
+    "expvar"
+    "sync/atomic"
+    "time"
+)
+
+// Counters of config operations, see Metrics.
+type Metrics struct {
+    // Number of values read through Get, the getters and Unmarshal
+    Reads uint64
+
+    // Reloads of a watched config file, remote config or refreshed source, and how many
+    // of them failed and kept the previous values
+    Reloads        uint64
+    ReloadFailures uint64
+
+    // Time of the last successful and the last failed reload, zero if there was none
+    LastReload        time.Time
+    LastReloadFailure time.Time
+
+    // Events received from watched config files and remote configs
+    WatchEvents uint64
+
+    // Fetches of sources and remote configs, how many failed, and the time spent in them
+    Fetches       uint64
+    FetchFailures uint64
+    FetchTime     time.Duration
+}
+
+// metrics accumulates the Metrics of a Config.
+type metrics struct {
+    reads             atomic.Uint64
+    reloads           atomic.Uint64
+    reloadFailures    atomic.Uint64
+    lastReload        atomic.Int64
+    lastReloadFailure atomic.Int64
+    watchEvents       atomic.Uint64
+    fetches           atomic.Uint64
+    fetchFailures     atomic.Uint64
+    fetchTime         atomic.Int64
+}
+
+// Returns the counters of the config operations done so far.
+func GetMetrics() Metrics { return c.Metrics() }
+func (c *Config) Metrics() Metrics {
+    m := &c.metrics
+
+    return Metrics{
+        Reads:             m.reads.Load(),
+        Reloads:           m.reloads.Load(),
+        ReloadFailures:    m.reloadFailures.Load(),
+        LastReload:        unixNanoTime(m.lastReload.Load()),
+        LastReloadFailure: unixNanoTime(m.lastReloadFailure.Load()),
+        WatchEvents:       m.watchEvents.Load(),
+        Fetches:           m.fetches.Load(),
+        FetchFailures:     m.fetchFailures.Load(),
+        FetchTime:         time.Duration(m.fetchTime.Load()),
+    }
+}
+
+// Publishes the metrics under name through expvar, so they are served on
+// /debug/vars. Like expvar.Publish, it panics if name is already published.
+func PublishExpvar(name string) { c.PublishExpvar(name) }
+func (c *Config) PublishExpvar(name string) {
+    expvar.Publish(name, expvar.Func(func() interface{} {
+        return c.Metrics()
+    }))
+}
+
+// reloaded counts a reload that ended with err.
+func (m *metrics) reloaded(err error) {
+    m.reloads.Add(1)
+    if err != nil {
+        m.reloadFailures.Add(1)
+        m.lastReloadFailure.Store(time.Now().UnixNano())
+        return
+    }
+    m.lastReload.Store(time.Now().UnixNano())
+}
+
+// fetched counts a fetch that took d and ended with err.
+func (m *metrics) fetched(d time.Duration, err error) {
+    m.fetches.Add(1)
+    m.fetchTime.Add(int64(d))
+    if err != nil {
+        m.fetchFailures.Add(1)
+    }
+}
+
+func unixNanoTime(ns int64) time.Time {
+    if ns == 0 {
+        return time.Time{}
+    }
+    return time.Unix(0, ns)
+}
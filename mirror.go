@@ -0,0 +1,191 @@
+package cfg
+
+import (
+    "encoding/binary"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "os"
+    "sync"
+)
+
+// Largest frame accepted by the mirror protocol.
+const maxMirrorFrame = 16 << 20
+
+// A request sent to a config mirror. An empty Key requests all settings.
+type MirrorRequest struct {
+    Key string `json:"key"`
+}
+
+// A response sent by a config mirror.
+type MirrorResponse struct {
+    Value interface{} `json:"value"`
+    Found bool        `json:"found"`
+    Error string      `json:"error,omitempty"`
+}
+
+// Serves a read-only view of a Config over a unix socket.
+//
+// The protocol is a sequence of frames, each a 4 byte big-endian length followed by
+// that many bytes of JSON. Clients send a MirrorRequest frame and receive a MirrorResponse
+// frame, repeating as often as they like on the same connection. The values of secret
+// keys are served as "***", see MarkSecret.
+type Mirror struct {
+    cfg      *Config
+    listener net.Listener
+    path     string
+
+    wg    sync.WaitGroup
+    mu    sync.Mutex
+    conns map[net.Conn]struct{}
+}
+
+// Starts serving the live configuration on the unix socket at path.
+// A stale socket file at path is removed first.
+func ServeMirror(path string) (*Mirror, error) { return c.ServeMirror(path) }
+func (c *Config) ServeMirror(path string) (*Mirror, error) {
+    if b, _ := exists(path); b {
+        if err := os.Remove(path); err != nil {
+            return nil, err
+        }
+    }
+
+    l, err := net.Listen("unix", path)
+    if err != nil {
+        return nil, err
+    }
+
+    m := &Mirror{cfg: c, listener: l, path: path, conns: make(map[net.Conn]struct{})}
+    m.wg.Add(1)
+    go m.accept()
+
+    c.logInfo("Serving config mirror on", path)
+    return m, nil
+}
+
+// Stops the mirror, closing all client connections and removing the socket.
+func (m *Mirror) Close() error {
+    err := m.listener.Close()
+
+    m.mu.Lock()
+    for conn := range m.conns {
+        conn.Close()
+    }
+    m.mu.Unlock()
+
+    m.wg.Wait()
+    os.Remove(m.path)
+
+    return err
+}
+
+func (m *Mirror) accept() {
+    defer m.wg.Done()
+
+    for {
+        conn, err := m.listener.Accept()
+        if err != nil {
+            return
+        }
+
+        m.mu.Lock()
+        m.conns[conn] = struct{}{}
+        m.mu.Unlock()
+
+        m.wg.Add(1)
+        go m.serve(conn)
+    }
+}
+
+func (m *Mirror) serve(conn net.Conn) {
+    defer m.wg.Done()
+    defer func() {
+        m.mu.Lock()
+        delete(m.conns, conn)
+        m.mu.Unlock()
+        conn.Close()
+    }()
+
+    for {
+        var req MirrorRequest
+        if err := readFrame(conn, &req); err != nil {
+            if err != io.EOF {
+                m.cfg.logDebug("Config mirror connection closed:", err)
+            }
+            return
+        }
+
+        if err := writeFrame(conn, m.respond(req)); err != nil {
+            m.cfg.logDebug("Config mirror write failed:", err)
+            return
+        }
+    }
+}
+
+// respond answers req with the values of secret keys redacted, see MarkSecret.
+func (m *Mirror) respond(req MirrorRequest) MirrorResponse {
+    if req.Key == "" {
+        return MirrorResponse{Value: normalizeMaps(m.cfg.AllSettingsNested(WithRedaction())), Found: true}
+    }
+
+    val, found := m.cfg.GetRedacted(req.Key)
+    return MirrorResponse{Value: normalizeMaps(val), Found: found}
+}
+
+// Reads a single value (or all settings when key is empty) from the mirror at path.
+func ReadMirror(path, key string) (interface{}, bool, error) {
+    conn, err := net.Dial("unix", path)
+    if err != nil {
+        return nil, false, err
+    }
+    defer conn.Close()
+
+    if err := writeFrame(conn, MirrorRequest{Key: key}); err != nil {
+        return nil, false, err
+    }
+
+    var resp MirrorResponse
+    if err := readFrame(conn, &resp); err != nil {
+        return nil, false, err
+    }
+
+    if resp.Error != "" {
+        return nil, false, errors.New(resp.Error)
+    }
+
+    return resp.Value, resp.Found, nil
+}
+
+func readFrame(r io.Reader, v interface{}) error {
+    var size uint32
+    if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+        return err
+    }
+
+    if size > maxMirrorFrame {
+        return fmt.Errorf("Mirror frame of %d bytes exceeds limit", size)
+    }
+
+    buf := make([]byte, size)
+    if _, err := io.ReadFull(r, buf); err != nil {
+        return err
+    }
+
+    return json.Unmarshal(buf, v)
+}
+
+func writeFrame(w io.Writer, v interface{}) error {
+    buf, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+
+    if err := binary.Write(w, binary.BigEndian, uint32(len(buf))); err != nil {
+        return err
+    }
+
+    _, err = w.Write(buf)
+    return err
+}
@@ -0,0 +1,41 @@
+package cfg
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+func TestMirrorRedactsSecrets(t *testing.T) {
+    c := New()
+    c.Set("db.user", "admin")
+    c.Set("db.password", "hunter2")
+    c.MarkSecret("db.password")
+
+    path := filepath.Join(t.TempDir(), "mirror.sock")
+    m, err := c.ServeMirror(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer m.Close()
+
+    val, found, err := ReadMirror(path, "db.password")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !found || val != redactedValue {
+        t.Errorf("ReadMirror(db.password) = %v, %v, want %q, true", val, found, redactedValue)
+    }
+
+    val, _, err = ReadMirror(path, "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    db, _ := val.(map[string]interface{})["db"].(map[string]interface{})
+    if db["password"] != redactedValue || db["user"] != "admin" {
+        t.Errorf("ReadMirror() db = %v, want password %q and user admin", db, redactedValue)
+    }
+
+    if _, found, _ := ReadMirror(path, "db.missing"); found {
+        t.Error("ReadMirror(db.missing) found a value")
+    }
+}
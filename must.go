@@ -0,0 +1,54 @@
+package cfg
+
+import (
+    "fmt"
+    "time"
+)
+
+// Denotes a key that is not set in any layer.
+type KeyNotFoundError string
+
+// Returns the formatted key not found error.
+func (str KeyNotFoundError) Error() string {
+    return fmt.Sprintf("Config key %q not set", string(str))
+}
+
+// Returns the value associated with the key converted to T, see GetAs. Panics with the
+// error GetAs returns, a KeyNotFoundError if the key is not set, for initialization code
+// where a missing value is unrecoverable.
+func MustGet[T any](c *Config, key string) T {
+    v, err := GetAs[T](c, key)
+    if err != nil {
+        panic(err)
+    }
+
+    return v
+}
+
+// Returns the value associated with the key as a string, panicking if it is not set
+func MustGetString(key string) string { return c.MustGetString(key) }
+func (c *Config) MustGetString(key string) string { return MustGet[string](c, key) }
+
+// Returns the value associated with the key as a boolean, panicking if it is not set or invalid
+func MustGetBool(key string) bool { return c.MustGetBool(key) }
+func (c *Config) MustGetBool(key string) bool { return MustGet[bool](c, key) }
+
+// Returns the value associated with the key as an integer, panicking if it is not set or invalid
+func MustGetInt(key string) int { return c.MustGetInt(key) }
+func (c *Config) MustGetInt(key string) int { return MustGet[int](c, key) }
+
+// Returns the value associated with the key as a 64-bit integer, panicking if it is not set or invalid
+func MustGetInt64(key string) int64 { return c.MustGetInt64(key) }
+func (c *Config) MustGetInt64(key string) int64 { return MustGet[int64](c, key) }
+
+// Returns the value associated with the key as a float64, panicking if it is not set or invalid
+func MustGetFloat64(key string) float64 { return c.MustGetFloat64(key) }
+func (c *Config) MustGetFloat64(key string) float64 { return MustGet[float64](c, key) }
+
+// Returns the value associated with the key as a duration, panicking if it is not set or invalid
+func MustGetDuration(key string) time.Duration { return c.MustGetDuration(key) }
+func (c *Config) MustGetDuration(key string) time.Duration { return MustGet[time.Duration](c, key) }
+
+// Returns the value associated with the key as a slice of strings, panicking if it is not set or invalid
+func MustGetStringSlice(key string) []string { return c.MustGetStringSlice(key) }
+func (c *Config) MustGetStringSlice(key string) []string { return MustGet[[]string](c, key) }
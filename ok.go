@@ -0,0 +1,47 @@
+package cfg
+
+import (
+    "time"
+)
+
+// Returns the value associated with the key converted to T, see GetAs, and whether it is
+// set and could be converted, for code that branches on a missing value. Unlike IsSet
+// followed by a getter, the key is looked up once, so the value cannot change in between.
+func GetOk[T any](c *Config, key string) (T, bool) {
+    v, err := GetAs[T](c, key)
+    return v, err == nil
+}
+
+// Returns the value associated with the key as a string, and whether it is set
+func GetStringOk(key string) (string, bool) { return c.GetStringOk(key) }
+func (c *Config) GetStringOk(key string) (string, bool) { return GetOk[string](c, key) }
+
+// Returns the value associated with the key as a boolean, and whether it is set and valid
+func GetBoolOk(key string) (bool, bool) { return c.GetBoolOk(key) }
+func (c *Config) GetBoolOk(key string) (bool, bool) { return GetOk[bool](c, key) }
+
+// Returns the value associated with the key as an integer, and whether it is set and valid
+func GetIntOk(key string) (int, bool) { return c.GetIntOk(key) }
+func (c *Config) GetIntOk(key string) (int, bool) { return GetOk[int](c, key) }
+
+// Returns the value associated with the key as a 64-bit integer, and whether it is set and valid
+func GetInt64Ok(key string) (int64, bool) { return c.GetInt64Ok(key) }
+func (c *Config) GetInt64Ok(key string) (int64, bool) { return GetOk[int64](c, key) }
+
+// Returns the value associated with the key as a float64, and whether it is set and valid
+func GetFloat64Ok(key string) (float64, bool) { return c.GetFloat64Ok(key) }
+func (c *Config) GetFloat64Ok(key string) (float64, bool) { return GetOk[float64](c, key) }
+
+// Returns the value associated with the key as a duration, and whether it is set and valid
+func GetDurationOk(key string) (time.Duration, bool) { return c.GetDurationOk(key) }
+func (c *Config) GetDurationOk(key string) (time.Duration, bool) { return GetOk[time.Duration](c, key) }
+
+// Returns the value associated with the key as a slice of strings, and whether it is set and valid
+func GetStringSliceOk(key string) ([]string, bool) { return c.GetStringSliceOk(key) }
+func (c *Config) GetStringSliceOk(key string) ([]string, bool) { return GetOk[[]string](c, key) }
+
+// Returns the value associated with the key as a map of interfaces, and whether it is set and valid
+func GetStringMapOk(key string) (map[string]interface{}, bool) { return c.GetStringMapOk(key) }
+func (c *Config) GetStringMapOk(key string) (map[string]interface{}, bool) {
+    return GetOk[map[string]interface{}](c, key)
+}
@@ -0,0 +1,214 @@
+package cfg
+
+import (
+    "fmt"
+    "strings"
+
+    yaml3 "gopkg.in/yaml.v3"
+)
+
+// Where the effective value of a key comes from, see Origin.
+type KeyOrigin struct {
+    Kind LayerKind
+
+    // Name of the layer, the source name for LayerSource.
+    Layer string
+
+    // Key the value is stored under, which differs from the key looked up when it is
+    // reached through an alias or a deprecated name.
+    Key string
+
+    // Config file the value was read from, and the line it is on, for LayerConfig. File
+    // is empty when the value was merged from a reader or a map, Line is 0 when it is
+    // not known.
+    File string
+    Line int
+}
+
+// Returns the origin as layer and, for the config layer, file and line.
+func (o KeyOrigin) String() string {
+    s := fmt.Sprintf("%s %q", o.Kind, o.Layer)
+    switch {
+    case o.File != "" && o.Line > 0:
+        s += fmt.Sprintf(" from %s:%d", o.File, o.Line)
+    case o.File != "":
+        s += " from " + o.File
+    }
+
+    return s
+}
+
+// Where a key of the config layer was read from.
+type fileOrigin struct {
+    file string
+    line int
+}
+
+// Returns where the effective value of key comes from, and false if no layer holds it.
+// Explains why a key has the value it has when many layers are in play.
+func Origin(key string) (KeyOrigin, bool) { return c.Origin(key) }
+func (c *Config) Origin(key string) (KeyOrigin, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    key = c.realKey(c.normalizeKey(key))
+    _, l, dep := c.find(key)
+    if l.kind == "" {
+        return KeyOrigin{}, false
+    }
+
+    o := KeyOrigin{Kind: l.kind, Layer: l.name, Key: key}
+    if dep != nil {
+        o.Key = dep.Key + strings.TrimPrefix(key, dep.NewKey)
+    }
+
+    if l.kind == LayerConfig {
+        path := strings.Split(o.Key, c.keyDelm)
+        for i := len(path); i > 0; i-- {
+            if fo, ok := c.configOrigins[strings.Join(path[:i], c.keyDelm)]; ok {
+                o.File = fo.file
+                if i == len(path) {
+                    o.Line = fo.line
+                }
+                break
+            }
+        }
+    }
+
+    return o, true
+}
+
+// fileOrigins returns the origin of every key of values, maps included, as read from
+// file. doc is the document values were decoded from, the lines are looked up in it.
+func (c *Config) fileOrigins(values map[string]interface{}, doc []byte, configType, file string) map[string]fileOrigin {
+    lines := c.keyLines(doc, configType)
+
+    origins := make(map[string]fileOrigin)
+    var walk func(m map[string]interface{}, prefix string)
+    walk = func(m map[string]interface{}, prefix string) {
+        for k, v := range m {
+            key := k
+            if prefix != "" {
+                key = prefix + c.keyDelm + k
+            }
+            origins[key] = fileOrigin{file, lines[key]}
+
+            if sub, ok := toStringMap(v); ok {
+                walk(sub, key)
+            }
+        }
+    }
+    walk(values, "")
+
+    return origins
+}
+
+// copyOrigins returns a copy of the origins of the config layer.
+func (c *Config) copyOrigins() map[string]fileOrigin {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    origins := make(map[string]fileOrigin, len(c.configOrigins))
+    for k, v := range c.configOrigins {
+        origins[k] = v
+    }
+
+    return origins
+}
+
+// keyLines returns the line every key of a YAML or TOML document is on, by key. Keys
+// that cannot be placed, such as those in arrays of tables, are left out.
+func (c *Config) keyLines(doc []byte, configType string) map[string]int {
+    lines := make(map[string]int)
+
+    switch strings.ToLower(configType) {
+    case "yaml", "yml":
+        var node yaml3.Node
+        if err := yaml3.Unmarshal(doc, &node); err == nil && len(node.Content) > 0 {
+            c.yamlKeyLines(node.Content[0], "", lines)
+        }
+
+    case "toml":
+        c.tomlKeyLines(string(doc), lines)
+    }
+
+    return lines
+}
+
+// yamlKeyLines records the line of every key of a YAML mapping, beneath prefix.
+func (c *Config) yamlKeyLines(node *yaml3.Node, prefix string, lines map[string]int) {
+    if node.Kind != yaml3.MappingNode {
+        return
+    }
+
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        key := c.normalizeKey(node.Content[i].Value)
+        if prefix != "" {
+            key = prefix + c.keyDelm + key
+        }
+        lines[key] = node.Content[i].Line
+
+        c.yamlKeyLines(node.Content[i+1], key, lines)
+    }
+}
+
+// tomlKeyLines records the line of every table header and assignment of a TOML
+// document.
+func (c *Config) tomlKeyLines(doc string, lines map[string]int) {
+    docLines := strings.Split(doc, "\n")
+
+    var table []string
+    inArray := false
+    for i := 0; i < len(docLines); i++ {
+        line := docLines[i]
+        trimmed := strings.TrimSpace(line)
+
+        if tomlArrayHeader.MatchString(line) {
+            inArray = true
+            continue
+        }
+
+        if m := tomlTableHeader.FindStringSubmatch(line); m != nil {
+            path, err := parseTOMLKey(m[1])
+            if err != nil {
+                return
+            }
+            table, inArray = path, false
+            lines[c.joinKeyPath(table)] = i + 1
+            continue
+        }
+
+        if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+            continue
+        }
+
+        eq := indexOutsideQuotes(line, '=')
+        if eq < 0 {
+            return
+        }
+        keyPath, err := parseTOMLKey(strings.TrimSpace(line[:eq]))
+        if err != nil {
+            return
+        }
+        if !inArray {
+            lines[c.joinKeyPath(append(append([]string{}, table...), keyPath...))] = i + 1
+        }
+
+        // Skip the remaining lines of a multi-line value.
+        value := line[eq+1:]
+        for !tomlValueComplete(value) && i+1 < len(docLines) {
+            i++
+            value += "\n" + docLines[i]
+        }
+    }
+}
+
+// joinKeyPath joins the elements of a key path read from a document into a key.
+func (c *Config) joinKeyPath(path []string) string {
+    keys := make([]string, len(path))
+    for i, p := range path {
+        keys[i] = c.normalizeKey(p)
+    }
+
+    return strings.Join(keys, c.keyDelm)
+}
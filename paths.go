@@ -0,0 +1,189 @@
+package cfg
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "runtime"
+    "sort"
+    "strings"
+)
+
+// Adds the conventional config locations of an application called appName to the
+// search paths, in order of precedence:
+//
+//   - the working directory
+//   - the user's config directory: $XDG_CONFIG_HOME/appName, ~/.config/appName when it
+//     is not set, on Linux and other Unix systems, ~/Library/Application Support/appName
+//     on macOS and %APPDATA%\appName on Windows
+//   - the system-wide directories: every $XDG_CONFIG_DIRS entry, /etc/xdg by default,
+//     followed by /etc/appName on Linux and other Unix systems, /Library/Application
+//     Support/appName on macOS and %ProgramData%\appName on Windows
+//
+// Locations that cannot be determined, such as the user's config directory when HOME
+// is not set, are left out.
+func AddStandardConfigPaths(appName string) { c.AddStandardConfigPaths(appName) }
+func (c *Config) AddStandardConfigPaths(appName string) {
+    for _, path := range standardConfigPaths(appName) {
+        c.AddConfigPath(path)
+    }
+}
+
+// standardConfigPaths returns the locations AddStandardConfigPaths adds, for the
+// platform the program runs on.
+func standardConfigPaths(appName string) []string {
+    paths := []string{"."}
+
+    if dir, err := os.UserConfigDir(); err == nil {
+        paths = append(paths, filepath.Join(dir, appName))
+    }
+
+    switch runtime.GOOS {
+    case "windows":
+        if dir := os.Getenv("ProgramData"); dir != "" {
+            paths = append(paths, filepath.Join(dir, appName))
+        }
+    case "darwin":
+        paths = append(paths, filepath.Join("/Library/Application Support", appName))
+    case "plan9":
+        // Plan 9 has no system-wide config directory.
+    default:
+        dirs := os.Getenv("XDG_CONFIG_DIRS")
+        if dirs == "" {
+            dirs = "/etc/xdg"
+        }
+        for _, dir := range strings.Split(dirs, string(os.PathListSeparator)) {
+            if filepath.IsAbs(dir) {
+                paths = append(paths, filepath.Join(dir, appName))
+            }
+        }
+        paths = append(paths, filepath.Join("/etc", appName))
+    }
+
+    return paths
+}
+
+// Searches the working directory and then each of its parents for the config file
+// before the config paths, the way git finds .git, so a tool run anywhere inside a
+// project tree finds the project's config. The search ends at the root of the
+// filesystem, or after the first of stopAt it reaches, such as the user's home
+// directory.
+func SetSearchUp(enable bool, stopAt ...string) { c.SetSearchUp(enable, stopAt...) }
+func (c *Config) SetSearchUp(enable bool, stopAt ...string) {
+    c.searchUp = enable
+    c.searchStops = nil
+    for _, dir := range stopAt {
+        c.searchStops = append(c.searchStops, c.absPathify(dir))
+    }
+}
+
+// searchUpward returns the config file in the working directory or the nearest of its
+// parents holding one, empty if there is none.
+func (c *Config) searchUpward() string {
+    dir := c.absPathify(".")
+    for {
+        if file := c.searchInPath(dir); file != "" {
+            return file
+        }
+
+        parent := filepath.Dir(dir)
+        if parent == dir || stringInSlice(dir, c.searchStops) {
+            return ""
+        }
+        dir = parent
+    }
+}
+
+// Adjusts how AddConfigPath searches a path.
+type ConfigPathOption func(*configPathOptions)
+
+type configPathOptions struct {
+    depth int
+}
+
+// Searches the subdirectories of the path too, down to depth levels beneath it, for
+// layouts such as one directory per service. Shallower files take precedence, and
+// several files at the same depth fail the search with an AmbiguousConfigError listing
+// them. Hidden directories are skipped.
+func Recursive(depth int) ConfigPathOption {
+    return func(o *configPathOptions) {
+        o.depth = depth
+    }
+}
+
+// Denotes a recursive search finding several config files where one was expected.
+type AmbiguousConfigError struct {
+    Path string
+
+    // The config files found, in lexical order
+    Files []string
+}
+
+// Returns the formatted ambiguous config error.
+func (ace AmbiguousConfigError) Error() string {
+    return fmt.Sprintf("Several config files found in %q: %s", ace.Path, strings.Join(ace.Files, ", "))
+}
+
+// searchRecursive returns the config file in dir, or else the only one in its
+// subdirectories at the shallowest depth holding any, down to depth levels.
+func (c *Config) searchRecursive(dir string, depth int) (string, error) {
+    if file := c.searchInPath(dir); file != "" || depth <= 0 {
+        return file, nil
+    }
+
+    level := []string{dir}
+    for i := 0; i < depth && len(level) > 0; i++ {
+        var next, found []string
+        for _, d := range level {
+            entries, err := c.readDir(d)
+            if err != nil {
+                continue
+            }
+            for _, e := range entries {
+                if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+                    continue
+                }
+                sub := filepath.Join(d, e.Name())
+                next = append(next, sub)
+                if file := c.searchInPath(sub); file != "" {
+                    found = append(found, file)
+                }
+            }
+        }
+
+        switch {
+        case len(found) == 1:
+            return found[0], nil
+        case len(found) > 1:
+            sort.Strings(found)
+            return "", AmbiguousConfigError{dir, found}
+        }
+        level = next
+    }
+
+    return "", nil
+}
+
+// Replaces the config file found in the config paths by the file its symlinks lead to,
+// so a file mounted from a Kubernetes ConfigMap or projected volume, a symlink into a
+// timestamped ..data directory, is read and reported by ConfigFileUsed as the file it
+// really is. A file set with SetConfigFile is used as given.
+func SetResolveSymlinks(enable bool) { c.SetResolveSymlinks(enable) }
+func (c *Config) SetResolveSymlinks(enable bool) {
+    c.resolveSymlinks = enable
+}
+
+// resolveFound returns the config file found, with its symlinks resolved if enabled.
+func (c *Config) resolveFound(file string) string {
+    if !c.resolveSymlinks || c.fsys != nil {
+        return file
+    }
+
+    resolved, err := filepath.EvalSymlinks(file)
+    if err != nil {
+        c.logWarn("Cannot resolve symlinks of", file, err)
+        return file
+    }
+
+    return resolved
+}
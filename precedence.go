@@ -0,0 +1,167 @@
+package cfg
+
+import (
+    "fmt"
+    "io"
+    "sort"
+    "strings"
+)
+
+// Identifies the kind of layer a value can be resolved from.
+type LayerKind string
+
+const (
+    LayerOverride LayerKind = "override"
+    LayerConfig   LayerKind = "config"
+    LayerComputed LayerKind = "computed"
+    LayerSource   LayerKind = "source"
+    LayerDefault  LayerKind = "default"
+    LayerCustom   LayerKind = "custom"
+)
+
+// Describes one layer consulted when resolving a key, in the order Get consults them.
+type PrecedenceRule struct {
+    // Position in the lookup order, starting at 1 for the highest precedence.
+    Order int `json:"order"`
+
+    Kind LayerKind `json:"kind"`
+
+    // Name of the layer, the source name for LayerSource.
+    Name string `json:"name"`
+
+    // Priority the layer is consulted at, higher priorities come first.
+    Priority int `json:"priority"`
+
+    Description string `json:"description"`
+}
+
+// Rules that apply to every lookup regardless of layer.
+var LookupRules = []string{
+    "Keys are lowercased before lookup, unless keys are case sensitive, see SetKeysCaseSensitive, or normalized to snake_case, see SetKeyNormalization.",
+    "Aliases are resolved before any layer is consulted, an alias of a parent path applies to every key beneath it.",
+    "Layers are consulted in order and the first layer holding the key wins.",
+    "Layers are ordered by priority, between layers of equal priority the one added last comes first.",
+    "Within a layer a flat key is matched first, then the key is treated as a path into nested maps, trying the longest stored prefix first.",
+    "A path that runs into a non-map value before it is exhausted does not match.",
+    "When the key holds a map, the maps and keys beneath it in every layer are merged by precedence, so values set for single keys beneath it show through. A scalar hides the maps in the layers below it.",
+}
+
+type layer struct {
+    kind     LayerKind
+    name     string
+    priority int
+    seq      int
+    values   map[string]interface{}
+}
+
+// layers returns every layer in lookup order. Caller must hold mu.
+func (c *Config) layers() []layer {
+    l := []layer{
+        {LayerOverride, "overrides", PriorityOverride, 0, c.overrides},
+        {LayerConfig, "config", PriorityConfig, 0, c.config},
+        {LayerDefault, "defaults", PriorityDefault, 0, c.defaults},
+    }
+    if len(c.computed) > 0 {
+        l = append(l, layer{LayerComputed, "computed", PriorityComputed, 0, c.computed})
+    }
+
+    c.sourceMu.RLock()
+    for _, src := range c.sources {
+        l = append(l, layer{LayerSource, src.name, PrioritySource, src.seq, src.values})
+    }
+    for _, custom := range c.customLayers {
+        l = append(l, layer{LayerCustom, custom.name, custom.priority, custom.seq, custom.snapshot()})
+    }
+    c.sourceMu.RUnlock()
+
+    sort.SliceStable(l, func(i, j int) bool {
+        if l[i].priority != l[j].priority {
+            return l[i].priority > l[j].priority
+        }
+        return l[i].seq > l[j].seq
+    })
+
+    return l
+}
+
+// Returns the layers consulted by Get in order of precedence, as data, so callers can
+// verify their assumptions about where values come from. See LookupRules for the rules
+// applied within every layer.
+func PrecedenceRules() []PrecedenceRule { return c.PrecedenceRules() }
+func (c *Config) PrecedenceRules() []PrecedenceRule {
+    var rules []PrecedenceRule
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    for i, l := range c.layers() {
+        rules = append(rules, PrecedenceRule{
+            Order:       i + 1,
+            Kind:        l.kind,
+            Name:        l.name,
+            Priority:    l.priority,
+            Description: describeLayer(l),
+        })
+    }
+
+    return rules
+}
+
+func describeLayer(l layer) string {
+    switch l.kind {
+    case LayerOverride:
+        return "Values assigned with Set."
+    case LayerConfig:
+        return "Values read from the config file."
+    case LayerComputed:
+        return "Values computed when read, see SetComputed."
+    case LayerSource:
+        return fmt.Sprintf("Values fetched from source %q, later sources take precedence.", l.name)
+    case LayerDefault:
+        return "Values assigned with SetDefault."
+    case LayerCustom:
+        return fmt.Sprintf("Values assigned to custom layer %q.", l.name)
+    }
+
+    return ""
+}
+
+// Writes the precedence rules in effect as a Markdown document: how keys are spelled,
+// the layers PrecedenceRules returns and LookupRules. Teams can check the document into
+// their repositories and compare it on upgrades, see cfgctl precedence.
+func WritePrecedenceDoc(w io.Writer) error { return c.WritePrecedenceDoc(w) }
+func (c *Config) WritePrecedenceDoc(w io.Writer) error {
+    var b strings.Builder
+
+    b.WriteString("# Config precedence\n\n")
+
+    switch {
+    case c.keyNormalization:
+        b.WriteString("Keys are normalized to snake_case before lookup.\n\n")
+    case c.caseSensitive:
+        b.WriteString("Keys are case sensitive.\n\n")
+    default:
+        b.WriteString("Keys are lowercased before lookup.\n\n")
+    }
+
+    b.WriteString("## Layers\n\n")
+    b.WriteString("| Order | Kind | Name | Priority | Description |\n")
+    b.WriteString("|---|---|---|---|---|\n")
+    for _, r := range c.PrecedenceRules() {
+        fmt.Fprintf(&b, "| %d | %s | %s | %d | %s |\n",
+            r.Order, r.Kind, markdownCell(r.Name), r.Priority, markdownCell(r.Description))
+    }
+
+    b.WriteString("\n## Lookup rules\n\n")
+    for i, rule := range LookupRules {
+        fmt.Fprintf(&b, "%d. %s\n", i+1, rule)
+    }
+
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// markdownCell escapes s for a cell of a Markdown table.
+func markdownCell(s string) string {
+    return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,344 @@
+package cfg
+
+import (
+    "bytes"
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// Layers in the order find() must consult them.
+var precedenceOrder = []LayerKind{LayerOverride, LayerConfig, LayerSource, LayerDefault}
+
+type storeFunc func(c *Config, kind LayerKind, key string, val interface{})
+
+// Stores the value under the full dotted key in the layer.
+func storeFlat(c *Config, kind LayerKind, key string, val interface{}) {
+    storeValue(c, kind, key, val)
+}
+
+// Stores the value as a nested map under the first path segment.
+func storeNested(c *Config, kind LayerKind, key string, val interface{}) {
+    path := strings.Split(key, ".")
+    for i := len(path) - 1; i > 0; i-- {
+        val = map[string]interface{}{path[i]: val}
+    }
+    storeValue(c, kind, path[0], val)
+}
+
+func storeValue(c *Config, kind LayerKind, key string, val interface{}) {
+    switch kind {
+    case LayerOverride:
+        c.Set(key, val)
+    case LayerConfig:
+        c.config[strings.ToLower(key)] = val
+    case LayerSource:
+        c.AddSource("test", func() (map[string]interface{}, error) {
+            return map[string]interface{}{key: val}, nil
+        }, 0)
+    case LayerDefault:
+        c.SetDefault(key, val)
+    }
+}
+
+func TestPrecedenceMatrix(t *testing.T) {
+    keys := []string{"key", "parent.key", "grand.parent.key"}
+    stores := map[string]storeFunc{"flat": storeFlat, "nested": storeNested}
+
+    // Every non-empty combination of layers.
+    for mask := 1; mask < 1<<len(precedenceOrder); mask++ {
+        var present []LayerKind
+        for i, kind := range precedenceOrder {
+            if mask&(1<<i) != 0 {
+                present = append(present, kind)
+            }
+        }
+        want := string(present[0])
+
+        for _, key := range keys {
+            for storeName, store := range stores {
+                for _, alias := range []bool{false, true} {
+                    for _, lookup := range []func(string) string{strings.ToLower, strings.ToUpper} {
+                        name := fmt.Sprintf("%v/%s/%s/alias=%v/%s", present, key, storeName, alias, lookup("case"))
+                        t.Run(name, func(t *testing.T) {
+                            c := New()
+                            for _, kind := range present {
+                                store(c, kind, key, string(kind))
+                            }
+
+                            get := key
+                            if alias {
+                                get = "alias." + key
+                                c.RegisterAlias(get, key)
+                            }
+
+                            if got := c.Get(lookup(get)); got != want {
+                                t.Errorf("Get(%q) = %v, want %v", lookup(get), got, want)
+                            }
+                        })
+                    }
+                }
+            }
+        }
+    }
+}
+
+func TestPrecedenceParentAlias(t *testing.T) {
+    c := New()
+    c.SetDefault("database.host", "default")
+    c.config["database"] = map[interface{}]interface{}{"host": "config"}
+    c.RegisterAlias("db", "database")
+
+    if got := c.GetString("DB.Host"); got != "config" {
+        t.Errorf("GetString(DB.Host) = %q, want config", got)
+    }
+}
+
+func TestPrecedenceLayerFallthrough(t *testing.T) {
+    // A map in a higher layer that lacks the key must not hide a lower layer.
+    c := New()
+    c.config["server"] = map[string]interface{}{"port": 80}
+    c.SetDefault("server.host", "localhost")
+
+    if got := c.GetString("server.host"); got != "localhost" {
+        t.Errorf("GetString(server.host) = %q, want localhost", got)
+    }
+    if got := c.GetInt("server.port"); got != 80 {
+        t.Errorf("GetInt(server.port) = %d, want 80", got)
+    }
+}
+
+func TestPrecedenceDeepMapViews(t *testing.T) {
+    // Every view of a map sees the values set beneath it in every layer.
+    c := New()
+    c.config["server"] = map[string]interface{}{"port": 80, "timeouts": map[string]interface{}{"write": 10}}
+    c.SetDefault("server.timeouts.read", 5)
+    c.SetDefault("server.port", 8080)
+    c.Set("server.host", "example.com")
+    c.Set("srv.tls", true)
+    c.RegisterAlias("srv", "server")
+
+    want := map[string]interface{}{
+        "host":     "example.com",
+        "port":     80,
+        "tls":      true,
+        "timeouts": map[string]interface{}{"read": 5, "write": 10},
+    }
+
+    if got := c.Get("server"); !reflect.DeepEqual(got, want) {
+        t.Errorf("Get(server) = %v, want %v", got, want)
+    }
+    if got := c.GetStringMap("server"); !reflect.DeepEqual(got, want) {
+        t.Errorf("GetStringMap(server) = %v, want %v", got, want)
+    }
+    if got := c.Get("srv"); !reflect.DeepEqual(got, want) {
+        t.Errorf("Get(srv) = %v, want %v", got, want)
+    }
+
+    sub := c.Sub("server")
+    if sub == nil {
+        t.Fatal("Sub(server) = nil")
+    }
+    if got := sub.GetStringMap("timeouts"); !reflect.DeepEqual(got, want["timeouts"]) {
+        t.Errorf("Sub(server).GetStringMap(timeouts) = %v, want %v", got, want["timeouts"])
+    }
+    for _, key := range []string{"host", "port", "tls"} {
+        if got := sub.Get(key); got != want[key] {
+            t.Errorf("Sub(server).Get(%s) = %v, want %v", key, got, want[key])
+        }
+    }
+
+    var out map[string]interface{}
+    if err := c.UnmarshalKey("server", &out); err != nil {
+        t.Fatal(err)
+    }
+    if !reflect.DeepEqual(out, want) {
+        t.Errorf("UnmarshalKey(server) = %v, want %v", out, want)
+    }
+
+    // Keys only set beneath a map read as the map too.
+    if got, want := c.Get("server.timeouts"), want["timeouts"]; !reflect.DeepEqual(got, want) {
+        t.Errorf("Get(server.timeouts) = %v, want %v", got, want)
+    }
+    d := New()
+    d.SetDefault("a.b.c", 1)
+    if got, want := d.Get("a"), map[string]interface{}{"b": map[string]interface{}{"c": 1}}; !reflect.DeepEqual(got, want) {
+        t.Errorf("Get(a) = %v, want %v", got, want)
+    }
+}
+
+func TestPrecedenceScalarPath(t *testing.T) {
+    c := New()
+    c.config["server"] = "scalar"
+
+    if got := c.Get("server.port"); got != nil {
+        t.Errorf("Get(server.port) = %v, want nil", got)
+    }
+}
+
+func TestPrecedenceSourceOrder(t *testing.T) {
+    c := New()
+    for _, name := range []string{"first", "second"} {
+        name := name
+        c.AddSource(name, func() (map[string]interface{}, error) {
+            return map[string]interface{}{"key": name}, nil
+        }, 0)
+    }
+
+    if got := c.GetString("key"); got != "second" {
+        t.Errorf("GetString(key) = %q, want second", got)
+    }
+}
+
+func TestPrecedenceCustomLayers(t *testing.T) {
+    c := New()
+    c.SetDefault("key", "default")
+    c.Set("key", "override")
+
+    flags, _ := c.AddLayer("flags", PriorityOverride+10)
+    flags.Set("KEY", "flags")
+    env, _ := c.AddLayer("env", PriorityConfig)
+    env.Set("key", "env")
+
+    if got := c.GetString("key"); got != "flags" {
+        t.Errorf("GetString(key) = %q, want flags", got)
+    }
+
+    flags.Unset("key")
+    delete(c.overrides, "key")
+    if got := c.GetString("key"); got != "env" {
+        t.Errorf("GetString(key) = %q, want env", got)
+    }
+
+    if _, err := c.AddLayer("env", PriorityDefault); err == nil {
+        t.Error("AddLayer(env) twice succeeded")
+    }
+
+    c.RemoveLayer("env")
+    if got := c.GetString("key"); got != "default" {
+        t.Errorf("GetString(key) = %q, want default", got)
+    }
+}
+
+func TestPrecedenceCustomLayerNestedUnset(t *testing.T) {
+    c := New()
+    c.SetDefault("db.host", "default")
+
+    flags, _ := c.AddLayer("flags", PriorityOverride+10)
+    flags.Set("db", map[string]interface{}{"host": "flags", "port": 5432})
+    flags.Set("db.user", "admin")
+
+    if got := c.GetString("db.host"); got != "flags" {
+        t.Errorf("GetString(db.host) = %q, want flags", got)
+    }
+
+    flags.Unset("db")
+    if got := c.GetString("db.host"); got != "default" {
+        t.Errorf("GetString(db.host) = %q, want default", got)
+    }
+    if c.IsSet("db.port") || c.IsSet("db.user") {
+        t.Errorf("db.port or db.user still set after Unset(db): %v", flags.Values())
+    }
+}
+
+func TestPrecedenceCustomLayerReplace(t *testing.T) {
+    values := map[string]interface{}{
+        "DB": map[interface{}]interface{}{
+            "Host": "localhost",
+            "Pool": map[string]interface{}{"MaxConns": 5},
+        },
+        "Log.Level": "debug",
+    }
+
+    c := New()
+    c.SetAuditLog(10)
+
+    set, _ := c.AddLayer("set", PriorityDefault-10)
+    for k, v := range values {
+        set.Set(k, v)
+    }
+    replaced, _ := c.AddLayer("replaced", PriorityOverride)
+    replaced.Set("stale", true)
+    replaced.Replace(values)
+
+    if got, want := replaced.Values(), set.Values(); !reflect.DeepEqual(got, want) {
+        t.Errorf("Values() after Replace = %v, want %v as after Set", got, want)
+    }
+    if got := c.GetInt("db.pool.maxconns"); got != 5 {
+        t.Errorf("GetInt(db.pool.maxconns) = %d, want 5", got)
+    }
+    if got := c.GetString("log.level"); got != "debug" {
+        t.Errorf("GetString(log.level) = %q, want debug", got)
+    }
+    if c.IsSet("stale") {
+        t.Error("stale still set after Replace")
+    }
+
+    var ops []string
+    for _, e := range c.AuditLog()[3:] {
+        ops = append(ops, string(e.Op)+" "+e.Key)
+    }
+    if want := []string{"set db", "set log.level", "unset stale"}; !reflect.DeepEqual(ops, want) {
+        t.Errorf("audited %v, want %v", ops, want)
+    }
+}
+
+func TestPrecedenceRules(t *testing.T) {
+    c := New()
+    c.AddSource("first", func() (map[string]interface{}, error) { return nil, nil }, 0)
+    c.AddSource("second", func() (map[string]interface{}, error) { return nil, nil }, 0)
+
+    want := []struct {
+        kind LayerKind
+        name string
+    }{
+        {LayerOverride, "overrides"},
+        {LayerConfig, "config"},
+        {LayerSource, "second"},
+        {LayerSource, "first"},
+        {LayerDefault, "defaults"},
+    }
+
+    rules := c.PrecedenceRules()
+    if len(rules) != len(want) {
+        t.Fatalf("got %d rules, want %d", len(rules), len(want))
+    }
+
+    for i, r := range rules {
+        if r.Order != i+1 || r.Kind != want[i].kind || r.Name != want[i].name {
+            t.Errorf("rule %d = %+v, want %v %q", i, r, want[i].kind, want[i].name)
+        }
+    }
+}
+
+func TestPrecedenceDoc(t *testing.T) {
+    c := New()
+    c.AddSource("vault", func() (map[string]interface{}, error) { return nil, nil }, 0)
+    c.AddLayer("flags", PriorityOverride+10)
+
+    var buf bytes.Buffer
+    if err := c.WritePrecedenceDoc(&buf); err != nil {
+        t.Fatal(err)
+    }
+
+    golden := filepath.Join("testdata", "precedence.md")
+    if *updateGolden {
+        if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    want, err := os.ReadFile(golden)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if got := buf.String(); got != string(want) {
+        t.Errorf("WritePrecedenceDoc() differs from %s, run go test -run TestPrecedenceDoc -update if the change is intended\ngot:\n%s", golden, got)
+    }
+}
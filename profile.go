@@ -0,0 +1,153 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// Sets the profiles whose overlays ReadInConfig merges over the config file.
+//
+// For a config file config.yaml and profile dev, config.dev.yaml is merged in when it
+// exists, trying every supported extension in turn so the overlay may be config.dev.toml,
+// or config.dev.yaml.age when a cipher is registered for age. Overlays are verified,
+// decrypted and rendered like the config file. Profiles are applied in order, later ones
+// taking precedence.
+func SetProfile(profiles ...string) { c.SetProfile(profiles...) }
+func (c *Config) SetProfile(profiles ...string) {
+    c.profiles = nil
+    for _, p := range profiles {
+        if p = strings.TrimSpace(p); p != "" {
+            c.profiles = append(c.profiles, p)
+        }
+    }
+}
+
+// Selects profiles from the named environment variable, a comma separated list such as
+// "prod,eu". When the variable is set and not empty it replaces profiles given to SetProfile.
+func SetProfileEnv(name string) { c.SetProfileEnv(name) }
+func (c *Config) SetProfileEnv(name string) {
+    c.profileEnv = name
+}
+
+// Returns the profiles in effect.
+func Profiles() []string { return c.Profiles() }
+func (c *Config) Profiles() []string {
+    if c.profileEnv != "" {
+        if env := os.Getenv(c.profileEnv); strings.TrimSpace(env) != "" {
+            var profiles []string
+            for _, p := range strings.Split(env, ",") {
+                if p = strings.TrimSpace(p); p != "" {
+                    profiles = append(profiles, p)
+                }
+            }
+            return profiles
+        }
+    }
+
+    return c.profiles
+}
+
+// Sets the role of the machine, such as "frontend" or "db", whose overlay ReadInConfig
+// merges over the config file and its profiles. For a config file config.yaml and role
+// db, config.db.yaml is merged in when it exists, trying every supported extension.
+func SetRole(role string) { c.SetRole(role) }
+func (c *Config) SetRole(role string) {
+    c.role = strings.TrimSpace(role)
+}
+
+// Selects the role from the named environment variable. When the variable is set and
+// not empty it replaces the role given to SetRole.
+func SetRoleEnv(name string) { c.SetRoleEnv(name) }
+func (c *Config) SetRoleEnv(name string) {
+    c.roleEnv = name
+}
+
+// Returns the role in effect, empty if there is none.
+func Role() string { return c.Role() }
+func (c *Config) Role() string {
+    if c.roleEnv != "" {
+        if env := strings.TrimSpace(os.Getenv(c.roleEnv)); env != "" {
+            return env
+        }
+    }
+
+    return c.role
+}
+
+// Enables merging the overlay of the machine's host name over the config file, its
+// profiles and role, so single hosts of a fleet can be configured apart. For a config
+// file config.yaml on host web-1.example.com, config.web-1.yaml and then
+// config.web-1.example.com.yaml are merged in when they exist.
+func SetHostOverlay(enable bool) { c.SetHostOverlay(enable) }
+func (c *Config) SetHostOverlay(enable bool) {
+    c.hostOverlay = enable
+}
+
+// overlays returns the names of the overlays merged over the config file in order,
+// the profiles, then the role and the host names, along with what they are.
+func (c *Config) overlays() (names, kinds []string) {
+    for _, profile := range c.Profiles() {
+        names, kinds = append(names, profile), append(kinds, "profile")
+    }
+
+    if role := c.Role(); role != "" {
+        names, kinds = append(names, role), append(kinds, "role")
+    }
+
+    if c.hostOverlay {
+        if host, err := os.Hostname(); err == nil && host != "" {
+            if short, _, found := strings.Cut(host, "."); found && short != "" {
+                names, kinds = append(names, short), append(kinds, "host")
+            }
+            names, kinds = append(names, host), append(kinds, "host")
+        }
+    }
+
+    return names, kinds
+}
+
+// mergeProfiles deep-merges the overlay of each profile in effect, the role and the
+// host into config, recording where their keys come from in origins.
+func (c *Config) mergeProfiles(ctx context.Context, config map[string]interface{}, origins map[string]fileOrigin) error {
+    cf, _ := c.getConfigFile()
+    if cf == "" || cf == StdinConfigFile || isURL(cf) {
+        return nil
+    }
+
+    plain, _ := c.cipherFor(cf)
+    base := strings.TrimSuffix(plain, filepath.Ext(plain))
+    names, kinds := c.overlays()
+    for i, name := range names {
+        overlay := c.searchName(filepath.Dir(base), filepath.Base(base)+"."+name)
+        if overlay == "" {
+            continue
+        }
+
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+
+        file, _, err := c.loadConfigFile(ctx, overlay)
+        if err != nil {
+            return fmt.Errorf("Cannot read %s %q: %w", kinds[i], name, err)
+        }
+
+        ext := c.typeForFile(overlay)
+        c.logInfo("Merging", kinds[i], name, "from", overlay)
+        src, err := c.decodeConfig(bytes.NewReader(file), ext)
+        if err != nil {
+            return parseErrorIn(err, overlay)
+        }
+
+        mergeMapsWith(config, src, c.mergeOptions(nil))
+        for k, v := range c.fileOrigins(src, file, ext, overlay) {
+            origins[k] = v
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,93 @@
+// Package prometheus exposes the metrics of a cfg.Config as a prometheus.Collector.
+package prometheus
+
+import (
+    "github.com/prometheus/client_golang/prometheus"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Collects the metrics of a Config on every scrape.
+type Collector struct {
+    cfg *cfg.Config
+
+    reads             *prometheus.Desc
+    reloads           *prometheus.Desc
+    reloadFailures    *prometheus.Desc
+    lastReload        *prometheus.Desc
+    lastReloadFailure *prometheus.Desc
+    watchEvents       *prometheus.Desc
+    fetches           *prometheus.Desc
+    fetchFailures     *prometheus.Desc
+    fetchSeconds      *prometheus.Desc
+}
+
+// Returns a collector of the metrics of c, named namespace_config_*. constLabels are
+// added to every metric, so several Configs can be told apart.
+//
+// Alert on a reload failing for 30 minutes with
+//
+//     time() - namespace_config_last_reload_timestamp_seconds > 1800
+//       and namespace_config_last_reload_failure_timestamp_seconds > namespace_config_last_reload_timestamp_seconds
+func NewCollector(c *cfg.Config, namespace string, constLabels prometheus.Labels) *Collector {
+    desc := func(name, help string) *prometheus.Desc {
+        return prometheus.NewDesc(prometheus.BuildFQName(namespace, "config", name), help, nil, constLabels)
+    }
+
+    return &Collector{
+        cfg:               c,
+        reads:             desc("reads_total", "Values read through Get, the getters and Unmarshal."),
+        reloads:           desc("reloads_total", "Reloads of watched config files, remote configs and refreshed sources."),
+        reloadFailures:    desc("reload_failures_total", "Reloads that failed and kept the previous values."),
+        lastReload:        desc("last_reload_timestamp_seconds", "Time of the last successful reload."),
+        lastReloadFailure: desc("last_reload_failure_timestamp_seconds", "Time of the last failed reload."),
+        watchEvents:       desc("watch_events_total", "Events received from watched config files and remote configs."),
+        fetches:           desc("fetches_total", "Fetches of sources and remote configs."),
+        fetchFailures:     desc("fetch_failures_total", "Fetches of sources and remote configs that failed."),
+        fetchSeconds:      desc("fetch_seconds_total", "Time spent fetching sources and remote configs."),
+    }
+}
+
+// Describe implements prometheus.Collector.
+func (col *Collector) Describe(ch chan<- *prometheus.Desc) {
+    ch <- col.reads
+    ch <- col.reloads
+    ch <- col.reloadFailures
+    ch <- col.lastReload
+    ch <- col.lastReloadFailure
+    ch <- col.watchEvents
+    ch <- col.fetches
+    ch <- col.fetchFailures
+    ch <- col.fetchSeconds
+}
+
+// Collect implements prometheus.Collector.
+func (col *Collector) Collect(ch chan<- prometheus.Metric) {
+    m := col.cfg.Metrics()
+
+    counter := func(desc *prometheus.Desc, v float64) {
+        ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v)
+    }
+    gauge := func(desc *prometheus.Desc, v float64) {
+        ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+    }
+
+    counter(col.reads, float64(m.Reads))
+    counter(col.reloads, float64(m.Reloads))
+    counter(col.reloadFailures, float64(m.ReloadFailures))
+    counter(col.watchEvents, float64(m.WatchEvents))
+    counter(col.fetches, float64(m.Fetches))
+    counter(col.fetchFailures, float64(m.FetchFailures))
+    counter(col.fetchSeconds, m.FetchTime.Seconds())
+
+    // Zero until the first reload of each kind.
+    var lastReload, lastReloadFailure float64
+    if !m.LastReload.IsZero() {
+        lastReload = float64(m.LastReload.UnixNano()) / 1e9
+    }
+    if !m.LastReloadFailure.IsZero() {
+        lastReloadFailure = float64(m.LastReloadFailure.UnixNano()) / 1e9
+    }
+    gauge(col.lastReload, lastReload)
+    gauge(col.lastReloadFailure, lastReloadFailure)
+}
@@ -0,0 +1,134 @@
+// Package redis provides a cfg source backed by a Redis hash or serialized document.
+package redis
+
+import (
+    "bytes"
+    "context"
+    "crypto/tls"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    goredis "github.com/redis/go-redis/v9"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Controls how config is read from Redis.
+type Options struct {
+    Addr     string
+    Username string
+    Password string
+    DB       int
+
+    // Enables TLS when non-nil.
+    TLS *tls.Config
+
+    // Key holding the config. A hash maps its fields to config keys, any other
+    // value is parsed as a document in Format.
+    Key string
+
+    // Format of a document stored as a string, json when empty.
+    Format string
+
+    // Channel whose messages trigger a reload, none when empty.
+    Channel string
+
+    // Interval at which the key is re-read, zero reads it only on start and on Channel messages.
+    Refresh time.Duration
+}
+
+// A Redis backed source registered on a Config.
+type Source struct {
+    client *goredis.Client
+    pubsub *goredis.PubSub
+}
+
+// Adds the key described by opts as a source on c.
+func AddSource(c *cfg.Config, opts Options) (*Source, error) {
+    client := goredis.NewClient(&goredis.Options{
+        Addr:      opts.Addr,
+        Username:  opts.Username,
+        Password:  opts.Password,
+        DB:        opts.DB,
+        TLSConfig: opts.TLS,
+    })
+
+    name := "redis:" + opts.Key
+    if err := c.AddSource(name, fetcher(client, opts), opts.Refresh); err != nil {
+        client.Close()
+        return nil, err
+    }
+
+    s := &Source{client: client}
+    if opts.Channel != "" {
+        s.pubsub = client.Subscribe(context.Background(), opts.Channel)
+        go func() {
+            for range s.pubsub.Channel() {
+                if err := c.RefreshSource(name); err != nil {
+                    c.Logger().Error(fmt.Sprintf("Failed to reload %s: %v", name, err))
+                }
+            }
+        }()
+    }
+
+    return s, nil
+}
+
+// Stops listening for reloads and closes the connection.
+func (s *Source) Close() error {
+    if s.pubsub != nil {
+        s.pubsub.Close()
+    }
+    return s.client.Close()
+}
+
+func fetcher(client *goredis.Client, opts Options) cfg.SourceFunc {
+    return func() (map[string]interface{}, error) {
+        ctx := context.Background()
+
+        kind, err := client.Type(ctx, opts.Key).Result()
+        if err != nil {
+            return nil, err
+        }
+
+        switch kind {
+        case "hash":
+            fields, err := client.HGetAll(ctx, opts.Key).Result()
+            if err != nil {
+                return nil, err
+            }
+
+            m := make(map[string]interface{}, len(fields))
+            for k, v := range fields {
+                m[k] = v
+            }
+            return m, nil
+
+        case "string":
+            doc, err := client.Get(ctx, opts.Key).Bytes()
+            if err != nil {
+                return nil, err
+            }
+            return decode(doc, opts.Format)
+
+        case "none":
+            return nil, fmt.Errorf("Key %q does not exist", opts.Key)
+        }
+
+        return nil, fmt.Errorf("Key %q holds an unsupported %s", opts.Key, kind)
+    }
+}
+
+func decode(doc []byte, format string) (map[string]interface{}, error) {
+    if format == "" || strings.ToLower(format) == "json" {
+        m := make(map[string]interface{})
+        if err := json.Unmarshal(doc, &m); err != nil {
+            return nil, err
+        }
+        return m, nil
+    }
+
+    return cfg.Decode(bytes.NewReader(doc), format)
+}
@@ -0,0 +1,120 @@
+package cfg
+
+import (
+    "fmt"
+    "io/ioutil"
+    "net/url"
+    "os"
+    "strings"
+)
+
+// Resolves a reference such as vault://secret/data/app#password, written as the value of
+// a key, to the value it stands for, see RegisterResolver.
+type Resolver func(ref *url.URL) (interface{}, error)
+
+// Denotes a reference that could not be resolved.
+type ResolveError struct {
+    Key string
+    Ref string
+    Err error
+}
+
+// Returns the formatted resolve error.
+func (re ResolveError) Error() string {
+    return fmt.Sprintf("Cannot resolve %q of key %q: %v", re.Ref, re.Key, re.Err)
+}
+
+// Returns the reason the reference could not be resolved.
+func (re ResolveError) Unwrap() error {
+    return re.Err
+}
+
+// Registers r to resolve string values that are URLs with the given scheme, such as
+// "vault" for vault://secret/data/app#password. References are resolved each time their
+// key is read, so secrets stay in their store and the config only says where they are.
+// A reference that fails to resolve reads as unset, and its ResolveError is returned by
+// GetE and the other E getters. A nil r removes the resolver of scheme.
+//
+// No resolvers are registered by default, EnvResolver and FileResolver resolve env:// and
+// file:// references.
+func RegisterResolver(scheme string, r Resolver) { c.RegisterResolver(scheme, r) }
+func (c *Config) RegisterResolver(scheme string, r Resolver) {
+    c.resolverMu.Lock()
+    defer c.resolverMu.Unlock()
+
+    scheme = strings.ToLower(scheme)
+    if r == nil {
+        delete(c.resolvers, scheme)
+        return
+    }
+
+    c.resolvers[scheme] = r
+}
+
+// Resolves env://NAME to the value of the environment variable NAME, failing if it is
+// not set.
+func EnvResolver(ref *url.URL) (interface{}, error) {
+    name := ref.Host + ref.Path
+    if val, ok := os.LookupEnv(name); ok {
+        return val, nil
+    }
+
+    return nil, fmt.Errorf("Environment variable %s is not set", name)
+}
+
+// Resolves file:///path to the contents of the file at path, without a trailing newline,
+// as written to mounted secrets such as /run/secrets/token.
+func FileResolver(ref *url.URL) (interface{}, error) {
+    b, err := ioutil.ReadFile(ref.Path)
+    if err != nil {
+        return nil, err
+    }
+
+    return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// mayExpand reports whether s may hold a reference, an interpolation or a file to
+// include, which plain values are told apart from without expanding them.
+func (c *Config) mayExpand(s string) bool {
+    return strings.Contains(s, "://") ||
+        c.interpolate && strings.Contains(s, "${") ||
+        c.includeFiles && strings.HasPrefix(s, includePrefix)
+}
+
+// resolveRef returns val, the value of key, or what it refers to if it is a reference
+// with a registered scheme or includes a file.
+func (c *Config) resolveRef(key string, val string) (interface{}, error) {
+    i := strings.Index(val, "://")
+
+    var (
+        r  Resolver
+        ok bool
+    )
+    if i > 0 {
+        c.resolverMu.RLock()
+        r, ok = c.resolvers[strings.ToLower(val[:i])]
+        c.resolverMu.RUnlock()
+    }
+    if !ok {
+        if path, included := c.includedPath(val); included {
+            contents, err := c.includeFile(path)
+            if err != nil {
+                return nil, ResolveError{key, val, err}
+            }
+            return contents, nil
+        }
+        return val, nil
+    }
+
+    u, err := url.Parse(val)
+    if err != nil {
+        return nil, ResolveError{key, val, err}
+    }
+
+    resolved, err := r(u)
+    if err != nil {
+        return nil, ResolveError{key, val, err}
+    }
+
+    return resolved, nil
+}
@@ -0,0 +1,75 @@
+package cfg
+
+import (
+    "sort"
+    "sync"
+)
+
+var (
+    instancesMu sync.Mutex
+    instances   = map[string]*Config{}
+)
+
+// Returns the Config registered as name, such as "database" or "telemetry", creating it
+// with New on first use, so parts of a program can each keep a config of their own
+// without passing it around. Instances are independent of one another and of the
+// global config the package-level functions use.
+func Instance(name string) *Config {
+    instancesMu.Lock()
+    defer instancesMu.Unlock()
+
+    inst, ok := instances[name]
+    if !ok {
+        inst = New()
+        instances[name] = inst
+    }
+
+    return inst
+}
+
+// Returns the names of the instances created with Instance, sorted.
+func Instances() []string {
+    instancesMu.Lock()
+    defer instancesMu.Unlock()
+
+    names := make([]string, 0, len(instances))
+    for name := range instances {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    return names
+}
+
+// Replaces the instance registered as name by a new Config, the way Reset replaces the
+// global config, after stopping its watches. Holders of the previous Config keep using
+// it unchanged. Returns false if there is no such instance.
+func ResetInstance(name string) bool {
+    instancesMu.Lock()
+    old, ok := instances[name]
+    if ok {
+        instances[name] = New()
+    }
+    instancesMu.Unlock()
+
+    if ok {
+        old.StopWatching()
+    }
+
+    return ok
+}
+
+// Removes the instance registered as name after stopping its watches, so the next
+// Instance call creates it anew. Returns false if there is no such instance.
+func RemoveInstance(name string) bool {
+    instancesMu.Lock()
+    old, ok := instances[name]
+    delete(instances, name)
+    instancesMu.Unlock()
+
+    if ok {
+        old.StopWatching()
+    }
+
+    return ok
+}
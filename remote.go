@@ -0,0 +1,275 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/url"
+    "path/filepath"
+    "reflect"
+    "sync"
+    "time"
+)
+
+// Signals a change in a remote provider's document. A non-nil Err reports a
+// failure of the watch itself.
+type RemoteEvent struct {
+    Err error
+}
+
+// A backend serving a config document.
+type RemoteProvider interface {
+    // Returns the current document.
+    Get(ctx context.Context) (io.Reader, error)
+
+    // Returns a channel receiving an event whenever the document changes. The channel
+    // is closed once ctx is done. Providers unable to watch return a nil channel and
+    // are polled instead.
+    Watch(ctx context.Context) (<-chan RemoteEvent, error)
+}
+
+// Creates a RemoteProvider for a URL whose scheme it was registered under.
+type RemoteProviderFactory func(u *url.URL) (RemoteProvider, error)
+
+// Denotes a remote URL with no registered provider for its scheme.
+type UnsupportedRemoteProviderError string
+
+// Returns the error for an unsupported remote provider.
+func (str UnsupportedRemoteProviderError) Error() string {
+    return fmt.Sprintf("Unsupported Remote Provider %q", string(str))
+}
+
+var (
+    remoteProvidersMu sync.RWMutex
+    remoteProviders   = map[string]RemoteProviderFactory{}
+)
+
+// Registers factory for URLs with the given scheme, replacing any previous registration.
+// Backends usually call this from their package init.
+func RegisterRemoteProvider(scheme string, factory RemoteProviderFactory) {
+    remoteProvidersMu.Lock()
+    defer remoteProvidersMu.Unlock()
+
+    remoteProviders[scheme] = factory
+}
+
+type remoteConfig struct {
+    cfg        *Config
+    url        string
+    configType string
+    provider   RemoteProvider
+}
+
+// Adds a remote config document to be loaded by ReadRemoteConfig.
+//
+// The provider is chosen by the scheme of rawurl. The document type is taken from the
+// extension of the URL path, falling back to the type set with SetConfigType.
+func AddRemoteProvider(rawurl string) error { return c.AddRemoteProvider(rawurl) }
+func (c *Config) AddRemoteProvider(rawurl string) error {
+    u, err := url.Parse(rawurl)
+    if err != nil {
+        return err
+    }
+
+    remoteProvidersMu.RLock()
+    factory, exists := remoteProviders[u.Scheme]
+    remoteProvidersMu.RUnlock()
+    if !exists {
+        return UnsupportedRemoteProviderError(u.Scheme)
+    }
+
+    provider, err := factory(u)
+    if err != nil {
+        return err
+    }
+
+    configType := c.configType
+    if ext := filepath.Ext(u.Path); len(ext) > 1 {
+        configType = ext[1:]
+    }
+    if !stringInSlice(configType, SupportedExts) {
+        return UnsupportedConfigError(configType)
+    }
+
+    c.remotes = append(c.remotes, &remoteConfig{cfg: c, url: rawurl, configType: configType, provider: provider})
+    return nil
+}
+
+// Reads every remote added with AddRemoteProvider into its own source layer, named
+// "remote:" followed by the URL. Remotes added later take precedence.
+func ReadRemoteConfig() error { return c.ReadRemoteConfig() }
+func (c *Config) ReadRemoteConfig() error {
+    return c.ReadRemoteConfigContext(context.Background())
+}
+
+// Like ReadRemoteConfig, but ctx is handed to the providers so a remote that does not
+// answer before ctx is done fails the read. Remotes read before that keep their layer.
+func ReadRemoteConfigContext(ctx context.Context) error { return c.ReadRemoteConfigContext(ctx) }
+func (c *Config) ReadRemoteConfigContext(ctx context.Context) error {
+    for _, rc := range c.remotes {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if err := c.readRemote(ctx, rc); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// Watches every remote added with AddRemoteProvider until ctx is done or StopWatching
+// is called, re-reading a remote whenever it changes and reporting each reload to the
+// OnConfigChange handlers with the remote URL as the event name.
+//
+// Remotes are watched natively when their provider supports it. Providers returning a
+// nil channel from Watch are polled every interval instead, with handlers only notified
+// when the document actually changed.
+func WatchRemoteConfig(ctx context.Context, interval time.Duration) error {
+    return c.WatchRemoteConfig(ctx, interval)
+}
+func (c *Config) WatchRemoteConfig(ctx context.Context, interval time.Duration) error {
+    for _, rc := range c.remotes {
+        events, err := rc.provider.Watch(ctx)
+        if err != nil {
+            return err
+        }
+
+        if events != nil {
+            ctx, done := c.startWatch(ctx)
+            go func(rc *remoteConfig) {
+                defer done()
+                c.watchRemote(ctx, rc, events)
+            }(rc)
+            continue
+        }
+
+        if interval <= 0 {
+            return fmt.Errorf("Remote %q cannot be watched and no poll interval was given", rc.url)
+        }
+
+        ctx, done := c.startWatch(ctx)
+        go func(rc *remoteConfig) {
+            defer done()
+            c.pollRemote(ctx, rc, interval)
+        }(rc)
+    }
+
+    return nil
+}
+
+func (c *Config) watchRemote(ctx context.Context, rc *remoteConfig, events <-chan RemoteEvent) {
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case event, ok := <-events:
+            if !ok {
+                return
+            }
+            c.metrics.watchEvents.Add(1)
+
+            if event.Err != nil {
+                c.logEvent(slog.LevelError, "reload_failed", slog.String("name", rc.url), slog.Any("error", event.Err))
+                c.notifyChange(ChangeEvent{Name: rc.url, Err: event.Err})
+                continue
+            }
+
+            c.reloadRemote(ctx, rc, false)
+        }
+    }
+}
+
+func (c *Config) pollRemote(ctx context.Context, rc *remoteConfig, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+            c.reloadRemote(ctx, rc, true)
+        }
+    }
+}
+
+// reloadRemote re-reads rc and notifies handlers. With onlyChanged set, successful
+// reloads that leave the document unchanged are not reported.
+func (c *Config) reloadRemote(ctx context.Context, rc *remoteConfig, onlyChanged bool) {
+    values := c.sourceValues(rc.sourceName())
+    before := c.beforeReload()
+
+    err := c.readRemote(ctx, rc)
+    c.metrics.reloaded(err)
+    if err != nil {
+        c.logEvent(slog.LevelError, "reload_failed", slog.String("name", rc.url), slog.Any("error", err))
+    } else if onlyChanged && reflect.DeepEqual(values, c.sourceValues(rc.sourceName())) {
+        return
+    }
+
+    c.notifyChange(c.reloaded(rc.url, 0, before, err))
+}
+
+func (c *Config) readRemote(ctx context.Context, rc *remoteConfig) error {
+    name := rc.sourceName()
+
+    c.sourceMu.RLock()
+    _, exists := c.getSource(name)
+    c.sourceMu.RUnlock()
+
+    var err error
+    if exists {
+        err = c.refreshSourceContext(ctx, name)
+    } else {
+        err = c.addSourceContext(ctx, name, rc.fetch, 0)
+    }
+    if err != nil {
+        return err
+    }
+
+    c.logInfo("Read remote config", rc.url)
+    return nil
+}
+
+func (rc *remoteConfig) sourceName() string {
+    return "remote:" + rc.url
+}
+
+func (rc *remoteConfig) fetch(ctx context.Context) (map[string]interface{}, error) {
+    r, err := rc.provider.Get(ctx)
+    if err != nil {
+        return nil, err
+    }
+
+    _, pinned := rc.cfg.checksums[rc.url]
+    if !pinned && len(rc.cfg.verifiers) == 0 {
+        values, err := rc.cfg.parseLimited(r, rc.configType)
+        return values, parseErrorIn(err, rc.url)
+    }
+
+    doc, err := rc.cfg.readConfigFrom(rc.url, r, 0)
+    if err != nil {
+        return nil, err
+    }
+
+    if _, err := rc.cfg.verifyChecksum(rc.url, doc, nil); err != nil {
+        return nil, err
+    }
+
+    err = rc.cfg.verifySignature(rc.url, doc, func() ([]byte, error) {
+        signed, ok := rc.provider.(SignedRemoteProvider)
+        if !ok {
+            return nil, errors.New("Remote provider does not serve signatures")
+        }
+        return signed.GetSignature(ctx)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    return rc.cfg.parseLimited(bytes.NewReader(doc), rc.configType)
+}
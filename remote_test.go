@@ -0,0 +1,241 @@
+package cfg
+
+import (
+    "context"
+    "errors"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strings"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+// A remote provider serving documents kept in memory by host, watched natively unless
+// poll is set.
+type memProvider struct {
+    mu     sync.Mutex
+    docs   map[string]string
+    poll   bool
+    events chan RemoteEvent
+}
+
+func newMemProvider(scheme string, poll bool, docs map[string]string) *memProvider {
+    p := &memProvider{docs: docs, poll: poll, events: make(chan RemoteEvent, 10)}
+    RegisterRemoteProvider(scheme, func(u *url.URL) (RemoteProvider, error) {
+        return memRemote{p, u.Host}, nil
+    })
+
+    return p
+}
+
+// Replaces the document of host and signals the change.
+func (p *memProvider) set(host, doc string) {
+    p.mu.Lock()
+    p.docs[host] = doc
+    p.mu.Unlock()
+
+    if !p.poll {
+        p.events <- RemoteEvent{}
+    }
+}
+
+type memRemote struct {
+    p    *memProvider
+    host string
+}
+
+func (r memRemote) Get(ctx context.Context) (io.Reader, error) {
+    r.p.mu.Lock()
+    defer r.p.mu.Unlock()
+
+    doc, ok := r.p.docs[r.host]
+    if !ok {
+        return nil, errors.New("No document for " + r.host)
+    }
+    return strings.NewReader(doc), nil
+}
+
+func (r memRemote) Watch(ctx context.Context) (<-chan RemoteEvent, error) {
+    if r.p.poll {
+        return nil, nil
+    }
+    return r.p.events, nil
+}
+
+func TestReadRemoteConfig(t *testing.T) {
+    newMemProvider("memread", false, map[string]string{
+        "first":   "name: first\nlevel: info\n",
+        "second":  `{"name": "second"}`,
+        "invalid": "name: [first\n",
+    })
+
+    tests := []struct {
+        name    string
+        urls    []string
+        want    map[string]string
+        wantErr bool
+    }{
+        {"single", []string{"memread://first/config.yaml"}, map[string]string{"name": "first", "level": "info"}, false},
+        {"later wins", []string{"memread://first/config.yaml", "memread://second/config.json"}, map[string]string{"name": "second", "level": "info"}, false},
+        {"missing", []string{"memread://none/config.yaml"}, nil, true},
+        {"invalid", []string{"memread://invalid/config.yaml"}, nil, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            for _, u := range tt.urls {
+                if err := c.AddRemoteProvider(u); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            err := c.ReadRemoteConfig()
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ReadRemoteConfig() = %v, want error %v", err, tt.wantErr)
+            }
+            for key, want := range tt.want {
+                if got := c.GetString(key); got != want {
+                    t.Errorf("GetString(%s) = %q, want %q", key, got, want)
+                }
+            }
+        })
+    }
+}
+
+func TestAddRemoteProviderErrors(t *testing.T) {
+    newMemProvider("memadd", false, nil)
+
+    var unsupported UnsupportedRemoteProviderError
+    if err := New().AddRemoteProvider("nosuch://host/config.yaml"); !errors.As(err, &unsupported) {
+        t.Errorf("AddRemoteProvider(nosuch) = %v, want UnsupportedRemoteProviderError", err)
+    }
+
+    var badType UnsupportedConfigError
+    if err := New().AddRemoteProvider("memadd://host/config.exe"); !errors.As(err, &badType) {
+        t.Errorf("AddRemoteProvider(config.exe) = %v, want UnsupportedConfigError", err)
+    }
+}
+
+func TestWatchRemoteConfig(t *testing.T) {
+    tests := []struct {
+        name string
+        poll bool
+    }{
+        {"native", false},
+        {"polled", true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            scheme := "memwatch" + tt.name
+            p := newMemProvider(scheme, tt.poll, map[string]string{"host": "name: api\n"})
+
+            c := New()
+            if err := c.AddRemoteProvider(scheme + "://host/config.yaml"); err != nil {
+                t.Fatal(err)
+            }
+            if err := c.ReadRemoteConfig(); err != nil {
+                t.Fatal(err)
+            }
+
+            events := make(chan ChangeEvent, 10)
+            c.OnConfigChange(func(e ChangeEvent) { events <- e })
+            if err := c.WatchRemoteConfig(context.Background(), 10*time.Millisecond); err != nil {
+                t.Fatal(err)
+            }
+            defer c.StopWatching()
+
+            p.set("host", "name: web\n")
+
+            e := nextEvent(t, events)
+            if e.Err != nil || e.Name != scheme+"://host/config.yaml" {
+                t.Errorf("reload = %+v, want the remote reloaded", e)
+            }
+            if got := c.GetString("name"); got != "web" {
+                t.Errorf("GetString(name) = %q, want web", got)
+            }
+        })
+    }
+}
+
+func TestWatchRemoteConfigNoInterval(t *testing.T) {
+    newMemProvider("memnopoll", true, map[string]string{"host": "name: api\n"})
+
+    c := New()
+    c.AddRemoteProvider("memnopoll://host/config.yaml")
+    if err := c.WatchRemoteConfig(context.Background(), 0); err == nil {
+        t.Error("WatchRemoteConfig() of a polled remote without an interval succeeded")
+    }
+}
+
+func TestConfigFromURL(t *testing.T) {
+    var conditional atomic.Int32
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        switch r.URL.Path {
+        case "/config.yaml":
+            if r.Header.Get("Authorization") != "Bearer token" {
+                http.Error(w, "Unauthorized", http.StatusUnauthorized)
+                return
+            }
+            if r.Header.Get("If-None-Match") == `"v1"` {
+                conditional.Add(1)
+                w.WriteHeader(http.StatusNotModified)
+                return
+            }
+            w.Header().Set("ETag", `"v1"`)
+            w.Write([]byte("name: api\n"))
+        default:
+            http.NotFound(w, r)
+        }
+    }))
+    defer srv.Close()
+
+    tests := []struct {
+        name    string
+        path    string
+        header  bool
+        wantErr bool
+    }{
+        {"fetched", "/config.yaml", true, false},
+        {"unauthorized", "/config.yaml", false, true},
+        {"not found", "/missing.yaml", true, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.SetConfigFile(srv.URL + tt.path)
+            if tt.header {
+                c.SetHTTPHeader("Authorization", "Bearer token")
+            }
+
+            err := c.ReadInConfig()
+            var fe ConfigFetchError
+            if tt.wantErr {
+                if !errors.As(err, &fe) {
+                    t.Errorf("ReadInConfig() = %v, want ConfigFetchError", err)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            // The second read is conditional and reuses the body of the first.
+            if err := c.ReadInConfig(); err != nil {
+                t.Fatal(err)
+            }
+            if got := c.GetString("name"); got != "api" {
+                t.Errorf("GetString(name) = %q, want api", got)
+            }
+            if conditional.Load() != 1 {
+                t.Errorf("%d conditional requests, want 1", conditional.Load())
+            }
+        })
+    }
+}
@@ -0,0 +1,157 @@
+package cfg
+
+import (
+    "context"
+    "crypto/tls"
+    "fmt"
+    "net/http"
+    "net/url"
+    "strings"
+    "time"
+)
+
+// Denotes an unexpected HTTP response while fetching a config file.
+type ConfigFetchError struct {
+    url    string
+    status string
+}
+
+// Returns the formatted fetch error.
+func (fe ConfigFetchError) Error() string {
+    return fmt.Sprintf("Fetching config %q failed: %s", fe.url, fe.status)
+}
+
+// The last successful response for a URL, used for conditional requests.
+type httpCache struct {
+    etag         string
+    lastModified string
+    body         []byte
+}
+
+// Sets a header sent with every request when the config file is an http(s) URL.
+func SetHTTPHeader(key, value string) { c.SetHTTPHeader(key, value) }
+func (c *Config) SetHTTPHeader(key, value string) {
+    c.httpHeaders.Set(key, value)
+}
+
+// Sets the timeout for fetching a config file from an http(s) URL. Defaults to 30 seconds.
+func SetHTTPTimeout(d time.Duration) { c.SetHTTPTimeout(d) }
+func (c *Config) SetHTTPTimeout(d time.Duration) {
+    c.httpMu.Lock()
+    defer c.httpMu.Unlock()
+
+    c.httpTimeout = d
+    c.httpClient = nil
+}
+
+// Sets the TLS configuration used when the config file is an https URL.
+func SetHTTPTLSConfig(t *tls.Config) { c.SetHTTPTLSConfig(t) }
+func (c *Config) SetHTTPTLSConfig(t *tls.Config) {
+    c.httpMu.Lock()
+    defer c.httpMu.Unlock()
+
+    c.httpTLS = t
+    c.httpClient = nil
+}
+
+// client returns the HTTP client for fetching config files, built from the HTTP
+// settings on first use. The caller must hold httpMu.
+func (c *Config) client() *http.Client {
+    if c.httpClient == nil {
+        c.httpClient = &http.Client{Timeout: c.httpTimeout}
+        if c.httpTLS != nil {
+            c.httpClient.Transport = &http.Transport{TLSClientConfig: c.httpTLS}
+        }
+    }
+
+    return c.httpClient
+}
+
+// Fetches the document at u. Responses carrying an ETag or Last-Modified header are
+// remembered by URL so later fetches of u are conditional and reuse the body on 304 Not
+// Modified.
+func (c *Config) fetchURL(ctx context.Context, u string) ([]byte, error) {
+    c.logInfo("Fetching config from", u)
+
+    req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    for key, vals := range c.httpHeaders {
+        req.Header[key] = vals
+    }
+
+    c.httpMu.Lock()
+    entry, cached := c.httpCache[u]
+    client := c.client()
+    c.httpMu.Unlock()
+
+    if cached {
+        if entry.etag != "" {
+            req.Header.Set("If-None-Match", entry.etag)
+        }
+        if entry.lastModified != "" {
+            req.Header.Set("If-Modified-Since", entry.lastModified)
+        }
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    switch {
+    case resp.StatusCode == http.StatusNotModified && cached:
+        c.logDebug("Config at", u, "not modified")
+        return entry.body, nil
+    case resp.StatusCode != http.StatusOK:
+        return nil, ConfigFetchError{u, resp.Status}
+    }
+
+    if c.maxConfigSize > 0 && resp.ContentLength > c.maxConfigSize {
+        return nil, ConfigTooLargeError{u, c.maxConfigSize}
+    }
+    var size int64
+    if resp.ContentLength > 0 {
+        size = resp.ContentLength
+    }
+    body, err := c.readConfigFrom(u, resp.Body, size)
+    if err != nil {
+        return nil, err
+    }
+
+    entry = httpCache{
+        etag:         resp.Header.Get("ETag"),
+        lastModified: resp.Header.Get("Last-Modified"),
+        body:         body,
+    }
+
+    c.httpMu.Lock()
+    switch {
+    case entry.etag == "" && entry.lastModified == "":
+        delete(c.httpCache, u)
+    case c.httpCache == nil:
+        c.httpCache = map[string]httpCache{u: entry}
+    default:
+        c.httpCache[u] = entry
+    }
+    c.httpMu.Unlock()
+
+    return body, nil
+}
+
+func isURL(s string) bool {
+    return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// urlPath returns the path component of u, so the config type can be taken from
+// its extension regardless of any query string.
+func urlPath(u string) string {
+    parsed, err := url.Parse(u)
+    if err != nil {
+        return u
+    }
+    return parsed.Path
+}
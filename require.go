@@ -0,0 +1,48 @@
+package cfg
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Denotes required keys that are not set in any layer.
+type MissingKeysError []string
+
+// Returns the error listing every missing key.
+func (keys MissingKeysError) Error() string {
+    quoted := make([]string, len(keys))
+    for i, key := range keys {
+        quoted[i] = fmt.Sprintf("%q", key)
+    }
+
+    return fmt.Sprintf("Required keys not set: %s", strings.Join(quoted, ", "))
+}
+
+// Declares keys that must be set, checked by Validate.
+func Require(keys ...string) { c.Require(keys...) }
+func (c *Config) Require(keys ...string) {
+    for _, key := range keys {
+        key = c.normalizeKey(key)
+        if !stringInSlice(key, c.required) {
+            c.required = append(c.required, key)
+        }
+    }
+}
+
+// Returns a MissingKeysError listing every required key that is unset after all layers
+// are consulted, in the order they were declared, or nil if all are set.
+func Validate() error { return c.Validate() }
+func (c *Config) Validate() error {
+    var missing MissingKeysError
+    for _, key := range c.required {
+        if !c.IsSet(key) {
+            missing = append(missing, key)
+        }
+    }
+
+    if len(missing) > 0 {
+        return missing
+    }
+
+    return nil
+}
@@ -0,0 +1,650 @@
+package cfg
+
+import (
+    "bytes"
+    "fmt"
+    "reflect"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+    yaml3 "gopkg.in/yaml.v3"
+)
+
+// mergeIntoDocument rewrites the existing document so it holds settings, editing it in
+// place so comments, key order and blank lines survive. Values that did not change
+// keep their original formatting. The result is always a valid document of the given
+// type, when editing fails the settings are marshalled from scratch instead.
+func mergeIntoDocument(existing []byte, settings map[string]interface{}, configType string) ([]byte, error) {
+    var out []byte
+    var err error
+
+    switch strings.ToLower(configType) {
+    case "yaml", "yml":
+        out, err = mergeYAML(existing, settings)
+    case "toml":
+        out, err = mergeTOML(existing, settings)
+    default:
+        return marshalConfig(settings, configType)
+    }
+
+    if err == nil {
+        if _, err = Decode(bytes.NewReader(out), configType); err == nil {
+            return out, nil
+        }
+    }
+
+    return marshalConfig(settings, configType)
+}
+
+func mergeYAML(existing []byte, settings map[string]interface{}) ([]byte, error) {
+    var doc yaml3.Node
+    if err := yaml3.Unmarshal(existing, &doc); err != nil {
+        return nil, err
+    }
+
+    if doc.Kind != yaml3.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml3.MappingNode {
+        return nil, fmt.Errorf("Document is not a mapping")
+    }
+
+    if err := mergeYAMLNode(doc.Content[0], settings); err != nil {
+        return nil, err
+    }
+
+    buf := new(bytes.Buffer)
+    enc := yaml3.NewEncoder(buf)
+    enc.SetIndent(2)
+    if err := enc.Encode(&doc); err != nil {
+        return nil, err
+    }
+    if err := enc.Close(); err != nil {
+        return nil, err
+    }
+
+    return buf.Bytes(), nil
+}
+
+func mergeYAMLNode(node *yaml3.Node, val interface{}) error {
+    if m, ok := val.(map[string]interface{}); ok && node.Kind == yaml3.MappingNode {
+        seen := make(map[string]bool)
+        var content []*yaml3.Node
+
+        for i := 0; i+1 < len(node.Content); i += 2 {
+            k, v := node.Content[i], node.Content[i+1]
+
+            // Merge keys only contribute values, they are kept as written.
+            if k.Value == "<<" {
+                content = append(content, k, v)
+                continue
+            }
+
+            key, found := matchKey(m, k.Value)
+            if !found {
+                continue
+            }
+            seen[key] = true
+
+            if err := mergeYAMLNode(v, m[key]); err != nil {
+                return err
+            }
+            content = append(content, k, v)
+        }
+
+        for _, key := range sortedKeys(m) {
+            if seen[key] {
+                continue
+            }
+
+            v := new(yaml3.Node)
+            if err := v.Encode(m[key]); err != nil {
+                return err
+            }
+            content = append(content, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: key}, v)
+        }
+
+        node.Content = content
+        return nil
+    }
+
+    var current interface{}
+    if err := node.Decode(&current); err == nil && sameValue(current, val) {
+        return nil
+    }
+
+    var repl yaml3.Node
+    if err := repl.Encode(val); err != nil {
+        return err
+    }
+    repl.HeadComment, repl.LineComment, repl.FootComment = node.HeadComment, node.LineComment, node.FootComment
+    *node = repl
+
+    return nil
+}
+
+var (
+    tomlArrayHeader = regexp.MustCompile(`^\s*\[\[(.+)\]\]\s*(#.*)?$`)
+    tomlTableHeader = regexp.MustCompile(`^\s*\[([^\[].*)\]\s*(#.*)?$`)
+    tomlBareKey     = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// mergeTOML edits the existing document line by line. Assignments whose value changed
+// are rewritten in place keeping any trailing comment, assignments no longer present in
+// settings are dropped and new keys are added as dotted keys to the deepest table that
+// already exists. Arrays of tables are kept as written, when one of them changed the
+// document cannot be edited and an error is returned.
+func mergeTOML(existing []byte, settings map[string]interface{}) ([]byte, error) {
+    var doc map[string]interface{}
+    if _, err := toml.Decode(string(existing), &doc); err != nil {
+        return nil, err
+    }
+
+    lines := strings.Split(strings.TrimSuffix(string(existing), "\n"), "\n")
+
+    var out []string
+    var table []string
+    inArray := false
+
+    // Where new keys for each existing table are inserted, by joined table path. The
+    // root table ends at its last assignment, or at the first header when it has none.
+    insertAt := map[string]int{}
+    rootSet := false
+    covered := map[string]bool{}
+
+    for i := 0; i < len(lines); i++ {
+        line := lines[i]
+        trimmed := strings.TrimSpace(line)
+
+        if m := tomlArrayHeader.FindStringSubmatch(line); m != nil {
+            path, err := parseTOMLKey(m[1])
+            if err != nil {
+                return nil, err
+            }
+            if tomlArrayChanged(doc, settings, path) {
+                return nil, fmt.Errorf("Array of tables %q changed", strings.Join(path, "."))
+            }
+            if !rootSet {
+                insertAt[""], rootSet = len(out), true
+            }
+            table, inArray = path, true
+            covered[strings.Join(path, ".")] = true
+            out = append(out, line)
+            continue
+        }
+
+        if m := tomlTableHeader.FindStringSubmatch(line); m != nil {
+            path, err := parseTOMLKey(m[1])
+            if err != nil {
+                return nil, err
+            }
+            if !rootSet {
+                insertAt[""], rootSet = len(out), true
+            }
+            table, inArray = path, false
+            out = append(out, line)
+            insertAt[strings.Join(path, ".")] = len(out)
+            continue
+        }
+
+        if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+            out = append(out, line)
+            continue
+        }
+
+        eq := indexOutsideQuotes(line, '=')
+        if eq < 0 {
+            return nil, fmt.Errorf("Cannot parse line %d", i+1)
+        }
+
+        keyText := strings.TrimSpace(line[:eq])
+        keyPath, err := parseTOMLKey(keyText)
+        if err != nil {
+            return nil, err
+        }
+
+        // Gather every line of a multi-line value.
+        valueLines := []string{line[eq+1:]}
+        for !tomlValueComplete(strings.Join(valueLines, "\n")) && i+1 < len(lines) {
+            i++
+            valueLines = append(valueLines, lines[i])
+        }
+        raw := strings.Join(valueLines, "\n")
+
+        full := append(append([]string{}, table...), keyPath...)
+        if inArray {
+            out = append(out, strings.Split(line[:eq+1]+raw, "\n")...)
+            continue
+        }
+
+        desired, found := lookupPath(settings, full)
+        if !found {
+            continue
+        }
+        covered[strings.Join(full, ".")] = true
+
+        var current map[string]interface{}
+        if _, err := toml.Decode("v = "+stripTOMLComment(raw), &current); err == nil && sameValue(current["v"], desired) {
+            out = append(out, strings.Split(line[:eq+1]+raw, "\n")...)
+        } else {
+            indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+            rewritten := indent + keyText + " = " + tomlValue(desired)
+            if comment := tomlComment(valueLines[len(valueLines)-1]); comment != "" {
+                rewritten += " " + comment
+            }
+            out = append(out, rewritten)
+        }
+
+        insertAt[strings.Join(table, ".")] = len(out)
+        if len(table) == 0 {
+            rootSet = true
+        }
+    }
+
+    if !rootSet {
+        insertAt[""] = len(out)
+    }
+
+    // Collect the new assignments for each table, then insert from the bottom up so
+    // earlier insertion points stay valid.
+    additions := map[int][]string{}
+    for _, leaf := range flattenLeaves(settings, nil) {
+        if isCovered(covered, leaf.path) {
+            continue
+        }
+
+        depth := len(leaf.path) - 1
+        for ; depth > 0; depth-- {
+            if _, ok := insertAt[strings.Join(leaf.path[:depth], ".")]; ok {
+                break
+            }
+        }
+
+        at := insertAt[strings.Join(leaf.path[:depth], ".")]
+        additions[at] = append(additions[at], tomlKey(leaf.path[depth:])+" = "+tomlValue(leaf.value))
+    }
+
+    positions := make([]int, 0, len(additions))
+    for at := range additions {
+        positions = append(positions, at)
+    }
+    sort.Sort(sort.Reverse(sort.IntSlice(positions)))
+
+    for _, at := range positions {
+        tail := append(additions[at], out[at:]...)
+        out = append(out[:at], tail...)
+    }
+
+    return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// tomlArrayChanged reports whether the array of tables at path, or the outermost array
+// it is nested in, holds something else in settings than in doc.
+func tomlArrayChanged(doc, settings map[string]interface{}, path []string) bool {
+    for i := 1; i <= len(path); i++ {
+        current, found := lookupPath(doc, path[:i])
+        if !found {
+            break
+        }
+        if _, isMap := current.(map[string]interface{}); isMap {
+            continue
+        }
+
+        desired, found := lookupPath(settings, path[:i])
+        return !found || !sameValue(current, desired)
+    }
+
+    return true
+}
+
+type leaf struct {
+    path  []string
+    value interface{}
+}
+
+// flattenLeaves returns every non-map value in m with its path, sorted by path.
+func flattenLeaves(m map[string]interface{}, prefix []string) []leaf {
+    var leaves []leaf
+
+    for _, key := range sortedKeys(m) {
+        path := append(append([]string{}, prefix...), key)
+        if sub, ok := m[key].(map[string]interface{}); ok {
+            leaves = append(leaves, flattenLeaves(sub, path)...)
+            continue
+        }
+        leaves = append(leaves, leaf{path, m[key]})
+    }
+
+    return leaves
+}
+
+func isCovered(covered map[string]bool, path []string) bool {
+    for i := 1; i <= len(path); i++ {
+        if covered[strings.Join(path[:i], ".")] {
+            return true
+        }
+    }
+    return false
+}
+
+// lookupPath finds path in m, matching keys case insensitively.
+func lookupPath(m map[string]interface{}, path []string) (interface{}, bool) {
+    var cur interface{} = m
+
+    for _, p := range path {
+        cm, ok := cur.(map[string]interface{})
+        if !ok {
+            return nil, false
+        }
+
+        key, found := matchKey(cm, p)
+        if !found {
+            return nil, false
+        }
+        cur = cm[key]
+    }
+
+    return cur, true
+}
+
+// matchKey returns the key of m matching k, preferring an exact match over a case
+// insensitive one.
+func matchKey(m map[string]interface{}, k string) (string, bool) {
+    if _, ok := m[k]; ok {
+        return k, true
+    }
+
+    for key := range m {
+        if strings.EqualFold(key, k) {
+            return key, true
+        }
+    }
+
+    return "", false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+    keys := make([]string, 0, len(m))
+    for key := range m {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
+
+// sameValue reports whether a and b are equal once maps and numeric types are normalized.
+func sameValue(a, b interface{}) bool {
+    return reflect.DeepEqual(normalizeNumbers(normalizeMaps(a)), normalizeNumbers(normalizeMaps(b)))
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+    switch val := v.(type) {
+    case map[string]interface{}:
+        m := make(map[string]interface{}, len(val))
+        for k, e := range val {
+            m[k] = normalizeNumbers(e)
+        }
+        return m
+    case []interface{}:
+        s := make([]interface{}, len(val))
+        for i, e := range val {
+            s[i] = normalizeNumbers(e)
+        }
+        return s
+    case []map[string]interface{}:
+        s := make([]interface{}, len(val))
+        for i, e := range val {
+            s[i] = normalizeNumbers(normalizeMaps(e))
+        }
+        return s
+    case int:
+        return int64(val)
+    case int8:
+        return int64(val)
+    case int16:
+        return int64(val)
+    case int32:
+        return int64(val)
+    case uint:
+        return int64(val)
+    case uint8:
+        return int64(val)
+    case uint16:
+        return int64(val)
+    case uint32:
+        return int64(val)
+    case uint64:
+        return int64(val)
+    case float32:
+        return float64(val)
+    }
+
+    return v
+}
+
+// parseTOMLKey splits a possibly dotted and quoted TOML key into its parts.
+func parseTOMLKey(s string) ([]string, error) {
+    var parts []string
+    s = strings.TrimSpace(s)
+
+    for len(s) > 0 {
+        var part string
+        switch s[0] {
+        case '"':
+            end := closingQuote(s, '"')
+            if end < 0 {
+                return nil, fmt.Errorf("Unterminated key %q", s)
+            }
+            unq, err := strconv.Unquote(s[:end+1])
+            if err != nil {
+                return nil, err
+            }
+            part, s = unq, s[end+1:]
+        case '\'':
+            end := strings.IndexByte(s[1:], '\'')
+            if end < 0 {
+                return nil, fmt.Errorf("Unterminated key %q", s)
+            }
+            part, s = s[1:end+1], s[end+2:]
+        default:
+            end := strings.IndexByte(s, '.')
+            if end < 0 {
+                end = len(s)
+            }
+            part, s = strings.TrimSpace(s[:end]), s[end:]
+            if !tomlBareKey.MatchString(part) {
+                return nil, fmt.Errorf("Invalid key %q", part)
+            }
+        }
+
+        parts = append(parts, part)
+        s = strings.TrimSpace(s)
+        if strings.HasPrefix(s, ".") {
+            s = strings.TrimSpace(s[1:])
+        } else if s != "" {
+            return nil, fmt.Errorf("Invalid key %q", s)
+        }
+    }
+
+    if len(parts) == 0 {
+        return nil, fmt.Errorf("Empty key")
+    }
+    return parts, nil
+}
+
+func closingQuote(s string, q byte) int {
+    for i := 1; i < len(s); i++ {
+        switch s[i] {
+        case '\\':
+            i++
+        case q:
+            return i
+        }
+    }
+    return -1
+}
+
+// indexOutsideQuotes returns the index of the first c in s not inside a string.
+func indexOutsideQuotes(s string, c byte) int {
+    var quote byte
+    for i := 0; i < len(s); i++ {
+        switch {
+        case quote == '"' && s[i] == '\\':
+            i++
+        case quote != 0 && s[i] == quote:
+            quote = 0
+        case quote == 0 && (s[i] == '"' || s[i] == '\''):
+            quote = s[i]
+        case quote == 0 && s[i] == c:
+            return i
+        }
+    }
+    return -1
+}
+
+// tomlValueComplete reports whether s holds an entire value, with every string and
+// bracket closed.
+func tomlValueComplete(s string) bool {
+    depth := 0
+    for i := 0; i < len(s); i++ {
+        switch {
+        case strings.HasPrefix(s[i:], `"""`) || strings.HasPrefix(s[i:], `'''`):
+            delim := s[i : i+3]
+            end := strings.Index(s[i+3:], delim)
+            if end < 0 {
+                return false
+            }
+            i += end + 5
+        case s[i] == '"':
+            end := closingQuote(s[i:], '"')
+            if end < 0 {
+                return false
+            }
+            i += end
+        case s[i] == '\'':
+            end := strings.IndexByte(s[i+1:], '\'')
+            if end < 0 {
+                return false
+            }
+            i += end + 1
+        case s[i] == '#':
+            nl := strings.IndexByte(s[i:], '\n')
+            if nl < 0 {
+                return depth == 0
+            }
+            i += nl
+        case s[i] == '[' || s[i] == '{':
+            depth++
+        case s[i] == ']' || s[i] == '}':
+            depth--
+        }
+    }
+    return depth == 0
+}
+
+// tomlComment returns the trailing comment of a line, including the leading #.
+func tomlComment(line string) string {
+    if i := indexOutsideQuotes(line, '#'); i >= 0 {
+        return strings.TrimSpace(line[i:])
+    }
+    return ""
+}
+
+func stripTOMLComment(raw string) string {
+    lines := strings.Split(raw, "\n")
+    last := lines[len(lines)-1]
+    if i := indexOutsideQuotes(last, '#'); i >= 0 && tomlValueComplete(strings.Join(append(lines[:len(lines)-1:len(lines)-1], last[:i]), "\n")) {
+        lines[len(lines)-1] = last[:i]
+    }
+    return strings.Join(lines, "\n")
+}
+
+func tomlKey(path []string) string {
+    parts := make([]string, len(path))
+    for i, p := range path {
+        if tomlBareKey.MatchString(p) {
+            parts[i] = p
+        } else {
+            parts[i] = tomlString(p)
+        }
+    }
+    return strings.Join(parts, ".")
+}
+
+// tomlValue renders v as an inline TOML value.
+func tomlValue(v interface{}) string {
+    switch val := normalizeMaps(v).(type) {
+    case nil:
+        return `""`
+    case string:
+        return tomlString(val)
+    case bool:
+        return strconv.FormatBool(val)
+    case time.Time:
+        return val.Format(time.RFC3339Nano)
+    case time.Duration:
+        return tomlString(val.String())
+    case float32:
+        return strconv.FormatFloat(float64(val), 'g', -1, 32)
+    case float64:
+        s := strconv.FormatFloat(val, 'g', -1, 64)
+        if !strings.ContainsAny(s, ".eEnN") {
+            s += ".0"
+        }
+        return s
+    case map[string]interface{}:
+        parts := make([]string, 0, len(val))
+        for _, key := range sortedKeys(val) {
+            parts = append(parts, tomlKey([]string{key})+" = "+tomlValue(val[key]))
+        }
+        return "{" + strings.Join(parts, ", ") + "}"
+    }
+
+    rv := reflect.ValueOf(v)
+    switch rv.Kind() {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return fmt.Sprint(v)
+    case reflect.Slice, reflect.Array:
+        parts := make([]string, rv.Len())
+        for i := range parts {
+            parts[i] = tomlValue(rv.Index(i).Interface())
+        }
+        return "[" + strings.Join(parts, ", ") + "]"
+    }
+
+    return tomlString(fmt.Sprint(v))
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+    var b strings.Builder
+    b.WriteByte('"')
+    for _, r := range s {
+        switch r {
+        case '"':
+            b.WriteString(`\"`)
+        case '\\':
+            b.WriteString(`\\`)
+        case '\b':
+            b.WriteString(`\b`)
+        case '\t':
+            b.WriteString(`\t`)
+        case '\n':
+            b.WriteString(`\n`)
+        case '\f':
+            b.WriteString(`\f`)
+        case '\r':
+            b.WriteString(`\r`)
+        default:
+            if r < 0x20 || r == 0x7f {
+                fmt.Fprintf(&b, `\u%04X`, r)
+            } else {
+                b.WriteRune(r)
+            }
+        }
+    }
+    b.WriteByte('"')
+    return b.String()
+}
@@ -0,0 +1,148 @@
+package cfg
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+var roundTripDocs = []struct {
+    ext string
+    doc string
+}{
+    {"toml", `# service settings
+name = "api" # shown in logs
+
+[[servers]]
+host = "a"
+port = 1
+
+[[servers]]
+host = "b"
+port = 2
+`},
+    {"yaml", `# service settings
+name: api # shown in logs
+servers:
+  - host: a
+    port: 1
+  - host: b
+    port: 2
+`},
+}
+
+// Writes doc as a config file of type ext, reads it and returns the config.
+func readRoundTripConfig(t *testing.T, ext, doc string) *Config {
+    t.Helper()
+
+    file := filepath.Join(t.TempDir(), "config."+ext)
+    if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c := New()
+    c.SetConfigFile(file)
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    return c
+}
+
+// Writes c back to its config file and returns the file as read by a fresh config.
+func rereadConfig(t *testing.T, c *Config) (*Config, string) {
+    t.Helper()
+
+    if err := c.WriteConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    written, err := os.ReadFile(c.ConfigFileUsed())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    reread := New()
+    reread.SetConfigFile(c.ConfigFileUsed())
+    if err := reread.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    return reread, string(written)
+}
+
+// Returns the host of every entry of the servers list.
+func serverHosts(c *Config) []string {
+    var hosts []string
+
+    list := reflect.ValueOf(c.Get("servers"))
+    if list.Kind() != reflect.Slice {
+        return nil
+    }
+    for i := 0; i < list.Len(); i++ {
+        server, _ := toStringMap(list.Index(i).Interface())
+        hosts = append(hosts, fmt.Sprint(server["host"]))
+    }
+
+    return hosts
+}
+
+func TestRoundTripChangedList(t *testing.T) {
+    for _, tt := range roundTripDocs {
+        t.Run(tt.ext, func(t *testing.T) {
+            c := readRoundTripConfig(t, tt.ext, tt.doc)
+            c.Set("servers", []interface{}{
+                map[string]interface{}{"host": "c", "port": 3},
+            })
+
+            reread, written := rereadConfig(t, c)
+            if got := serverHosts(reread); !reflect.DeepEqual(got, []string{"c"}) {
+                t.Errorf("servers hosts = %v, want [c]\n%s", got, written)
+            }
+            if got := reread.GetString("name"); got != "api" {
+                t.Errorf("GetString(name) = %q, want api", got)
+            }
+        })
+    }
+}
+
+func TestRoundTripKeepsLayout(t *testing.T) {
+    for _, tt := range roundTripDocs {
+        t.Run(tt.ext, func(t *testing.T) {
+            c := readRoundTripConfig(t, tt.ext, tt.doc)
+            c.Set("name", "web")
+
+            reread, written := rereadConfig(t, c)
+            if got := reread.GetString("name"); got != "web" {
+                t.Errorf("GetString(name) = %q, want web", got)
+            }
+            if got := serverHosts(reread); !reflect.DeepEqual(got, []string{"a", "b"}) {
+                t.Errorf("servers hosts = %v, want [a b]", got)
+            }
+            for _, comment := range []string{"# service settings", "# shown in logs"} {
+                if !strings.Contains(written, comment) {
+                    t.Errorf("written document lost %q:\n%s", comment, written)
+                }
+            }
+        })
+    }
+}
+
+func TestRoundTripRemovedList(t *testing.T) {
+    for _, tt := range roundTripDocs {
+        t.Run(tt.ext, func(t *testing.T) {
+            c := readRoundTripConfig(t, tt.ext, tt.doc)
+            if err := c.DeleteFromConfig("servers"); err != nil {
+                t.Fatal(err)
+            }
+
+            reread, written := rereadConfig(t, c)
+            if reread.IsSet("servers") {
+                t.Errorf("servers still set after DeleteFromConfig:\n%s", written)
+            }
+        })
+    }
+}
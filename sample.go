@@ -0,0 +1,135 @@
+package cfg
+
+import (
+    "encoding/json"
+    "fmt"
+    "io"
+    "reflect"
+    "strings"
+
+    "gopkg.in/yaml.v2"
+)
+
+type sampleEntry struct {
+    value    interface{}
+    typeName string
+}
+
+// Writes a commented out template of every registered default to w, as a starting
+// point for a config file in the given format. Each entry lists its type and default.
+//
+// Keys of any target structs are included as well, with their zero value when no
+// default is registered, so settings without a default still appear in the sample.
+// Keys are taken from the tags Unmarshal uses, see SetTagName, falling back to the
+// lowercased field name.
+func GenerateSample(w io.Writer, format string, targets ...interface{}) error {
+    return c.GenerateSample(w, format, targets...)
+}
+func (c *Config) GenerateSample(w io.Writer, format string, targets ...interface{}) error {
+    tree := make(map[string]interface{})
+
+    for _, target := range targets {
+        t := reflect.TypeOf(target)
+        for t != nil && t.Kind() == reflect.Ptr {
+            t = t.Elem()
+        }
+        if t == nil || t.Kind() != reflect.Struct {
+            return fmt.Errorf("Sample target must be a struct, got %T", target)
+        }
+        c.sampleStruct(tree, t, nil)
+    }
+
+    c.mu.RLock()
+    for key, val := range c.defaults {
+        c.sampleValue(tree, strings.Split(key, c.keyDelm), normalizeMaps(val))
+    }
+    c.mu.RUnlock()
+
+    switch canonicalType(format) {
+    case "yaml":
+        fmt.Fprintln(w, "# Sample configuration generated from the registered defaults.")
+        fmt.Fprintln(w, "# Uncomment and edit a setting to change it.")
+        return writeYAMLSample(w, tree, 0)
+    case "toml":
+        fmt.Fprintln(w, "# Sample configuration generated from the registered defaults.")
+        fmt.Fprintln(w, "# Uncomment and edit a setting to change it.")
+        return writeTOMLSample(w, tree, nil)
+    }
+
+    return UnsupportedConfigError(format)
+}
+
+// sampleValue records val at path, expanding maps into nested entries.
+func (c *Config) sampleValue(tree map[string]interface{}, path []string, val interface{}) {
+    if m, ok := val.(map[string]interface{}); ok && len(m) > 0 {
+        for k, v := range m {
+            c.sampleValue(tree, append(append([]string{}, path...), c.normalizeKey(k)), v)
+        }
+        return
+    }
+
+    setNested(tree, path, sampleEntry{val, fmt.Sprintf("%T", val)})
+}
+
+func (c *Config) sampleStruct(tree map[string]interface{}, t reflect.Type, prefix []string) {
+    c.walkStruct(t, prefix, func(path []string, f reflect.StructField) {
+        setNested(tree, path, sampleEntry{reflect.Zero(f.Type).Interface(), f.Type.String()})
+    })
+}
+
+func writeYAMLSample(w io.Writer, tree map[string]interface{}, depth int) error {
+    indent := strings.Repeat("  ", depth)
+
+    for _, key := range sortedKeys(tree) {
+        switch val := tree[key].(type) {
+        case map[string]interface{}:
+            fmt.Fprintf(w, "# %s%s:\n", indent, yamlScalar(key))
+            if err := writeYAMLSample(w, val, depth+1); err != nil {
+                return err
+            }
+        case sampleEntry:
+            fmt.Fprintf(w, "# %s%s: %s  # %s\n", indent, yamlScalar(key), yamlScalar(val.value), val.typeName)
+        }
+    }
+
+    return nil
+}
+
+func writeTOMLSample(w io.Writer, tree map[string]interface{}, path []string) error {
+    var tables []string
+
+    for _, key := range sortedKeys(tree) {
+        switch val := tree[key].(type) {
+        case map[string]interface{}:
+            tables = append(tables, key)
+        case sampleEntry:
+            fmt.Fprintf(w, "# %s = %s  # %s\n", tomlKey([]string{key}), tomlValue(val.value), val.typeName)
+        }
+    }
+
+    for _, key := range tables {
+        sub := append(append([]string{}, path...), key)
+        fmt.Fprintf(w, "\n# [%s]\n", tomlKey(sub))
+        if err := writeTOMLSample(w, tree[key].(map[string]interface{}), sub); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// yamlScalar renders v on a single line, using flow style for collections.
+func yamlScalar(v interface{}) string {
+    rv := reflect.ValueOf(v)
+    if v != nil && (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array || rv.Kind() == reflect.Map) {
+        if b, err := json.Marshal(normalizeMaps(v)); err == nil {
+            return string(b)
+        }
+    }
+
+    b, err := yaml.Marshal(v)
+    if err != nil {
+        return fmt.Sprint(v)
+    }
+    return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,204 @@
+package cfg
+
+import (
+    "encoding/json"
+    "path"
+    "strconv"
+    "strings"
+
+    "github.com/spf13/cast"
+)
+
+// Replaces the values of secret keys in redacted output.
+const redactedValue = "***"
+
+// Marks keys as secret, so Debug and AllSettings with WithRedaction print "***" in
+// place of their values and of everything beneath them. A key may be a pattern in
+// which every element is matched on its own, so "*.password" covers db.password and
+// cache.password but not db.primary.password. The elements of a list are matched by
+// their index, so "servers.*.password" covers the password of every entry of servers.
+func MarkSecret(keys ...string) { c.MarkSecret(keys...) }
+func (c *Config) MarkSecret(keys ...string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    for _, key := range keys {
+        key = c.normalizeKey(key)
+        if !stringInSlice(key, c.secrets) {
+            c.secrets = append(c.secrets, key)
+        }
+    }
+}
+
+// Returns whether key, or a key above it, has been marked secret.
+func IsSecret(key string) bool { return c.IsSecret(key) }
+func (c *Config) IsSecret(key string) bool {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    return c.isSecret(c.realKey(c.normalizeKey(key)))
+}
+
+// Returns the value of key like Get, or the maps beneath it in every layer merged like
+// Sub, with the values of secret keys replaced by "***", see MarkSecret. found is false
+// if no layer holds key.
+func GetRedacted(key string) (value interface{}, found bool) { return c.GetRedacted(key) }
+func (c *Config) GetRedacted(key string) (value interface{}, found bool) {
+    if settings, found := c.settingsUnder(key); found {
+        c.mu.RLock()
+        defer c.mu.RUnlock()
+
+        key = c.realKey(c.normalizeKey(key))
+        if c.isSecret(key) {
+            return redactedValue, true
+        }
+        return c.redact(settings, key), true
+    }
+
+    if !c.Has(key) {
+        return nil, false
+    }
+
+    return c.redactKey(key, c.Get(key)), true
+}
+
+// redactKey returns v, the value of key, with it or the secret keys beneath it replaced.
+func (c *Config) redactKey(key string, v interface{}) interface{} {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    key = c.realKey(c.normalizeKey(key))
+    if c.isSecret(key) {
+        return redactedValue
+    }
+
+    return c.redactValue(v, key)
+}
+
+// Returns the effective settings as a JSON document, with the values of secret keys
+// replaced by "***", so printing or logging a Config does not leak credentials.
+func (c *Config) String() string {
+    b, err := c.MarshalJSON()
+    if err != nil {
+        return "Config: " + err.Error()
+    }
+
+    return string(b)
+}
+
+// Returns the effective settings of AllSettingsNested as JSON, with the values of
+// secret keys replaced by "***", see MarkSecret.
+func (c *Config) MarshalJSON() ([]byte, error) {
+    return json.Marshal(normalizeMaps(c.AllSettingsNested(WithRedaction())))
+}
+
+// Adjusts what AllSettings returns.
+type SettingsOption func(*settingsOptions)
+
+type settingsOptions struct {
+    redact bool
+}
+
+// Replaces the values of the keys marked secret with "***", see MarkSecret.
+func WithRedaction() SettingsOption {
+    return func(o *settingsOptions) {
+        o.redact = true
+    }
+}
+
+// isSecret reports whether key or a key above it matches a secret pattern. Caller must
+// hold mu.
+func (c *Config) isSecret(key string) bool {
+    if len(c.secrets) == 0 {
+        return false
+    }
+
+    elems := strings.Split(key, c.keyDelm)
+    for i := len(elems); i > 0; i-- {
+        for _, pattern := range c.secrets {
+            if c.matchKey(pattern, elems[:i]) {
+                return true
+            }
+        }
+    }
+
+    return false
+}
+
+// matchKey reports whether the elements of a key match pattern element by element.
+func (c *Config) matchKey(pattern string, elems []string) bool {
+    patElems := strings.Split(pattern, c.keyDelm)
+    if len(patElems) != len(elems) {
+        return false
+    }
+
+    for i, p := range patElems {
+        if ok, _ := path.Match(p, elems[i]); !ok {
+            return false
+        }
+    }
+
+    return true
+}
+
+// redact returns a copy of m, whose keys sit beneath prefix, with the values of secret
+// keys replaced. m is returned as is when no key is secret. Caller must hold mu.
+func (c *Config) redact(m map[string]interface{}, prefix string) map[string]interface{} {
+    if len(c.secrets) == 0 {
+        return m
+    }
+
+    out := make(map[string]interface{}, len(m))
+    for k, v := range m {
+        key := k
+        if prefix != "" {
+            key = prefix + c.keyDelm + k
+        }
+
+        if c.isSecret(c.realKey(key)) {
+            out[k] = redactedValue
+        } else {
+            out[k] = c.redactValue(v, key)
+        }
+    }
+
+    return out
+}
+
+// redactValue returns v, the value of key, with the secret keys beneath it replaced.
+// The elements of a list sit beneath key at their index. Caller must hold mu.
+func (c *Config) redactValue(v interface{}, key string) interface{} {
+    if len(c.secrets) == 0 {
+        return v
+    }
+
+    switch v := v.(type) {
+    case map[string]interface{}, map[interface{}]interface{}:
+        return c.redact(cast.ToStringMap(v), key)
+    case []interface{}:
+        out := make([]interface{}, len(v))
+        for i, e := range v {
+            out[i] = c.redactElem(e, key, i)
+        }
+        return out
+    case []map[string]interface{}:
+        out := make([]interface{}, len(v))
+        for i, e := range v {
+            out[i] = c.redactElem(e, key, i)
+        }
+        return out
+    }
+
+    return v
+}
+
+// redactElem returns the element at index i of the list held by key, redacted.
+// Caller must hold mu.
+func (c *Config) redactElem(e interface{}, key string, i int) interface{} {
+    elemKey := key + c.keyDelm + strconv.Itoa(i)
+    if c.isSecret(c.realKey(elemKey)) {
+        return redactedValue
+    }
+
+    return c.redactValue(e, elemKey)
+}
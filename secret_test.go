@@ -0,0 +1,73 @@
+package cfg
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+func TestRedactLists(t *testing.T) {
+    servers := []struct {
+        name  string
+        value interface{}
+    }{
+        {"interfaces", []interface{}{
+            map[string]interface{}{"host": "a", "password": "hunter2"},
+            map[interface{}]interface{}{"host": "b", "password": "hunter3"},
+        }},
+        {"maps", []map[string]interface{}{
+            {"host": "a", "password": "hunter2"},
+            {"host": "b", "password": "hunter3"},
+        }},
+    }
+
+    for _, tt := range servers {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            c.Set("servers", tt.value)
+            c.MarkSecret("servers.*.password")
+
+            if !c.IsSecret("servers.0.password") {
+                t.Error("IsSecret(servers.0.password) = false")
+            }
+
+            b, err := json.Marshal(c)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if strings.Contains(string(b), "hunter") {
+                t.Errorf("MarshalJSON() = %s, leaks a password", b)
+            }
+
+            var doc struct {
+                Servers []map[string]string
+            }
+            if err := json.Unmarshal(b, &doc); err != nil {
+                t.Fatal(err)
+            }
+            if len(doc.Servers) != 2 || doc.Servers[1]["host"] != "b" || doc.Servers[1]["password"] != redactedValue {
+                t.Errorf("MarshalJSON() servers = %v, want hosts kept and passwords %q", doc.Servers, redactedValue)
+            }
+
+            got, _ := c.GetRedacted("servers")
+            if b, _ := json.Marshal(normalizeMaps(got)); strings.Contains(string(b), "hunter") {
+                t.Errorf("GetRedacted(servers) = %s, leaks a password", b)
+            }
+            if s := c.String(); strings.Contains(s, "hunter") {
+                t.Errorf("String() = %s, leaks a password", s)
+            }
+        })
+    }
+}
+
+func TestRedactListElements(t *testing.T) {
+    c := New()
+    c.Set("tokens", []interface{}{"public", "private"})
+    c.MarkSecret("tokens.1")
+
+    got, _ := c.GetRedacted("tokens")
+    list, _ := got.([]interface{})
+    if len(list) != 2 || list[0] != "public" || list[1] != redactedValue {
+        t.Errorf("GetRedacted(tokens) = %v, want [public %s]", got, redactedValue)
+    }
+}
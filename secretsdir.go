@@ -0,0 +1,127 @@
+package cfg
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+)
+
+// Priority of the layers added by AddSecretsDir unless set otherwise, above the config
+// file and below computed values and overrides.
+const DefaultSecretsDirPriority = PriorityConfig + 10
+
+// Separator in secret file names that AddSecretsDir turns into the key delimiter
+// unless set otherwise.
+const DefaultSecretsDirSeparator = "__"
+
+// Adjusts how AddSecretsDir maps the files of a directory.
+type SecretsDirOption func(*secretsDirOptions)
+
+type secretsDirOptions struct {
+    priority  int
+    separator string
+}
+
+// Places the layer at priority instead of DefaultSecretsDirPriority, see the Priority
+// constants for where the built-in layers sit.
+func WithSecretsPriority(priority int) SecretsDirOption {
+    return func(o *secretsDirOptions) {
+        o.priority = priority
+    }
+}
+
+// Nests keys at sep in file names instead of at DefaultSecretsDirSeparator. An empty sep
+// turns every file into a top-level key.
+func WithSecretsSeparator(sep string) SecretsDirOption {
+    return func(o *secretsDirOptions) {
+        o.separator = sep
+    }
+}
+
+// Adds the files of dir, such as the /run/secrets of Docker Swarm or a mounted
+// Kubernetes Secret, as a layer named "secrets:" followed by dir.
+//
+// Every file becomes a key named after it, holding its contents with surrounding
+// whitespace trimmed, and is marked secret, see MarkSecret. The separator in file
+// names nests keys, so the file db__password holds db.password. Hidden files and
+// directories, such as the ..data bookkeeping of Kubernetes, are skipped. The files
+// are read once, when the directory is added.
+func AddSecretsDir(dir string, opts ...SecretsDirOption) error { return c.AddSecretsDir(dir, opts...) }
+func (c *Config) AddSecretsDir(dir string, opts ...SecretsDirOption) error {
+    return c.addSecretsDir("secrets:"+dir, dir, opts)
+}
+
+// Environment variable systemd sets to the directory holding the credentials of a unit.
+const CredentialsDirectoryEnv = "CREDENTIALS_DIRECTORY"
+
+// Adds the credentials systemd passes to the unit with LoadCredential=,
+// LoadCredentialEncrypted= or SetCredential= as a layer named "systemd-credentials",
+// mapping the files of $CREDENTIALS_DIRECTORY like AddSecretsDir. A credential named
+// db.password, or db__password, holds db.password. This works for units running with
+// DynamicUser= too, which cannot read the original files. Nothing is added when
+// CREDENTIALS_DIRECTORY is not set, outside systemd or for units without credentials.
+func AddSystemdCredentials(opts ...SecretsDirOption) error { return c.AddSystemdCredentials(opts...) }
+func (c *Config) AddSystemdCredentials(opts ...SecretsDirOption) error {
+    dir := os.Getenv(CredentialsDirectoryEnv)
+    if dir == "" {
+        return nil
+    }
+
+    return c.addSecretsDir("systemd-credentials", dir, opts)
+}
+
+// addSecretsDir adds the files of dir as the layer called name.
+func (c *Config) addSecretsDir(name, dir string, opts []SecretsDirOption) error {
+    o := secretsDirOptions{priority: DefaultSecretsDirPriority, separator: DefaultSecretsDirSeparator}
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    values, err := c.readSecretsDir(dir, o.separator)
+    if err != nil {
+        return fmt.Errorf("Cannot read secrets dir %q: %w", dir, err)
+    }
+
+    l, err := c.AddLayer(name, o.priority)
+    if err != nil {
+        return err
+    }
+    l.Replace(values)
+
+    keys := make([]string, 0, len(values))
+    for key := range values {
+        keys = append(keys, key)
+    }
+    c.MarkSecret(keys...)
+
+    return nil
+}
+
+// readSecretsDir returns the trimmed contents of every file in dir by key.
+func (c *Config) readSecretsDir(dir, separator string) (map[string]interface{}, error) {
+    files, err := c.readDir(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    values := make(map[string]interface{})
+    for _, f := range files {
+        if strings.HasPrefix(f.Name(), ".") || f.IsDir() {
+            continue
+        }
+
+        data, err := c.readConfigPath(filepath.Join(dir, f.Name()))
+        if err != nil {
+            return nil, err
+        }
+
+        key := f.Name()
+        if separator != "" {
+            key = strings.ReplaceAll(key, separator, c.keyDelm)
+        }
+        values[key] = strings.TrimSpace(string(data))
+    }
+
+    return values, nil
+}
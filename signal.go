@@ -0,0 +1,52 @@
+package cfg
+
+import (
+    "context"
+    "os"
+    "os/signal"
+    "sync"
+    "syscall"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// Re-reads the config file whenever one of sig is received, SIGHUP when none are given.
+//
+// Reloads are validated exactly like watched reloads, a rejected file leaves the previous
+// config in effect, and every attempt is reported to the OnConfigChange handlers.
+// Calling stop, more than once if need be, or StopWatching uninstalls the handler.
+func ReloadOnSignal(sig ...os.Signal) (stop func()) { return c.ReloadOnSignal(sig...) }
+func (c *Config) ReloadOnSignal(sig ...os.Signal) (stop func()) {
+    if len(sig) == 0 {
+        sig = []os.Signal{syscall.SIGHUP}
+    }
+
+    ctx, done := c.startWatch(context.Background())
+    ctx, cancel := context.WithCancel(ctx)
+
+    ch := make(chan os.Signal, 1)
+    signal.Notify(ch, sig...)
+
+    go func() {
+        defer done()
+        defer signal.Stop(ch)
+
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case s := <-ch:
+                c.logInfo("Received", s, "reloading config")
+                c.reloadWatched(fsnotify.Event{Name: c.ConfigFileUsed()})
+            }
+        }
+    }()
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            signal.Stop(ch)
+            cancel()
+        })
+    }
+}
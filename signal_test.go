@@ -0,0 +1,66 @@
+//go:build unix
+
+package cfg
+
+import (
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+    "time"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(file, []byte("name: api\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c := New()
+    c.SetConfigFile(file)
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    stop := c.ReloadOnSignal(syscall.SIGUSR1)
+    defer stop()
+
+    if err := os.WriteFile(file, []byte("name: web\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+        t.Fatal(err)
+    }
+
+    deadline := time.Now().Add(5 * time.Second)
+    for c.GetString("name") != "web" {
+        if time.Now().After(deadline) {
+            t.Fatalf("GetString(name) = %q after SIGUSR1, want web", c.GetString("name"))
+        }
+        time.Sleep(time.Millisecond)
+    }
+}
+
+func TestReloadOnSignalStop(t *testing.T) {
+    tests := []struct {
+        name string
+        stop func(c *Config, stop func())
+    }{
+        {"stop twice", func(c *Config, stop func()) {
+            stop()
+            stop()
+            c.StopWatching()
+        }},
+        {"stop watching", func(c *Config, stop func()) {
+            c.StopWatching()
+            stop()
+        }},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := New()
+            tt.stop(c, c.ReloadOnSignal(syscall.SIGUSR2))
+        })
+    }
+}
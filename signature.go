@@ -0,0 +1,218 @@
+package cfg
+
+import (
+    "bytes"
+    "context"
+    "crypto/ed25519"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/url"
+    "strings"
+
+    "github.com/ProtonMail/go-crypto/openpgp"
+    "golang.org/x/crypto/blake2b"
+)
+
+// Extension of the detached signature of a config file, config.yaml.sig for config.yaml.
+const SignatureExt = ".sig"
+
+// Denotes a config file whose signature is missing or does not verify.
+type SignatureError struct {
+    File string
+    Err  error
+}
+
+// Returns the formatted signature error.
+func (se SignatureError) Error() string {
+    return fmt.Sprintf("Signature of config file %q does not verify: %v", se.File, se.Err)
+}
+
+// Returns the reason the signature does not verify.
+func (se SignatureError) Unwrap() error {
+    return se.Err
+}
+
+// Implemented by RemoteProviders able to serve a detached signature of their document,
+// see RequireSignature.
+type SignedRemoteProvider interface {
+    RemoteProvider
+
+    // Returns the detached signature of the document Get returns.
+    GetSignature(ctx context.Context) ([]byte, error)
+}
+
+// Verifies data against a detached signature.
+type signatureVerifier interface {
+    verify(data, sig []byte) error
+}
+
+// Requires config files, the drop-in files and profiles merged into them, and remote
+// configs to carry a detached signature by the key pubkey, rejecting them with a
+// SignatureError otherwise. The signature of a file is read from the file of the same
+// name with SignatureExt appended, that of a URL from the URL with SignatureExt appended
+// to its path, that of a remote config from its provider, see SignedRemoteProvider.
+//
+// pubkey is a minisign public key, as the .pub file written by minisign -G or its
+// second line, or an ASCII armored PGP public key. Calling it again adds another key,
+// and a signature by any of them is accepted, so keys can be rotated.
+func RequireSignature(pubkey []byte) error { return c.RequireSignature(pubkey) }
+func (c *Config) RequireSignature(pubkey []byte) error {
+    var (
+        v   signatureVerifier
+        err error
+    )
+
+    if bytes.Contains(pubkey, []byte("-----BEGIN PGP PUBLIC KEY BLOCK-----")) {
+        v, err = newPGPVerifier(pubkey)
+    } else {
+        v, err = newMinisignVerifier(pubkey)
+    }
+    if err != nil {
+        return err
+    }
+
+    c.verifiers = append(c.verifiers, v)
+    return nil
+}
+
+// verifySignature checks data, read from file, against the signature read by readSig,
+// if signatures are required.
+func (c *Config) verifySignature(file string, data []byte, readSig func() ([]byte, error)) error {
+    if len(c.verifiers) == 0 {
+        return nil
+    }
+
+    sig, err := readSig()
+    if err != nil {
+        return SignatureError{file, fmt.Errorf("Cannot read signature: %w", err)}
+    }
+
+    for _, v := range c.verifiers {
+        if err = v.verify(data, sig); err == nil {
+            return nil
+        }
+    }
+
+    return SignatureError{file, err}
+}
+
+// verifyFile checks data, read from the config file file, against its signature.
+func (c *Config) verifyFile(ctx context.Context, file string, data []byte) error {
+    return c.verifySignature(file, data, func() ([]byte, error) {
+        switch {
+        case file == StdinConfigFile:
+            return nil, errors.New("Config read from standard input has no signature")
+        case isURL(file):
+            u, err := url.Parse(file)
+            if err != nil {
+                return nil, err
+            }
+            u.Path += SignatureExt
+            return c.fetchURL(ctx, u.String())
+        }
+
+        return c.readFile(file + SignatureExt)
+    })
+}
+
+// Signature algorithms of minisign, plain Ed25519 and Ed25519 over a BLAKE2b-512 hash.
+const (
+    minisignAlg       = "Ed"
+    minisignHashedAlg = "ED"
+)
+
+// Verifies minisign signatures.
+type minisignVerifier struct {
+    keyID [8]byte
+    key   ed25519.PublicKey
+}
+
+func newMinisignVerifier(pubkey []byte) (*minisignVerifier, error) {
+    b, err := base64.StdEncoding.DecodeString(lastLine(string(pubkey), "untrusted comment:"))
+    if err != nil || len(b) != 2+8+ed25519.PublicKeySize || string(b[:2]) != minisignAlg {
+        return nil, errors.New("Public key is neither a minisign nor an armored PGP key")
+    }
+
+    v := &minisignVerifier{key: ed25519.PublicKey(b[10:])}
+    copy(v.keyID[:], b[2:10])
+
+    return v, nil
+}
+
+// verify checks a minisign signature file: an untrusted comment, the signature, a
+// trusted comment and the global signature over the signature and trusted comment.
+func (v *minisignVerifier) verify(data, sig []byte) error {
+    lines := strings.Split(strings.TrimSpace(string(sig)), "\n")
+    if len(lines) != 4 {
+        return errors.New("Malformed minisign signature")
+    }
+
+    b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+    if err != nil || len(b) != 2+8+ed25519.SignatureSize {
+        return errors.New("Malformed minisign signature")
+    }
+    if !bytes.Equal(b[2:10], v.keyID[:]) {
+        return errors.New("Signed by another key")
+    }
+
+    switch string(b[:2]) {
+    case minisignAlg:
+    case minisignHashedAlg:
+        sum := blake2b.Sum512(data)
+        data = sum[:]
+    default:
+        return fmt.Errorf("Unsupported minisign algorithm %q", b[:2])
+    }
+    if !ed25519.Verify(v.key, data, b[10:]) {
+        return errors.New("Invalid signature")
+    }
+
+    trusted := strings.TrimSpace(lines[2])
+    if !strings.HasPrefix(trusted, "trusted comment: ") {
+        return errors.New("Malformed minisign signature")
+    }
+    global, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+    signed := append(append([]byte(nil), b[10:]...), strings.TrimPrefix(trusted, "trusted comment: ")...)
+    if err != nil || !ed25519.Verify(v.key, signed, global) {
+        return errors.New("Invalid signature of the trusted comment")
+    }
+
+    return nil
+}
+
+// lastLine returns the last non-empty line of s not starting with skip.
+func lastLine(s, skip string) string {
+    lines := strings.Split(strings.TrimSpace(s), "\n")
+    for i := len(lines) - 1; i >= 0; i-- {
+        if line := strings.TrimSpace(lines[i]); line != "" && !strings.HasPrefix(line, skip) {
+            return line
+        }
+    }
+
+    return ""
+}
+
+// Verifies binary and ASCII armored PGP signatures.
+type pgpVerifier struct {
+    keyring openpgp.EntityList
+}
+
+func newPGPVerifier(pubkey []byte) (*pgpVerifier, error) {
+    keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(pubkey))
+    if err != nil {
+        return nil, fmt.Errorf("Reading PGP public key: %w", err)
+    }
+
+    return &pgpVerifier{keyring}, nil
+}
+
+func (v *pgpVerifier) verify(data, sig []byte) error {
+    if bytes.HasPrefix(bytes.TrimSpace(sig), []byte("-----BEGIN PGP SIGNATURE-----")) {
+        _, err := openpgp.CheckArmoredDetachedSignature(v.keyring, bytes.NewReader(data), bytes.NewReader(sig), nil)
+        return err
+    }
+
+    _, err := openpgp.CheckDetachedSignature(v.keyring, bytes.NewReader(data), bytes.NewReader(sig), nil)
+    return err
+}
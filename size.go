@@ -0,0 +1,74 @@
+package cfg
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "io"
+)
+
+// Maximum size of a config file unless set otherwise, see SetMaxConfigSize.
+const DefaultMaxConfigSize = 64 << 20
+
+// Denotes a config file larger than the maximum size, see SetMaxConfigSize.
+type ConfigTooLargeError struct {
+    File string
+    Max  int64
+}
+
+// Returns the formatted size error.
+func (tle ConfigTooLargeError) Error() string {
+    if tle.File == "" {
+        return fmt.Sprintf("Config is larger than the maximum of %d bytes", tle.Max)
+    }
+    return fmt.Sprintf("Config file %q is larger than the maximum of %d bytes", tle.File, tle.Max)
+}
+
+// Sets the maximum size in bytes of the config file, the drop-in files and profiles
+// merged into it, and of the documents read by ReadConfig, MergeConfig, command sources
+// and remote providers, DefaultMaxConfigSize by default. Reading a larger file fails
+// with a ConfigTooLargeError before it is read into memory, or as soon as the limit is
+// passed for standard input, URLs and readers, whose size is not known up front.
+// Documents are read into memory whole before they are decoded, so the limit bounds
+// the memory a single document takes. Zero or less removes the limit.
+func SetMaxConfigSize(n int64) { c.SetMaxConfigSize(n) }
+func (c *Config) SetMaxConfigSize(n int64) {
+    c.maxConfigSize = n
+}
+
+// readConfigPath reads the config file at path, checking its size before reading it.
+func (c *Config) readConfigPath(path string) ([]byte, error) {
+    f, err := c.openFile(path)
+    if err != nil {
+        return nil, err
+    }
+    defer f.Close()
+
+    fi, err := f.Stat()
+    if err != nil {
+        return nil, err
+    }
+    if c.maxConfigSize > 0 && fi.Size() > c.maxConfigSize {
+        return nil, ConfigTooLargeError{path, c.maxConfigSize}
+    }
+
+    return c.readConfigFrom(path, bufio.NewReader(f), fi.Size())
+}
+
+// readConfigFrom reads the config file name from r, failing once it passes the maximum
+// size. size is the expected size, zero if it is not known.
+func (c *Config) readConfigFrom(name string, r io.Reader, size int64) ([]byte, error) {
+    if c.maxConfigSize > 0 {
+        r = io.LimitReader(r, c.maxConfigSize+1)
+    }
+
+    buf := bytes.NewBuffer(make([]byte, 0, size+bytes.MinRead))
+    if _, err := buf.ReadFrom(r); err != nil {
+        return nil, err
+    }
+    if c.maxConfigSize > 0 && int64(buf.Len()) > c.maxConfigSize {
+        return nil, ConfigTooLargeError{name, c.maxConfigSize}
+    }
+
+    return buf.Bytes(), nil
+}
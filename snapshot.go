@@ -0,0 +1,134 @@
+package cfg
+
+import (
+    "net"
+    "net/netip"
+    "net/url"
+    "regexp"
+    "time"
+)
+
+// A read-only view of a Config. It resolves keys exactly as the Config it was taken
+// from, but offers no way to change them.
+type Snapshot struct {
+    c *Config
+}
+
+// Returns the value of the key, see Config.Get.
+func (s Snapshot) Get(key string) interface{} { return s.c.Get(key) }
+
+// Returns the value of the key, see Config.GetE.
+func (s Snapshot) GetE(key string) (interface{}, error) { return s.c.GetE(key) }
+
+// Returns the value associated with the key as a string
+func (s Snapshot) GetString(key string) string { return s.c.GetString(key) }
+
+// Returns the value associated with the key as a boolean
+func (s Snapshot) GetBool(key string) bool { return s.c.GetBool(key) }
+
+// Returns the value associated with the key as an integer
+func (s Snapshot) GetInt(key string) int { return s.c.GetInt(key) }
+
+// Returns the value associated with the key as a 32-bit integer
+func (s Snapshot) GetInt32(key string) int32 { return s.c.GetInt32(key) }
+
+// Returns the value associated with the key as a 64-bit integer
+func (s Snapshot) GetInt64(key string) int64 { return s.c.GetInt64(key) }
+
+// Returns the value associated with the key as an unsigned integer
+func (s Snapshot) GetUint(key string) uint { return s.c.GetUint(key) }
+
+// Returns the value associated with the key as a 32-bit unsigned integer
+func (s Snapshot) GetUint32(key string) uint32 { return s.c.GetUint32(key) }
+
+// Returns the value associated with the key as a 64-bit unsigned integer
+func (s Snapshot) GetUint64(key string) uint64 { return s.c.GetUint64(key) }
+
+// Returns the value associated with the key as a float64
+func (s Snapshot) GetFloat64(key string) float64 { return s.c.GetFloat64(key) }
+
+// Returns the value associated with the key as time
+func (s Snapshot) GetTime(key string) time.Time { return s.c.GetTime(key) }
+
+// Returns the value associated with the key as a duration
+func (s Snapshot) GetDuration(key string) time.Duration { return s.c.GetDuration(key) }
+
+// Returns the value associated with the key as a slice of strings
+func (s Snapshot) GetStringSlice(key string) []string { return s.c.GetStringSlice(key) }
+
+// Returns the value associated with the key as a slice of integers
+func (s Snapshot) GetIntSlice(key string) []int { return s.c.GetIntSlice(key) }
+
+// Returns the value associated with the key as a slice of float64s
+func (s Snapshot) GetFloat64Slice(key string) []float64 { return s.c.GetFloat64Slice(key) }
+
+// Returns the value associated with the key as a slice of durations
+func (s Snapshot) GetDurationSlice(key string) []time.Duration { return s.c.GetDurationSlice(key) }
+
+// Returns the value associated with the key as a map of interfaces
+func (s Snapshot) GetStringMap(key string) map[string]interface{} { return s.c.GetStringMap(key) }
+
+// Returns the value associated with the key as a map of strings
+func (s Snapshot) GetStringMapString(key string) map[string]string {
+    return s.c.GetStringMapString(key)
+}
+
+// Returns the value associated with the key as an absolute URL
+func (s Snapshot) GetURL(key string) *url.URL { return s.c.GetURL(key) }
+
+// Returns the value associated with the key as an absolute URL, see Config.GetURLE.
+func (s Snapshot) GetURLE(key string) (*url.URL, error) { return s.c.GetURLE(key) }
+
+// Returns the value associated with the key as an IP address
+func (s Snapshot) GetIP(key string) net.IP { return s.c.GetIP(key) }
+
+// Returns the value associated with the key as an IP network
+func (s Snapshot) GetCIDR(key string) *net.IPNet { return s.c.GetCIDR(key) }
+
+// Returns the value associated with the key as a netip.Addr
+func (s Snapshot) GetAddr(key string) netip.Addr { return s.c.GetAddr(key) }
+
+// Returns the value associated with the key as a netip.Prefix
+func (s Snapshot) GetPrefix(key string) netip.Prefix { return s.c.GetPrefix(key) }
+
+// Returns the value associated with the key as a regular expression, see Config.GetRegexp.
+func (s Snapshot) GetRegexp(key string) (*regexp.Regexp, error) { return s.c.GetRegexp(key) }
+
+// Returns the value associated with the key decoded as binary data, see Config.GetBytes.
+func (s Snapshot) GetBytes(key string, enc Encoding) []byte { return s.c.GetBytes(key, enc) }
+
+// Returns the time zone named by the value associated with the key, see Config.GetLocation.
+func (s Snapshot) GetLocation(key string) (*time.Location, error) { return s.c.GetLocation(key) }
+
+// Returns the value associated with the key as a map of integers
+func (s Snapshot) GetStringMapInt(key string) map[string]int { return s.c.GetStringMapInt(key) }
+
+// Returns the value associated with the key as a map of 64-bit integers
+func (s Snapshot) GetStringMapInt64(key string) map[string]int64 { return s.c.GetStringMapInt64(key) }
+
+// Returns the value associated with the key as a map of booleans
+func (s Snapshot) GetStringMapBool(key string) map[string]bool { return s.c.GetStringMapBool(key) }
+
+// Reports whether the key is set in any layer.
+func (s Snapshot) IsSet(key string) bool { return s.c.IsSet(key) }
+
+// Returns every key, see Config.AllKeys.
+func (s Snapshot) AllKeys() []string { return s.c.AllKeys() }
+
+// Returns every setting, see Config.AllSettings.
+func (s Snapshot) AllSettings(opts ...SettingsOption) map[string]interface{} {
+    return s.c.AllSettings(opts...)
+}
+
+// Returns every setting as a nested tree, see Config.AllSettingsNested.
+func (s Snapshot) AllSettingsNested(opts ...SettingsOption) map[string]interface{} {
+    return s.c.AllSettingsNested(opts...)
+}
+
+// Decodes the value of key into rawVal, see Config.UnmarshalKey.
+func (s Snapshot) UnmarshalKey(key string, rawVal interface{}) error {
+    return s.c.UnmarshalKey(key, rawVal)
+}
+
+// Decodes all settings into rawVal, see Config.Unmarshal.
+func (s Snapshot) Unmarshal(rawVal interface{}) error { return s.c.Unmarshal(rawVal) }
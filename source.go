@@ -0,0 +1,255 @@
+package cfg
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "time"
+
+    "go.opentelemetry.io/otel/attribute"
+)
+
+// Fetches the complete contents of a named configuration source.
+type SourceFunc func() (map[string]interface{}, error)
+
+// Denotes a source name that has already been registered.
+type SourceExistsError string
+
+// Returns the error for a duplicate source.
+func (str SourceExistsError) Error() string {
+    return fmt.Sprintf("Source %q already registered", string(str))
+}
+
+// Denotes a source name that has not been registered.
+type SourceNotFoundError string
+
+// Returns the error for a missing source.
+func (str SourceNotFoundError) Error() string {
+    return fmt.Sprintf("Source %q not registered", string(str))
+}
+
+type source struct {
+    name   string
+    seq    int
+    fetch  func(ctx context.Context) (map[string]interface{}, error)
+    values map[string]interface{}
+    stop   chan struct{}
+}
+
+// Adds a named layer populated by fetch.
+//
+// Sources sit at PrioritySource, below the config file and above defaults, sources added later take
+// precedence over earlier ones. The source is fetched once immediately and, when
+// refresh is greater than zero, again on every interval so rotated values are picked up.
+// A failed refresh keeps serving the previously fetched values.
+func AddSource(name string, fetch SourceFunc, refresh time.Duration) error {
+    return c.AddSource(name, fetch, refresh)
+}
+func (c *Config) AddSource(name string, fetch SourceFunc, refresh time.Duration) error {
+    return c.addSourceContext(context.Background(), name, func(context.Context) (map[string]interface{}, error) {
+        return fetch()
+    }, refresh)
+}
+
+// addSourceContext adds a source whose fetch honors ctx. Only the initial fetch runs
+// under ctx, refreshes use their own.
+func (c *Config) addSourceContext(ctx context.Context, name string, fetch func(ctx context.Context) (map[string]interface{}, error), refresh time.Duration) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.sourceMu.RLock()
+    _, exists := c.getSource(name)
+    c.sourceMu.RUnlock()
+    if exists {
+        return SourceExistsError(name)
+    }
+
+    values, err := c.fetchSource(ctx, name, fetch)
+    if err != nil {
+        return err
+    }
+
+    src := &source{name: name, fetch: fetch, values: values}
+    if refresh > 0 {
+        src.stop = make(chan struct{})
+    }
+
+    err = c.validateCandidate(func(cand *Config) {
+        added := *src
+        added.seq = cand.layerSeq + 1
+        cand.sources = append(cand.sources, &added)
+    })
+    if err != nil {
+        return err
+    }
+
+    c.sourceMu.Lock()
+    if _, exists := c.getSource(name); exists {
+        c.sourceMu.Unlock()
+        return SourceExistsError(name)
+    }
+    c.layerSeq++
+    src.seq = c.layerSeq
+    c.sources = append(c.sources, src)
+    c.invalidate()
+    c.sourceMu.Unlock()
+    c.changed()
+
+    if refresh > 0 {
+        go c.refreshSource(src, src.stop, refresh)
+    }
+
+    return nil
+}
+
+// Re-fetches the named source immediately.
+func RefreshSource(name string) error { return c.RefreshSource(name) }
+func (c *Config) RefreshSource(name string) error {
+    return c.refreshSourceContext(context.Background(), name)
+}
+
+func (c *Config) refreshSourceContext(ctx context.Context, name string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.sourceMu.RLock()
+    src, exists := c.getSource(name)
+    c.sourceMu.RUnlock()
+    if !exists {
+        return SourceNotFoundError(name)
+    }
+
+    values, err := c.fetchSource(ctx, src.name, src.fetch)
+    if err != nil {
+        return err
+    }
+
+    if err := c.validateSource(name, values); err != nil {
+        return err
+    }
+
+    c.sourceMu.Lock()
+    src.values = values
+    c.invalidate()
+    c.sourceMu.Unlock()
+    c.changed()
+
+    return nil
+}
+
+// Removes the named source, stopping any pending refresh.
+func RemoveSource(name string) error { return c.RemoveSource(name) }
+func (c *Config) RemoveSource(name string) error {
+    if err := c.checkFrozen(); err != nil {
+        return err
+    }
+
+    c.sourceMu.Lock()
+    for i, src := range c.sources {
+        if src.name == name {
+            if src.stop != nil {
+                close(src.stop)
+            }
+            c.sources = append(c.sources[:i], c.sources[i+1:]...)
+            c.invalidate()
+            c.sourceMu.Unlock()
+            c.changed()
+            return nil
+        }
+    }
+    c.sourceMu.Unlock()
+
+    return SourceNotFoundError(name)
+}
+
+// Returns the names of all registered sources in order of increasing precedence.
+func Sources() []string { return c.Sources() }
+func (c *Config) Sources() []string {
+    c.sourceMu.RLock()
+    defer c.sourceMu.RUnlock()
+
+    names := make([]string, 0, len(c.sources))
+    for _, src := range c.sources {
+        names = append(names, src.name)
+    }
+
+    return names
+}
+
+// sourceValues returns the current values of the named source, nil if it does not exist.
+func (c *Config) sourceValues(name string) map[string]interface{} {
+    c.sourceMu.RLock()
+    defer c.sourceMu.RUnlock()
+
+    if src, exists := c.getSource(name); exists {
+        return src.values
+    }
+    return nil
+}
+
+// refreshSource re-fetches src every refresh until stop is closed.
+func (c *Config) refreshSource(src *source, stop <-chan struct{}, refresh time.Duration) {
+    ticker := time.NewTicker(refresh)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-stop:
+            return
+        case <-ticker.C:
+            if c.Frozen() {
+                continue
+            }
+
+            values, err := c.fetchSource(context.Background(), src.name, src.fetch)
+            if err == nil {
+                err = c.validateSource(src.name, values)
+            }
+            c.metrics.reloaded(err)
+            if err != nil {
+                c.logEvent(slog.LevelError, "reload_failed", slog.String("name", src.name), slog.Any("error", err))
+                continue
+            }
+
+            c.sourceMu.Lock()
+            src.values = values
+            c.invalidate()
+            c.sourceMu.Unlock()
+            c.logDebug("Refreshed source", src.name)
+            c.changed()
+        }
+    }
+}
+
+// caller must hold sourceMu
+func (c *Config) getSource(name string) (*source, bool) {
+    for _, src := range c.sources {
+        if src.name == name {
+            return src, true
+        }
+    }
+
+    return nil, false
+}
+
+func (c *Config) fetchSource(ctx context.Context, name string, fetch func(ctx context.Context) (map[string]interface{}, error)) (values map[string]interface{}, err error) {
+    ctx, span := c.startSpan(ctx, "cfg.FetchSource", attribute.String("cfg.source", name))
+    defer func() { endSpan(span, err) }()
+
+    start := time.Now()
+    values, err = fetch(ctx)
+    c.metrics.fetched(time.Since(start), err)
+    if err != nil {
+        return nil, err
+    }
+
+    if values == nil {
+        values = make(map[string]interface{})
+    }
+    c.normalizeKeys(values)
+    span.SetAttributes(attribute.Int("cfg.keys", c.countKeys(values)))
+
+    return values, nil
+}
@@ -0,0 +1,73 @@
+package cfg
+
+import (
+    "errors"
+    "sync"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+func TestSourceErrors(t *testing.T) {
+    fetch := func() (map[string]interface{}, error) { return map[string]interface{}{"key": "value"}, nil }
+
+    tests := []struct {
+        name string
+        run  func(c *Config) error
+        want error
+    }{
+        {"add twice", func(c *Config) error {
+            c.AddSource("vault", fetch, 0)
+            return c.AddSource("vault", fetch, 0)
+        }, SourceExistsError("vault")},
+        {"remove missing", func(c *Config) error {
+            return c.RemoveSource("vault")
+        }, SourceNotFoundError("vault")},
+        {"refresh missing", func(c *Config) error {
+            return c.RefreshSource("vault")
+        }, SourceNotFoundError("vault")},
+        {"add frozen", func(c *Config) error {
+            c.Freeze()
+            return c.AddSource("vault", fetch, 0)
+        }, ErrFrozen},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if err := tt.run(New()); !errors.Is(err, tt.want) {
+                t.Errorf("got %v, want %v", err, tt.want)
+            }
+        })
+    }
+}
+
+func TestSourceRemoveStopsRefresh(t *testing.T) {
+    c := New()
+
+    var fetches atomic.Int32
+    fetch := func() (map[string]interface{}, error) {
+        fetches.Add(1)
+        return map[string]interface{}{"key": "value"}, nil
+    }
+
+    // Remove the source while it is being added, the refresh must stop either way.
+    var wg sync.WaitGroup
+    wg.Add(1)
+    go func() {
+        defer wg.Done()
+        for c.RemoveSource("vault") != nil {
+            time.Sleep(time.Microsecond)
+        }
+    }()
+    if err := c.AddSource("vault", fetch, time.Millisecond); err != nil {
+        t.Fatal(err)
+    }
+    wg.Wait()
+
+    time.Sleep(10 * time.Millisecond)
+    stopped := fetches.Load()
+    time.Sleep(20 * time.Millisecond)
+    if got := fetches.Load(); got != stopped {
+        t.Errorf("source fetched %d times after RemoveSource", got-stopped)
+    }
+}
@@ -0,0 +1,130 @@
+package cfg
+
+import (
+    "strings"
+)
+
+// Enables strict key checking. When on, reading or merging a config that holds keys
+// with no registered default and not allowed with AllowKeys fails with an
+// UnknownKeysError, and the previous config stays in effect.
+func SetStrictKeys(strict bool) { c.SetStrictKeys(strict) }
+func (c *Config) SetStrictKeys(strict bool) {
+    c.strictKeys = strict
+}
+
+// Allows keys in the config that have no default. Allowing a key allows everything
+// beneath it.
+func AllowKeys(keys ...string) { c.AllowKeys(keys...) }
+func (c *Config) AllowKeys(keys ...string) {
+    for _, key := range keys {
+        key = c.normalizeKey(key)
+        if !stringInSlice(key, c.allowedKeys) {
+            c.allowedKeys = append(c.allowedKeys, key)
+        }
+    }
+}
+
+// Registers a function called with the unknown keys of every config read or merged,
+// whether or not strict key checking is on, so they can be logged as warnings.
+func OnUnknownKeys(run func(keys []string)) { c.OnUnknownKeys(run) }
+func (c *Config) OnUnknownKeys(run func(keys []string)) {
+    c.onUnknownKeys = append(c.onUnknownKeys, run)
+}
+
+// checkUnknownKeys reports the keys of config that are neither defaulted nor allowed.
+func (c *Config) checkUnknownKeys(config map[string]interface{}) error {
+    if !c.strictKeys && len(c.onUnknownKeys) == 0 {
+        return nil
+    }
+
+    c.mu.RLock()
+    unknown := c.unknownKeys(config)
+    c.mu.RUnlock()
+    if len(unknown) == 0 {
+        return nil
+    }
+
+    for _, run := range c.onUnknownKeys {
+        run(unknown)
+    }
+
+    if c.strictKeys {
+        return UnknownKeysError(unknown)
+    }
+
+    return nil
+}
+
+// unknownKeys returns the sorted paths of the leaves of config that are neither
+// defaulted nor allowed.
+func (c *Config) unknownKeys(config map[string]interface{}) []string {
+    var unknown []string
+
+    for _, l := range flattenLeaves(normalizeMaps(config).(map[string]interface{}), nil) {
+        if !c.knownKey(l.path) {
+            unknown = append(unknown, strings.Join(l.path, c.keyDelm))
+        }
+    }
+
+    return unknown
+}
+
+// knownKey reports whether path, or any path above it, has a default or is allowed.
+// Caller must hold mu.
+func (c *Config) knownKey(path []string) bool {
+    for i := len(path); i > 0; i-- {
+        key := c.realKey(strings.Join(path[:i], c.keyDelm))
+
+        if _, exists := c.searchLayer(c.defaults, key); exists {
+            return true
+        }
+        if stringInSlice(key, c.allowedKeys) || stringInSlice(strings.Join(path[:i], c.keyDelm), c.allowedKeys) {
+            return true
+        }
+    }
+
+    return false
+}
+
+// Enables converting values to the type of the default of their key, so a key defaulting
+// to 8080 reads as an int even when set to "8080" in an environment variable. Besides
+// the scalar types of the getters, values are converted to maps such as
+// map[string]int, to slices of strings, integers, floats, booleans and durations, and,
+// once a decode hook is registered with RegisterDecodeHook, to any other type. Get
+// returns the zero value of the type of the default for a value that cannot be
+// converted, see SetStrictTypes for reporting it.
+func SetTypeByDefaultValue(enable bool) { c.SetTypeByDefaultValue(enable) }
+func (c *Config) SetTypeByDefaultValue(enable bool) {
+    c.typeByDefValue = enable
+    c.invalidate()
+}
+
+// Enables strict typing. When on, every value with a default is converted to the type of
+// its default, as with SetTypeByDefaultValue, and reading or merging a config holding a value
+// that cannot be converted fails with a TypeMismatchError. GetE reports the same error.
+func SetStrictTypes(strict bool) { c.SetStrictTypes(strict) }
+func (c *Config) SetStrictTypes(strict bool) {
+    c.strictTypes = strict
+    c.invalidate()
+}
+
+// checkTypes reports the first defaulted key, in sorted order, whose value in cand
+// cannot be converted to the type of its default.
+func (c *Config) checkTypes(cand *Config) error {
+    if !c.strictTypes {
+        return nil
+    }
+
+    c.mu.RLock()
+    defaults := normalizeMaps(c.defaults).(map[string]interface{})
+    c.mu.RUnlock()
+
+    for _, l := range flattenLeaves(defaults, nil) {
+        _, err := cand.GetE(strings.Join(l.path, c.keyDelm))
+        if _, mismatch := err.(TypeMismatchError); mismatch {
+            return err
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,192 @@
+package cfg
+
+import (
+    "fmt"
+    "net/url"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// Denotes a decoded field whose value breaks a rule of its validate tag.
+type FieldError struct {
+    // Config key of the field, such as "database.port".
+    Key string
+
+    // Rule that failed, such as "min=1".
+    Rule string
+
+    Value interface{}
+}
+
+// Returns the formatted field error.
+func (fe FieldError) Error() string {
+    return fmt.Sprintf("%s: value %v does not satisfy %q", fe.Key, fe.Value, fe.Rule)
+}
+
+// Denotes every field that failed validation after unmarshalling.
+type FieldErrors []FieldError
+
+// Returns the formatted field errors, one per line.
+func (fe FieldErrors) Error() string {
+    lines := make([]string, len(fe))
+    for i, e := range fe {
+        lines[i] = e.Error()
+    }
+
+    return fmt.Sprintf("Invalid config:\n%s", strings.Join(lines, "\n"))
+}
+
+// Replaces the validation run after Unmarshal and UnmarshalKey decode into a struct,
+// for instance to hand the struct to an external validation library. Passing nil
+// restores the built-in validate tag checks.
+//
+// The built-in checks read comma separated rules from the validate tag of each field:
+// required, min=N and max=N (bounds on numbers, or on the length of strings, slices
+// and maps), url, and oneof=a b c.
+func SetStructValidator(check func(v interface{}) error) { c.SetStructValidator(check) }
+func (c *Config) SetStructValidator(check func(v interface{}) error) {
+    c.structValidator = check
+}
+
+// validateStruct checks the struct rawVal points to, prefix is the key it was
+// decoded from.
+func (c *Config) validateStruct(rawVal interface{}, prefix string) error {
+    if c.structValidator != nil {
+        return c.structValidator(rawVal)
+    }
+
+    var errs FieldErrors
+    c.checkValue(reflect.ValueOf(rawVal), prefix, &errs)
+    if len(errs) > 0 {
+        return errs
+    }
+
+    return nil
+}
+
+func (c *Config) checkValue(v reflect.Value, key string, errs *FieldErrors) {
+    for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+        if v.IsNil() {
+            return
+        }
+        v = v.Elem()
+    }
+
+    switch v.Kind() {
+    case reflect.Struct:
+        t := v.Type()
+        for i := 0; i < t.NumField(); i++ {
+            field := t.Field(i)
+            if field.PkgPath != "" {
+                continue
+            }
+
+            fieldKey := c.joinKey(key, c.fieldName(field))
+            if field.Anonymous && field.Tag.Get(c.getTagName()) == "" {
+                fieldKey = key
+            }
+
+            if rules := field.Tag.Get("validate"); rules != "" {
+                for _, rule := range strings.Split(rules, ",") {
+                    if rule = strings.TrimSpace(rule); rule != "" && !checkRule(v.Field(i), rule) {
+                        *errs = append(*errs, FieldError{fieldKey, rule, v.Field(i).Interface()})
+                    }
+                }
+            }
+
+            c.checkValue(v.Field(i), fieldKey, errs)
+        }
+
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            c.checkValue(v.Index(i), fmt.Sprintf("%s[%d]", key, i), errs)
+        }
+
+    case reflect.Map:
+        for _, k := range v.MapKeys() {
+            c.checkValue(v.MapIndex(k), c.joinKey(key, fmt.Sprint(k.Interface())), errs)
+        }
+    }
+}
+
+func (c *Config) joinKey(prefix, key string) string {
+    if prefix == "" {
+        return key
+    }
+    return prefix + c.keyDelm + key
+}
+
+// fieldName returns the key a struct field is decoded from.
+func (c *Config) fieldName(field reflect.StructField) string {
+    if tag := strings.Split(field.Tag.Get(c.getTagName()), ",")[0]; tag != "" {
+        return tag
+    }
+    return c.normalizeKey(field.Name)
+}
+
+// checkRule reports whether v satisfies a single validate rule. Unknown rules pass.
+func checkRule(v reflect.Value, rule string) bool {
+    name, arg := rule, ""
+    if i := strings.Index(rule, "="); i >= 0 {
+        name, arg = rule[:i], rule[i+1:]
+    }
+
+    for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+        if v.IsNil() {
+            return name != "required"
+        }
+        v = v.Elem()
+    }
+
+    switch name {
+    case "required":
+        return !v.IsZero()
+
+    case "min", "max":
+        bound, err := strconv.ParseFloat(arg, 64)
+        if err != nil {
+            return false
+        }
+
+        var n float64
+        switch v.Kind() {
+        case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+            n = float64(v.Int())
+        case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+            n = float64(v.Uint())
+        case reflect.Float32, reflect.Float64:
+            n = v.Float()
+        case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+            n = float64(v.Len())
+        default:
+            return true
+        }
+
+        if name == "min" {
+            return n >= bound
+        }
+        return n <= bound
+
+    case "url":
+        if v.Kind() != reflect.String {
+            return true
+        }
+        if v.Len() == 0 {
+            return true
+        }
+        u, err := url.Parse(v.String())
+        return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+
+    case "oneof":
+        s := fmt.Sprint(v.Interface())
+        for _, option := range strings.Fields(arg) {
+            if s == option {
+                return true
+            }
+        }
+        return false
+    }
+
+    return true
+}
@@ -0,0 +1,65 @@
+package cfg
+
+import (
+    "bytes"
+    "fmt"
+    "io/ioutil"
+    "os"
+    "strings"
+    "text/template"
+)
+
+// Enables rendering config files, the drop-in files and profiles merged into them, as
+// text/template templates before they are parsed, so a file can compute or leave out
+// settings:
+//
+//     listen: {{ env "HOST" | default "0.0.0.0" }}:8080
+//     {{ if eq hostname "db1" }}primary: true{{ end }}
+//     tls_cert: {{ file "/etc/ssl/cert.pem" | printf "%q" }}
+//
+// Besides the builtins of text/template, templates may only call env, which returns an
+// environment variable, hostname, default, which returns its second argument or the
+// first if the second is empty, and file, which returns the contents of a file.
+// Templates are rendered after files are decrypted and their signatures verified.
+func SetTemplating(enable bool) { c.SetTemplating(enable) }
+func (c *Config) SetTemplating(enable bool) {
+    c.templating = enable
+}
+
+// Functions config templates may call.
+var templateFuncs = template.FuncMap{
+    "env": os.Getenv,
+    "hostname": func() (string, error) {
+        return os.Hostname()
+    },
+    "default": func(def, val interface{}) interface{} {
+        if val == nil || val == "" {
+            return def
+        }
+        return val
+    },
+    "file": func(filename string) (string, error) {
+        b, err := ioutil.ReadFile(filename)
+        return strings.TrimSuffix(string(b), "\n"), err
+    },
+}
+
+// renderFile returns data, read from filename, rendered as a template if templating is
+// enabled.
+func (c *Config) renderFile(filename string, data []byte) ([]byte, error) {
+    if !c.templating {
+        return data, nil
+    }
+
+    tmpl, err := template.New(filename).Option("missingkey=error").Funcs(templateFuncs).Parse(string(data))
+    if err != nil {
+        return nil, fmt.Errorf("Cannot parse config template %q: %w", filename, err)
+    }
+
+    buf := new(bytes.Buffer)
+    if err := tmpl.Execute(buf, nil); err != nil {
+        return nil, fmt.Errorf("Cannot render config template %q: %w", filename, err)
+    }
+
+    return buf.Bytes(), nil
+}
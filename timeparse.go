@@ -0,0 +1,40 @@
+package cfg
+
+import (
+    "strings"
+    "time"
+
+    "github.com/spf13/cast"
+)
+
+// Sets layouts, in the form accepted by time.Parse, that GetTime tries before the
+// formats it understands by default. Layouts are tried in the order given.
+func SetTimeLayouts(layouts ...string) { c.SetTimeLayouts(layouts...) }
+func (c *Config) SetTimeLayouts(layouts ...string) {
+    c.timeLayouts = layouts
+}
+
+// Sets the location GetTime interprets timestamps without a zone in, UTC by default.
+func SetTimeLocation(loc *time.Location) { c.SetTimeLocation(loc) }
+func (c *Config) SetTimeLocation(loc *time.Location) {
+    c.timeLocation = loc
+}
+
+// toTimeE casts val to a time, trying the configured layouts first.
+func (c *Config) toTimeE(val interface{}) (time.Time, error) {
+    loc := c.timeLocation
+    if loc == nil {
+        loc = time.UTC
+    }
+
+    if s, ok := val.(string); ok {
+        s = strings.TrimSpace(s)
+        for _, layout := range c.timeLayouts {
+            if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+                return t, nil
+            }
+        }
+    }
+
+    return cast.ToTimeInDefaultLocationE(val, loc)
+}
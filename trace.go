@@ -0,0 +1,52 @@
+package cfg
+
+import (
+    "context"
+    "fmt"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/trace"
+    "go.opentelemetry.io/otel/trace/noop"
+)
+
+// Name of the tracer spans are started with.
+const tracerName = "github.com/nwlucas/cfg"
+
+// Traces reading the config file, fetching sources and remote configs, and unmarshalling
+// with spans from tp, so slow config backends show up in startup traces. A nil tp turns
+// tracing off again, which is the default.
+func SetTracerProvider(tp trace.TracerProvider) { c.SetTracerProvider(tp) }
+func (c *Config) SetTracerProvider(tp trace.TracerProvider) {
+    if tp == nil {
+        c.tracer.Store(nil)
+        return
+    }
+
+    t := tp.Tracer(tracerName)
+    c.tracer.Store(&t)
+}
+
+// startSpan starts a span named name beneath the span in ctx.
+func (c *Config) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+    t := c.tracer.Load()
+    if t == nil {
+        return ctx, noop.Span{}
+    }
+
+    return (*t).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, recording err as its status.
+func endSpan(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
+
+// targetType returns the type name of an unmarshal target for span attributes.
+func targetType(rawVal interface{}) attribute.KeyValue {
+    return attribute.String("cfg.target", fmt.Sprintf("%T", rawVal))
+}
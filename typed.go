@@ -0,0 +1,301 @@
+package cfg
+
+import (
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net"
+    "net/netip"
+    "net/url"
+    "regexp"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/spf13/cast"
+)
+
+// Denotes a value that cannot be parsed into the type requested of it.
+type ValueError struct {
+    Key   string
+    Value interface{}
+    err   error
+}
+
+// Returns the formatted value error.
+func (ve ValueError) Error() string {
+    return fmt.Sprintf("Invalid value %#v for %q: %s", ve.Value, ve.Key, ve.err.Error())
+}
+
+// Returns the error reported by the parser.
+func (ve ValueError) Unwrap() error {
+    return ve.err
+}
+
+// Returns the value associated with the key as an absolute URL, nil if it is unset or
+// invalid.
+func GetURL(key string) *url.URL { return c.GetURL(key) }
+func (c *Config) GetURL(key string) *url.URL {
+    u, _ := c.GetURLE(key)
+    return u
+}
+
+// Like GetURL, but returns a ValueError if the value is not an absolute URL.
+func GetURLE(key string) (*url.URL, error) { return c.GetURLE(key) }
+func (c *Config) GetURLE(key string) (*url.URL, error) {
+    if u, ok := c.Get(key).(*url.URL); ok {
+        return u, nil
+    }
+
+    s, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    u, err := url.Parse(s)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+    if u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+        return nil, ValueError{c.normalizeKey(key), val, errors.New("Not an absolute URL")}
+    }
+
+    return u, nil
+}
+
+// Returns the value associated with the key as an IP address, nil if it is unset or
+// invalid.
+func GetIP(key string) net.IP { return c.GetIP(key) }
+func (c *Config) GetIP(key string) net.IP {
+    ip, _ := c.GetIPE(key)
+    return ip
+}
+
+// Like GetIP, but returns a ValueError if the value is not an IP address.
+func GetIPE(key string) (net.IP, error) { return c.GetIPE(key) }
+func (c *Config) GetIPE(key string) (net.IP, error) {
+    s, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    ip := net.ParseIP(s)
+    if ip == nil {
+        return nil, ValueError{c.normalizeKey(key), val, errors.New("Not an IP address")}
+    }
+
+    return ip, nil
+}
+
+// Returns the value associated with the key as an IP network in CIDR notation, nil if
+// it is unset or invalid.
+func GetCIDR(key string) *net.IPNet { return c.GetCIDR(key) }
+func (c *Config) GetCIDR(key string) *net.IPNet {
+    n, _ := c.GetCIDRE(key)
+    return n
+}
+
+// Like GetCIDR, but returns a ValueError if the value is not in CIDR notation.
+func GetCIDRE(key string) (*net.IPNet, error) { return c.GetCIDRE(key) }
+func (c *Config) GetCIDRE(key string) (*net.IPNet, error) {
+    s, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    _, n, err := net.ParseCIDR(s)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return n, nil
+}
+
+// Returns the value associated with the key as a netip.Addr, the zero Addr if it is
+// unset or invalid.
+func GetAddr(key string) netip.Addr { return c.GetAddr(key) }
+func (c *Config) GetAddr(key string) netip.Addr {
+    a, _ := c.GetAddrE(key)
+    return a
+}
+
+// Like GetAddr, but returns a ValueError if the value is not an IP address.
+func GetAddrE(key string) (netip.Addr, error) { return c.GetAddrE(key) }
+func (c *Config) GetAddrE(key string) (netip.Addr, error) {
+    s, val, err := c.getText(key)
+    if err != nil {
+        return netip.Addr{}, err
+    }
+
+    a, err := netip.ParseAddr(s)
+    if err != nil {
+        return netip.Addr{}, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return a, nil
+}
+
+// Returns the value associated with the key as a netip.Prefix, the zero Prefix if it
+// is unset or invalid.
+func GetPrefix(key string) netip.Prefix { return c.GetPrefix(key) }
+func (c *Config) GetPrefix(key string) netip.Prefix {
+    p, _ := c.GetPrefixE(key)
+    return p
+}
+
+// Like GetPrefix, but returns a ValueError if the value is not in CIDR notation.
+func GetPrefixE(key string) (netip.Prefix, error) { return c.GetPrefixE(key) }
+func (c *Config) GetPrefixE(key string) (netip.Prefix, error) {
+    s, val, err := c.getText(key)
+    if err != nil {
+        return netip.Prefix{}, err
+    }
+
+    p, err := netip.ParsePrefix(s)
+    if err != nil {
+        return netip.Prefix{}, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return p, nil
+}
+
+// Selects how GetBytes decodes a value.
+type Encoding int
+
+const (
+    // The bytes of the string itself.
+    EncodingRaw Encoding = iota
+
+    // Standard or URL-safe base64, padded or not.
+    EncodingBase64
+
+    // Hexadecimal digits.
+    EncodingHex
+)
+
+// Returns the value associated with the key decoded as binary data, nil if it is unset
+// or cannot be decoded.
+func GetBytes(key string, enc Encoding) []byte { return c.GetBytes(key, enc) }
+func (c *Config) GetBytes(key string, enc Encoding) []byte {
+    b, _ := c.GetBytesE(key, enc)
+    return b
+}
+
+// Like GetBytes, but returns a ValueError if the value cannot be decoded.
+func GetBytesE(key string, enc Encoding) ([]byte, error) { return c.GetBytesE(key, enc) }
+func (c *Config) GetBytesE(key string, enc Encoding) ([]byte, error) {
+    if b, ok := c.Get(key).([]byte); ok {
+        return b, nil
+    }
+
+    s, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    var b []byte
+    switch enc {
+    case EncodingBase64:
+        b, err = decodeBase64(s)
+    case EncodingHex:
+        b, err = hex.DecodeString(s)
+    default:
+        // Raw values are kept byte for byte, surrounding whitespace included.
+        b = []byte(cast.ToString(val))
+    }
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return b, nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+    var err error
+    for _, enc := range []*base64.Encoding{
+        base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding,
+    } {
+        var b []byte
+        if b, err = enc.DecodeString(s); err == nil {
+            return b, nil
+        }
+    }
+
+    return nil, err
+}
+
+// Returns the time zone named by the value associated with the key, such as
+// "America/New_York", "UTC" or "Local", a KeyNotFoundError if it is unset, or a
+// ValueError if it is not a known IANA zone.
+func GetLocation(key string) (*time.Location, error) { return c.GetLocation(key) }
+func (c *Config) GetLocation(key string) (*time.Location, error) {
+    if loc, ok := c.Get(key).(*time.Location); ok {
+        return loc, nil
+    }
+
+    name, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    loc, err := time.LoadLocation(name)
+    if err != nil {
+        return nil, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return loc, nil
+}
+
+type regexpCache struct {
+    mu       sync.Mutex
+    compiled map[string]*regexp.Regexp
+}
+
+// Returns the value associated with the key compiled as a regular expression, a
+// KeyNotFoundError if it is unset, or a ValueError if it does not compile. The compiled expression is cached
+// per key until the value changes, so calling GetRegexp on every request is cheap.
+func GetRegexp(key string) (*regexp.Regexp, error) { return c.GetRegexp(key) }
+func (c *Config) GetRegexp(key string) (*regexp.Regexp, error) {
+    pattern, val, err := c.getText(key)
+    if err != nil {
+        return nil, err
+    }
+
+    key = c.normalizeKey(key)
+
+    c.regexps.mu.Lock()
+    defer c.regexps.mu.Unlock()
+
+    if re, ok := c.regexps.compiled[key]; ok && re.String() == pattern {
+        return re, nil
+    }
+
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        return nil, ValueError{key, val, err}
+    }
+
+    if c.regexps.compiled == nil {
+        c.regexps.compiled = make(map[string]*regexp.Regexp)
+    }
+    c.regexps.compiled[key] = re
+
+    return re, nil
+}
+
+// getText returns the value of key as a trimmed string along with the raw value, which
+// is nil if the key is unset.
+func (c *Config) getText(key string) (string, interface{}, error) {
+    val, err := c.lookupE(key)
+    if err != nil {
+        return "", val, err
+    }
+
+    s, err := cast.ToStringE(val)
+    if err != nil {
+        return "", val, ValueError{c.normalizeKey(key), val, err}
+    }
+
+    return strings.TrimSpace(s), val, nil
+}
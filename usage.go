@@ -0,0 +1,157 @@
+package cfg
+
+import (
+    "reflect"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "github.com/spf13/cast"
+)
+
+// Returns the keys of the loaded config file that were never read through Get, the
+// getters or Unmarshal, in order. A key counts as read when it or a key above it was
+// read, so unused keys point at dead configuration and typos.
+func UnusedKeys() []string { return c.UnusedKeys() }
+func (c *Config) UnusedKeys() []string {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    var unused []string
+    c.flatten(c.config, "", func(key string, _ interface{}) {
+        if !c.wasRead(key) && !c.wasRead(c.realKey(key)) {
+            unused = append(unused, key)
+        }
+    })
+    sort.Strings(unused)
+
+    return unused
+}
+
+// Access statistics of a key, see AccessStats.
+type KeyStats struct {
+    // Number of times the key was read
+    Reads uint64
+
+    LastRead time.Time
+
+    // Name of the layer the last read found the key in, empty when that read was by
+    // unmarshalling or found nothing
+    Layer string
+}
+
+// accessStats accumulates the KeyStats of a key.
+type accessStats struct {
+    mu    sync.Mutex
+    stats KeyStats
+}
+
+// Turns access statistics on or off. While on, every read of a key is counted, see
+// AccessStats. Turning them off keeps the statistics gathered so far.
+func SetAccessStats(on bool) { c.SetAccessStats(on) }
+func (c *Config) SetAccessStats(on bool) {
+    c.accessStatsOn.Store(on)
+}
+
+// Returns the access statistics of every key read while they were on, by key.
+func AccessStats() map[string]KeyStats { return c.AccessStats() }
+func (c *Config) AccessStats() map[string]KeyStats {
+    stats := make(map[string]KeyStats)
+    c.accessStats.Range(func(key, val interface{}) bool {
+        as := val.(*accessStats)
+        as.mu.Lock()
+        stats[key.(string)] = as.stats
+        as.mu.Unlock()
+        return true
+    })
+
+    return stats
+}
+
+// Clears the access statistics gathered so far.
+func ResetAccessStats() { c.ResetAccessStats() }
+func (c *Config) ResetAccessStats() {
+    c.accessStats.Range(func(key, _ interface{}) bool {
+        c.accessStats.Delete(key)
+        return true
+    })
+}
+
+// recordRead notes that key was read from layer, empty when it is not known.
+func (c *Config) recordRead(key, layer string) {
+    if _, ok := c.reads.Load(key); !ok {
+        c.reads.Store(key, struct{}{})
+    }
+
+    if !c.accessStatsOn.Load() {
+        return
+    }
+
+    val, ok := c.accessStats.Load(key)
+    if !ok {
+        val, _ = c.accessStats.LoadOrStore(key, &accessStats{})
+    }
+
+    as := val.(*accessStats)
+    as.mu.Lock()
+    as.stats.Reads++
+    as.stats.LastRead = time.Now()
+    as.stats.Layer = layer
+    as.mu.Unlock()
+}
+
+// recordFields notes that every key rawVal has a field for was read.
+func (c *Config) recordFields(rawVal interface{}) {
+    t := reflect.TypeOf(rawVal)
+    for t != nil && t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    if t == nil || t.Kind() != reflect.Struct {
+        return
+    }
+
+    c.walkStruct(t, nil, func(path []string, _ reflect.StructField) {
+        c.recordRead(strings.Join(path, c.keyDelm), "")
+    })
+}
+
+// wasRead reports whether key or a key above it was read.
+func (c *Config) wasRead(key string) bool {
+    path := strings.Split(key, c.keyDelm)
+    for i := len(path); i > 0; i-- {
+        if _, ok := c.reads.Load(strings.Join(path[:i], c.keyDelm)); ok {
+            return true
+        }
+    }
+
+    return false
+}
+
+// countKeys returns the number of leaves of m, see flatten.
+func (c *Config) countKeys(m map[string]interface{}) int {
+    n := 0
+    c.flatten(m, "", func(string, interface{}) { n++ })
+    return n
+}
+
+// flatten calls fn with the full key and value of every leaf of m, descending into
+// nested maps. Empty maps count as leaves.
+func (c *Config) flatten(m map[string]interface{}, prefix string, fn func(key string, val interface{})) {
+    for k, v := range m {
+        key := k
+        if prefix != "" {
+            key = prefix + c.keyDelm + k
+        }
+
+        switch v.(type) {
+        case map[string]interface{}, map[interface{}]interface{}:
+            if sub := cast.ToStringMap(v); len(sub) > 0 {
+                c.flatten(sub, key, fn)
+                continue
+            }
+        }
+
+        fn(key, v)
+    }
+}
@@ -2,22 +2,30 @@ package cfg
 
 import (
     "bytes"
+    "encoding/json"
+    "errors"
     "fmt"
     "io"
+    "math"
+    "math/bits"
     "os"
     "path/filepath"
+    "regexp"
     "runtime"
+    "strconv"
     "strings"
+    "time"
     "unicode"
 
     "gopkg.in/yaml.v2"
 
     "github.com/BurntSushi/toml"
     "github.com/spf13/cast"
-    jww "github.com/spf13/jwalterweatherman"
 )
 
 // Denotes failing to parse configuration file.
+//
+// Deprecated: decode failures are reported as ParseError.
 type ConfigParseError struct {
     err error
 }
@@ -27,14 +35,175 @@ func (pe ConfigParseError) Error() string {
     return fmt.Sprintf("While parsing config: %s", pe.err.Error())
 }
 
+// Returns the decoder error.
+func (pe ConfigParseError) Unwrap() error {
+    return pe.err
+}
+
+// Denotes a configuration document that cannot be decoded.
+type ParseError struct {
+    // File the document was read from, empty when it was not read from a file.
+    File string
+
+    // Line the decoder failed at, 0 when it is not known.
+    Line int
+
+    Err error
+}
+
+// Returns the formatted parse error.
+func (pe ParseError) Error() string {
+    where := "config"
+    if pe.File != "" {
+        where = fmt.Sprintf("%q", pe.File)
+    }
+    if pe.Line > 0 {
+        where = fmt.Sprintf("%s line %d", where, pe.Line)
+    }
+
+    return fmt.Sprintf("While parsing %s: %s", where, pe.Err.Error())
+}
+
+// Returns the decoder error.
+func (pe ParseError) Unwrap() error {
+    return pe.Err
+}
+
+// newParseError wraps a decoder error, picking the line it failed at out of it.
+func newParseError(err error) ParseError {
+    pe := ParseError{Err: err}
+
+    var tomlErr toml.ParseError
+    if errors.As(err, &tomlErr) {
+        pe.Line = tomlErr.Position.Line
+    } else if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+        pe.Line, _ = strconv.Atoi(m[1])
+    }
+
+    return pe
+}
+
+// Matches the line number in the errors of the YAML decoder.
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
+// parseErrorIn records the file a ParseError comes from, returning any other error as
+// is.
+func parseErrorIn(err error, file string) error {
+    if pe, ok := err.(ParseError); ok && pe.File == "" {
+        pe.File = file
+        return pe
+    }
+    if le, ok := err.(ConfigLimitError); ok && le.File == "" {
+        le.File = file
+        return le
+    }
+    if tle, ok := err.(ConfigTooLargeError); ok && tle.File == "" {
+        tle.File = file
+        return tle
+    }
+
+    return err
+}
+
+// insensitiviseMap lowercases every key of m, including those of nested maps, so any
+// path into the map can be looked up case insensitively.
 func insensitiviseMap(m map[string]interface{}) {
+    canonicalizeMap(m, strings.ToLower)
+}
+
+// canonicalizeMap turns the maps nested in m, in place, into the map[string]interface{}
+// every layer stores, renaming their keys and those of m with rename unless it is nil.
+// Maps within slices are left as decoded. Layers are canonicalized once when they are
+// stored, so reading and merging them never has to convert or copy their maps again.
+func canonicalizeMap(m map[string]interface{}, rename func(string) string) {
     for key, val := range m {
-        lower := strings.ToLower(key)
-        if key != lower {
-            delete(m, key)
-            m[lower] = val
+        switch v := val.(type) {
+        case map[interface{}]interface{}:
+            sm := make(map[string]interface{}, len(v))
+            for k, e := range v {
+                sm[cast.ToString(k)] = e
+            }
+            canonicalizeMap(sm, rename)
+            val = sm
+        case map[string]interface{}:
+            canonicalizeMap(v, rename)
+        }
+
+        if rename != nil {
+            if rk := rename(key); rk != key {
+                delete(m, key)
+                key = rk
+            }
+        }
+        m[key] = val
+    }
+}
+
+// toStringMap returns v as a map[string]interface{}, v itself if it is one already.
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+    switch m := v.(type) {
+    case map[string]interface{}:
+        return m, true
+    case map[interface{}]interface{}:
+        return normalizeMaps(m).(map[string]interface{}), true
+    }
+
+    return nil, false
+}
+
+// copyMap returns a shallow copy of m.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+    cp := make(map[string]interface{}, len(m))
+    for k, v := range m {
+        cp[k] = v
+    }
+
+    return cp
+}
+
+// toFloat64Slice casts every element of a slice to float64, returning nil if v is not a
+// slice or an element cannot be cast.
+func toFloat64Slice(v interface{}) []float64 {
+    s, _ := toFloat64SliceE(v)
+    return s
+}
+
+func toFloat64SliceE(v interface{}) ([]float64, error) {
+    elems, ok := toSlice(v)
+    if !ok {
+        return nil, fmt.Errorf("Unable to cast %#v of type %T to []float64", v, v)
+    }
+
+    s := make([]float64, len(elems))
+    for i, e := range elems {
+        f, err := cast.ToFloat64E(e)
+        if err != nil {
+            return nil, err
         }
+        s[i] = f
     }
+
+    return s, nil
+}
+
+// toDurationSliceE casts every element of a slice to a duration with toDuration,
+// reporting the first element that cannot be cast.
+func toDurationSliceE(v interface{}, toDuration func(interface{}) (time.Duration, error)) ([]time.Duration, error) {
+    elems, ok := toSlice(v)
+    if !ok {
+        return nil, fmt.Errorf("Unable to cast %#v of type %T to []time.Duration", v, v)
+    }
+
+    s := make([]time.Duration, len(elems))
+    for i, e := range elems {
+        d, err := toDuration(e)
+        if err != nil {
+            return nil, fmt.Errorf("Element %d: %s", i, err.Error())
+        }
+        s[i] = d
+    }
+
+    return s, nil
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -46,8 +215,13 @@ func stringInSlice(a string, list []string) bool {
     return false
 }
 
-func absPathify(inPath string) string {
-    jww.INFO.Println("Trying to resolve absolute path to", inPath)
+func (c *Config) absPathify(inPath string) string {
+    // Paths on a filesystem set with WithFS are relative to its root.
+    if c.fsys != nil {
+        return c.fsPath(inPath)
+    }
+
+    c.logInfo("Trying to resolve absolute path to", inPath)
 
     if strings.HasPrefix(inPath, "$HOME") {
         inPath = userHomeDir() + inPath[5:]
@@ -66,8 +240,8 @@ func absPathify(inPath string) string {
     if err == nil {
         return filepath.Clean(p)
     } else {
-        jww.ERROR.Println("Couldn't discover absolute path")
-        jww.ERROR.Println(err)
+        c.logError("Couldn't discover absolute path")
+        c.logError(err)
     }
     return ""
 }
@@ -125,24 +299,29 @@ func unmarshallConfigReader(in io.Reader, c map[string]interface{}, configType s
     switch strings.ToLower(configType) {
     case "yaml", "yml":
         if err := yaml.Unmarshal(buf.Bytes(), &c); err != nil {
-            return ConfigParseError{err}
+            return newParseError(err)
         }
 
-    // case "json":
-    //     if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
-    //         return ConfigParseError{err}
-    //     }
+    case "json":
+        if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+            pe := newParseError(err)
+            var syntaxErr *json.SyntaxError
+            if errors.As(err, &syntaxErr) {
+                pe.Line = 1 + bytes.Count(buf.Bytes()[:syntaxErr.Offset], []byte("\n"))
+            }
+            return pe
+        }
 
     case "toml":
         if _, err := toml.Decode(buf.String(), &c); err != nil {
-            return ConfigParseError{err}
+            return newParseError(err)
         }
 
         // case "properties", "props", "prop":
         //     var p *properties.Properties
         //     var err error
         //     if p, err = properties.Load(buf.Bytes(), properties.UTF8); err != nil {
-        //         return ConfigParseError{err}
+        //         return newParseError(err)
         //     }
         //     for _, key := range p.Keys() {
         //         value, _ := p.Get(key)
@@ -150,49 +329,142 @@ func unmarshallConfigReader(in io.Reader, c map[string]interface{}, configType s
         //     }
     }
 
-    insensitiviseMap(c)
     return nil
 }
 
-func safeMul(a, b uint) uint {
-    c := a * b
-    if a > 1 && b > 1 && c/b != a {
-        return 0
-    }
-    return c
-}
-
-// parseSizeInBytes converts strings like 1GB or 12 mb into an unsigned integer number of bytes
-func parseSizeInBytes(sizeStr string) uint {
-    sizeStr = strings.TrimSpace(sizeStr)
-    lastChar := len(sizeStr) - 1
-    multiplier := uint(1)
-
-    if lastChar > 0 {
-        if sizeStr[lastChar] == 'b' || sizeStr[lastChar] == 'B' {
-            if lastChar > 1 {
-                switch unicode.ToLower(rune(sizeStr[lastChar-1])) {
-                case 'k':
-                    multiplier = 1 << 10
-                    sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
-                case 'm':
-                    multiplier = 1 << 20
-                    sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
-                case 'g':
-                    multiplier = 1 << 30
-                    sizeStr = strings.TrimSpace(sizeStr[:lastChar-1])
-                default:
-                    multiplier = 1
-                    sizeStr = strings.TrimSpace(sizeStr[:lastChar])
-                }
+func marshalConfig(m map[string]interface{}, configType string) ([]byte, error) {
+    switch strings.ToLower(configType) {
+    case "yaml", "yml":
+        return yaml.Marshal(m)
+
+    case "toml":
+        buf := new(bytes.Buffer)
+        if err := toml.NewEncoder(buf).Encode(m); err != nil {
+            return nil, err
+        }
+        return buf.Bytes(), nil
+
+    case "json":
+        b, err := json.MarshalIndent(normalizeMaps(m), "", "  ")
+        if err != nil {
+            return nil, err
+        }
+        return append(b, '\n'), nil
+    }
+
+    return nil, UnsupportedConfigError(configType)
+}
+
+// Multipliers of the size suffixes understood by parseSizeInBytes, by lowercased suffix.
+var sizeUnits = map[string]uint64{
+    "":    1,
+    "b":   1,
+    "kb":  1e3,
+    "mb":  1e6,
+    "gb":  1e9,
+    "tb":  1e12,
+    "pb":  1e15,
+    "eb":  1e18,
+    "kib": 1 << 10,
+    "mib": 1 << 20,
+    "gib": 1 << 30,
+    "tib": 1 << 40,
+    "pib": 1 << 50,
+    "eib": 1 << 60,
+}
+
+// parseSizeInBytes converts strings like 1GB, 1.5 GiB or 12 mb into a number of bytes.
+// SI suffixes (KB, MB, GB, ...) are powers of 1000 and IEC suffixes (KiB, MiB, GiB, ...)
+// powers of 1024, suffixes are case insensitive.
+func parseSizeInBytes(sizeStr string) uint64 {
+    size, _ := parseSizeInBytesE(sizeStr)
+    return size
+}
+
+// parseSizeInBytesE is parseSizeInBytes reporting sizes that are malformed, negative or
+// overflow a uint64. The zero size is returned alongside the error.
+func parseSizeInBytesE(sizeStr string) (uint64, error) {
+    s := strings.TrimSpace(sizeStr)
+
+    i := strings.IndexFunc(s, func(r rune) bool {
+        return !unicode.IsDigit(r) && r != '.' && r != '-' && r != '+'
+    })
+    if i < 0 {
+        i = len(s)
+    }
+    number, suffix := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+
+    multiplier, ok := sizeUnits[suffix]
+    if !ok || number == "" {
+        return 0, fmt.Errorf("Invalid size %q", sizeStr)
+    }
+
+    if !strings.Contains(number, ".") {
+        n, err := strconv.ParseInt(number, 10, 64)
+        if err != nil {
+            if strings.HasPrefix(number, "-") {
+                return 0, fmt.Errorf("Negative size %q", sizeStr)
+            }
+            u, uerr := strconv.ParseUint(strings.TrimPrefix(number, "+"), 10, 64)
+            if uerr != nil {
+                return 0, fmt.Errorf("Invalid size %q", sizeStr)
+            }
+            if multiplier != 1 {
+                return 0, fmt.Errorf("Size %q overflows uint64", sizeStr)
             }
+            return u, nil
+        }
+        if n < 0 {
+            return 0, fmt.Errorf("Negative size %q", sizeStr)
+        }
+
+        hi, lo := bits.Mul64(uint64(n), multiplier)
+        if hi != 0 {
+            return 0, fmt.Errorf("Size %q overflows uint64", sizeStr)
         }
+        return lo, nil
     }
 
-    size := cast.ToInt(sizeStr)
-    if size < 0 {
-        size = 0
+    f, err := strconv.ParseFloat(number, 64)
+    if err != nil {
+        return 0, fmt.Errorf("Invalid size %q", sizeStr)
+    }
+    if f < 0 {
+        return 0, fmt.Errorf("Negative size %q", sizeStr)
+    }
+
+    size := f * float64(multiplier)
+    if size >= math.MaxUint64 {
+        return 0, fmt.Errorf("Size %q overflows uint64", sizeStr)
+    }
+
+    return uint64(size), nil
+}
+
+// normalizeMaps converts the map[interface{}]interface{} values produced by the YAML
+// decoder into map[string]interface{}, so the result can be marshalled as JSON and
+// merged uniformly.
+func normalizeMaps(v interface{}) interface{} {
+    switch val := v.(type) {
+    case map[interface{}]interface{}:
+        m := make(map[string]interface{}, len(val))
+        for k, e := range val {
+            m[cast.ToString(k)] = normalizeMaps(e)
+        }
+        return m
+    case map[string]interface{}:
+        m := make(map[string]interface{}, len(val))
+        for k, e := range val {
+            m[k] = normalizeMaps(e)
+        }
+        return m
+    case []interface{}:
+        s := make([]interface{}, len(val))
+        for i, e := range val {
+            s[i] = normalizeMaps(e)
+        }
+        return s
     }
 
-    return safeMul(uint(size), multiplier)
+    return v
 }
@@ -0,0 +1,109 @@
+package cfg
+
+import (
+    "fmt"
+)
+
+// Denotes a config rejected by a registered validator.
+type ValidationError struct {
+    err error
+}
+
+// Returns the formatted validation error.
+func (ve ValidationError) Error() string {
+    return fmt.Sprintf("Config rejected: %s", ve.err.Error())
+}
+
+// Returns the error reported by the validator.
+func (ve ValidationError) Unwrap() error {
+    return ve.err
+}
+
+// Registers a check run before every change to the config file layer or a source takes
+// effect: ReadInConfig, the merge functions, AddConfigDir, watched and remote reloads and
+// source refreshes. The snapshot passed in resolves keys exactly as the Config would
+// once the change is in place, so invariants spanning several keys, such as tls.cert
+// requiring tls.key, can be checked. If any check fails the change is rejected with a
+// ValidationError and the previous values stay in effect.
+func RegisterValidator(check func(Snapshot) error) { c.RegisterValidator(check) }
+func (c *Config) RegisterValidator(check func(Snapshot) error) {
+    c.validators = append(c.validators, check)
+}
+
+// validate checks config for unknown keys and mistyped values, and runs every
+// registered validator against c with config in place of the current config layer.
+func (c *Config) validate(config map[string]interface{}) error {
+    if err := c.checkUnknownKeys(config); err != nil {
+        return err
+    }
+
+    return c.validateCandidate(func(cand *Config) {
+        cand.config = config
+    })
+}
+
+// validateSource runs the checks of validate against c with values in place of the
+// current values of the named source.
+func (c *Config) validateSource(name string, values map[string]interface{}) error {
+    return c.validateCandidate(func(cand *Config) {
+        for i, src := range cand.sources {
+            if src.name == name {
+                replaced := *src
+                replaced.values = values
+                cand.sources[i] = &replaced
+            }
+        }
+    })
+}
+
+func (c *Config) validateCandidate(change func(cand *Config)) error {
+    if !c.strictTypes && len(c.validators) == 0 {
+        return nil
+    }
+
+    candidate := c.candidate()
+    change(candidate)
+
+    if err := c.checkTypes(candidate); err != nil {
+        return err
+    }
+
+    for _, check := range c.validators {
+        if err := check(Snapshot{candidate}); err != nil {
+            return ValidationError{err}
+        }
+    }
+
+    return nil
+}
+
+// candidate returns a Config resolving keys exactly as c does.
+func (c *Config) candidate() *Config {
+    cand := New()
+    c.copySettingsTo(cand)
+
+    // The candidate is read without holding mu, so it gets copies of the maps Set,
+    // SetDefault and RegisterAlias change in place.
+    c.mu.RLock()
+    cand.config = c.config
+    cand.defaults = copyMap(c.defaults)
+    cand.overrides = copyMap(c.overrides)
+    cand.computed = copyMap(c.computed)
+    cand.aliases = make(map[string]string, len(c.aliases))
+    for k, v := range c.aliases {
+        cand.aliases[k] = v
+    }
+    cand.deprecations = c.deprecations
+    c.mu.RUnlock()
+
+    cand.onDeprecated = c.onDeprecated
+    cand.required = c.required
+
+    c.sourceMu.RLock()
+    cand.sources = append(cand.sources, c.sources...)
+    cand.customLayers = append(cand.customLayers, c.customLayers...)
+    cand.layerSeq = c.layerSeq
+    c.sourceMu.RUnlock()
+
+    return cand
+}
@@ -0,0 +1,240 @@
+package cfg
+
+import (
+    "bytes"
+    "crypto/ed25519"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "github.com/ProtonMail/go-crypto/openpgp"
+    "github.com/ProtonMail/go-crypto/openpgp/armor"
+    "golang.org/x/crypto/blake2b"
+)
+
+const verifiedDoc = "name: api\n"
+
+// Writes verifiedDoc as config.yaml, with the files in extra next to it, and returns
+// its path.
+func verifiedConfigFile(t *testing.T, extra map[string][]byte) string {
+    t.Helper()
+
+    dir := t.TempDir()
+    file := filepath.Join(dir, "config.yaml")
+    if err := os.WriteFile(file, []byte(verifiedDoc), 0644); err != nil {
+        t.Fatal(err)
+    }
+    for name, b := range extra {
+        if err := os.WriteFile(filepath.Join(dir, name), b, 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    return file
+}
+
+func sha256Hex(s string) string {
+    sum := sha256.Sum256([]byte(s))
+    return hex.EncodeToString(sum[:])
+}
+
+func TestChecksum(t *testing.T) {
+    good, bad := sha256Hex(verifiedDoc), sha256Hex("name: web\n")
+
+    tests := []struct {
+        name    string
+        pin     string
+        require bool
+        sidecar string
+        wantErr bool
+    }{
+        {"pinned", good, false, "", false},
+        {"pinned mismatch", bad, false, "", true},
+        {"pin beats checksum file", good, true, bad + "  config.yaml\n", false},
+        {"checksum file", "", true, good + "  config.yaml\n", false},
+        {"checksum file upper case", "", true, strings.ToUpper(good) + "  config.yaml\n", false},
+        {"checksum file empty", "", true, "\n", true},
+        {"checksum file mismatch", "", true, bad + "  config.yaml\n", true},
+        {"checksum file missing", "", true, "", true},
+        {"not required", "", false, "", false},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            extra := map[string][]byte{}
+            if tt.sidecar != "" {
+                extra["config.yaml"+ChecksumExt] = []byte(tt.sidecar)
+            }
+            file := verifiedConfigFile(t, extra)
+
+            c := New()
+            c.SetConfigFile(file)
+            c.RequireChecksumFile(tt.require)
+            if tt.pin != "" {
+                if err := c.PinChecksum(file, tt.pin); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            err := c.ReadInConfig()
+            var ce ChecksumError
+            if tt.wantErr {
+                if !errors.As(err, &ce) {
+                    t.Errorf("ReadInConfig() = %v, want ChecksumError", err)
+                }
+                if c.IsSet("name") {
+                    t.Error("config read despite checksum error")
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := c.ConfigChecksum(); got != good {
+                t.Errorf("ConfigChecksum() = %s, want %s", got, good)
+            }
+        })
+    }
+}
+
+func TestPinChecksumInvalid(t *testing.T) {
+    if err := New().PinChecksum("config.yaml", "abc"); err == nil {
+        t.Error("PinChecksum(abc) succeeded")
+    }
+}
+
+// A minisign key pair, signing like minisign -S.
+type minisignKey struct {
+    id   [8]byte
+    priv ed25519.PrivateKey
+    pub  ed25519.PublicKey
+}
+
+func newMinisignKey(t *testing.T) minisignKey {
+    t.Helper()
+
+    var k minisignKey
+    var err error
+    if k.pub, k.priv, err = ed25519.GenerateKey(rand.Reader); err != nil {
+        t.Fatal(err)
+    }
+    rand.Read(k.id[:])
+
+    return k
+}
+
+// publicKey returns the key as minisign -G writes it to the .pub file.
+func (k minisignKey) publicKey() []byte {
+    b := append(append([]byte(minisignAlg), k.id[:]...), k.pub...)
+    return []byte("untrusted comment: minisign public key\n" + base64.StdEncoding.EncodeToString(b) + "\n")
+}
+
+// sign returns the signature file of data, prehashed when alg is minisignHashedAlg.
+func (k minisignKey) sign(data []byte, alg string) []byte {
+    if alg == minisignHashedAlg {
+        sum := blake2b.Sum512(data)
+        data = sum[:]
+    }
+
+    sig := ed25519.Sign(k.priv, data)
+    trusted := "timestamp:0"
+    global := ed25519.Sign(k.priv, append(append([]byte(nil), sig...), trusted...))
+
+    return []byte("untrusted comment: signature\n" +
+        base64.StdEncoding.EncodeToString(append(append([]byte(alg), k.id[:]...), sig...)) + "\n" +
+        "trusted comment: " + trusted + "\n" +
+        base64.StdEncoding.EncodeToString(global) + "\n")
+}
+
+// Returns an armored PGP public key and a function signing data with it, armored or not.
+func newPGPKey(t *testing.T) ([]byte, func(data []byte, armored bool) []byte) {
+    t.Helper()
+
+    entity, err := openpgp.NewEntity("cfg", "test", "cfg@example.com", nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var pub bytes.Buffer
+    w, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := entity.Serialize(w); err != nil {
+        t.Fatal(err)
+    }
+    w.Close()
+
+    return pub.Bytes(), func(data []byte, armored bool) []byte {
+        var sig bytes.Buffer
+        sign := openpgp.DetachSign
+        if armored {
+            sign = openpgp.ArmoredDetachSign
+        }
+        if err := sign(&sig, entity, bytes.NewReader(data), nil); err != nil {
+            t.Fatal(err)
+        }
+        return sig.Bytes()
+    }
+}
+
+func TestRequireSignature(t *testing.T) {
+    key, other := newMinisignKey(t), newMinisignKey(t)
+    pgpKey, pgpSign := newPGPKey(t)
+    doc := []byte(verifiedDoc)
+
+    tests := []struct {
+        name    string
+        pubkeys [][]byte
+        sig     []byte
+        wantErr bool
+    }{
+        {"minisign", [][]byte{key.publicKey()}, key.sign(doc, minisignAlg), false},
+        {"minisign prehashed", [][]byte{key.publicKey()}, key.sign(doc, minisignHashedAlg), false},
+        {"minisign rotated key", [][]byte{other.publicKey(), key.publicKey()}, key.sign(doc, minisignAlg), false},
+        {"minisign other key", [][]byte{key.publicKey()}, other.sign(doc, minisignAlg), true},
+        {"minisign tampered", [][]byte{key.publicKey()}, key.sign([]byte("name: web\n"), minisignAlg), true},
+        {"pgp armored", [][]byte{pgpKey}, pgpSign(doc, true), false},
+        {"pgp binary", [][]byte{pgpKey}, pgpSign(doc, false), false},
+        {"pgp tampered", [][]byte{pgpKey}, pgpSign([]byte("name: web\n"), true), true},
+        {"missing", [][]byte{key.publicKey()}, nil, true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            extra := map[string][]byte{}
+            if tt.sig != nil {
+                extra["config.yaml"+SignatureExt] = tt.sig
+            }
+
+            c := New()
+            c.SetConfigFile(verifiedConfigFile(t, extra))
+            for _, pubkey := range tt.pubkeys {
+                if err := c.RequireSignature(pubkey); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            err := c.ReadInConfig()
+            var se SignatureError
+            if tt.wantErr != errors.As(err, &se) {
+                t.Errorf("ReadInConfig() = %v, want SignatureError %v", err, tt.wantErr)
+            }
+            if !tt.wantErr && c.GetString("name") != "api" {
+                t.Errorf("GetString(name) = %q, want api", c.GetString("name"))
+            }
+        })
+    }
+}
+
+func TestRequireSignatureInvalidKey(t *testing.T) {
+    if err := New().RequireSignature([]byte("not a key")); err == nil {
+        t.Error("RequireSignature(not a key) succeeded")
+    }
+}
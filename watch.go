@@ -0,0 +1,236 @@
+package cfg
+
+import (
+    "context"
+    "fmt"
+    "log/slog"
+    "path/filepath"
+    "time"
+
+    "github.com/fsnotify/fsnotify"
+)
+
+// Describes a reload of the config file or a remote config.
+type ChangeEvent struct {
+    // Path of the file or URL of the remote that changed.
+    Name string
+
+    // Operation that triggered the reload, zero for remote configs.
+    Op fsnotify.Op
+
+    // Set when the new file could not be read, parsed or validated. The previous
+    // config is still in effect.
+    Err error
+
+    // Keys the reload added, removed or changed, with the values of secret keys
+    // redacted, see Diff. Empty when Err is set.
+    Changes ConfigDiff
+}
+
+// Registers a function to be called after each reload attempt of a watched config.
+// Handlers run in the order they were registered, on the watching goroutine.
+func OnConfigChange(run func(e ChangeEvent)) { c.OnConfigChange(run) }
+func (c *Config) OnConfigChange(run func(e ChangeEvent)) {
+    c.changeMu.Lock()
+    defer c.changeMu.Unlock()
+
+    c.onChange = append(c.onChange, run)
+}
+
+// beforeReload returns a copy of the config to diff a reload against, nil when no
+// handler would receive the diff.
+func (c *Config) beforeReload() *Config {
+    c.changeMu.Lock()
+    handlers := len(c.onChange)
+    c.changeMu.Unlock()
+
+    if handlers == 0 {
+        return nil
+    }
+    return c.Clone()
+}
+
+// reloaded returns the event reporting a reload of name, diffed against before.
+func (c *Config) reloaded(name string, op fsnotify.Op, before *Config, err error) ChangeEvent {
+    e := ChangeEvent{Name: name, Op: op, Err: err}
+    if err == nil && before != nil {
+        e.Changes = Diff(before, c, WithRedaction())
+    }
+    return e
+}
+
+func (c *Config) notifyChange(e ChangeEvent) {
+    c.changeMu.Lock()
+    handlers := make([]func(ChangeEvent), len(c.onChange))
+    copy(handlers, c.onChange)
+    c.changeMu.Unlock()
+
+    for _, run := range handlers {
+        run(e)
+    }
+}
+
+// Sets the window over which bursts of file events are coalesced into a single reload.
+// Each new event restarts the window. Zero, the default, reloads on every event.
+// Takes effect for watches started afterwards.
+func SetWatchDebounce(d time.Duration) { c.SetWatchDebounce(d) }
+func (c *Config) SetWatchDebounce(d time.Duration) {
+    c.watchDebounce = d
+}
+
+// Watches the config file and re-reads it whenever it changes.
+//
+// The containing directory is watched rather than the file itself so that editors
+// replacing the file by rename, and Kubernetes swapping the ..data symlink of a
+// mounted ConfigMap, are both noticed. Watching stops when the file is removed.
+func WatchConfig() error { return c.WatchConfig() }
+func (c *Config) WatchConfig() error {
+    return c.WatchConfigContext(context.Background())
+}
+
+// Like WatchConfig, but watching stops and the underlying file handles are released
+// once ctx is done.
+func WatchConfigContext(ctx context.Context) error { return c.WatchConfigContext(ctx) }
+func (c *Config) WatchConfigContext(ctx context.Context) error {
+    filename, err := c.getConfigFile()
+    if err != nil {
+        return err
+    }
+    if isURL(filename) {
+        return fmt.Errorf("Cannot watch remote config %q", filename)
+    }
+    if filename == StdinConfigFile {
+        return fmt.Errorf("Cannot watch config read from standard input")
+    }
+
+    watcher, err := fsnotify.NewWatcher()
+    if err != nil {
+        return err
+    }
+
+    configFile := filepath.Clean(filename)
+    configDir, _ := filepath.Split(configFile)
+    realConfigFile, _ := filepath.EvalSymlinks(filename)
+
+    if err := watcher.Add(configDir); err != nil {
+        watcher.Close()
+        return err
+    }
+
+    debounce := c.watchDebounce
+    ctx, done := c.startWatch(ctx)
+
+    go func() {
+        defer done()
+        defer watcher.Close()
+
+        // While a burst of events is being coalesced, fire delivers the reload.
+        var timer *time.Timer
+        var fire <-chan time.Time
+        var pending fsnotify.Event
+
+        for {
+            select {
+            case <-ctx.Done():
+                if timer != nil {
+                    timer.Stop()
+                }
+                return
+
+            case event, ok := <-watcher.Events:
+                if !ok {
+                    return
+                }
+                c.metrics.watchEvents.Add(1)
+
+                // The target of a symlinked config changes when Kubernetes swaps ..data.
+                currentConfigFile, _ := filepath.EvalSymlinks(filename)
+                written := filepath.Clean(event.Name) == configFile &&
+                    event.Op&(fsnotify.Write|fsnotify.Create) != 0
+
+                if written || (currentConfigFile != "" && currentConfigFile != realConfigFile) {
+                    realConfigFile = currentConfigFile
+                    c.logDebug("Config file changed:", event.Name)
+
+                    if debounce <= 0 {
+                        c.reloadWatched(event)
+                        continue
+                    }
+
+                    pending = event
+                    if timer == nil {
+                        timer = time.NewTimer(debounce)
+                    } else {
+                        if !timer.Stop() {
+                            select {
+                            case <-timer.C:
+                            default:
+                            }
+                        }
+                        timer.Reset(debounce)
+                    }
+                    fire = timer.C
+                } else if filepath.Clean(event.Name) == configFile && event.Op&fsnotify.Remove != 0 {
+                    c.logInfo("Config file removed, no longer watching:", event.Name)
+                    return
+                }
+
+            case <-fire:
+                fire = nil
+                c.reloadWatched(pending)
+
+            case err, ok := <-watcher.Errors:
+                if !ok {
+                    return
+                }
+                c.logError("Config watcher error:", err)
+            }
+        }
+    }()
+
+    return nil
+}
+
+// Stops every watch started by WatchConfig, WatchConfigContext and WatchRemoteConfig,
+// returning once all of them have exited and released their resources.
+func StopWatching() { c.StopWatching() }
+func (c *Config) StopWatching() {
+    c.watchMu.Lock()
+    cancels := c.watchCancels
+    c.watchCancels = nil
+    c.watchMu.Unlock()
+
+    for _, cancel := range cancels {
+        cancel()
+    }
+
+    c.watchWG.Wait()
+}
+
+// startWatch derives the context a watch goroutine runs under so StopWatching can end
+// it. The goroutine must call done when it exits.
+func (c *Config) startWatch(parent context.Context) (ctx context.Context, done func()) {
+    ctx, cancel := context.WithCancel(parent)
+
+    c.watchMu.Lock()
+    c.watchCancels = append(c.watchCancels, cancel)
+    c.watchMu.Unlock()
+
+    c.watchWG.Add(1)
+    return ctx, func() {
+        cancel()
+        c.watchWG.Done()
+    }
+}
+
+func (c *Config) reloadWatched(event fsnotify.Event) {
+    c.logInfo("Reloading config file:", event.Name)
+
+    before := c.beforeReload()
+    err := c.ReadInConfig()
+    c.metrics.reloaded(err)
+    if err != nil {
+        c.logEvent(slog.LevelError, "reload_failed", slog.String("name", event.Name), slog.Any("error", err))
+    }
+    c.notifyChange(c.reloaded(event.Name, event.Op, before, err))
+}
@@ -0,0 +1,104 @@
+package cfg
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// Reads a yaml config file holding doc and watches it, sending every reload to the
+// returned channel.
+func watchedConfig(t *testing.T, doc string, debounce time.Duration) (*Config, string, <-chan ChangeEvent) {
+    t.Helper()
+
+    file := filepath.Join(t.TempDir(), "config.yaml")
+    if err := os.WriteFile(file, []byte(doc), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c := New()
+    c.SetConfigFile(file)
+    c.SetWatchDebounce(debounce)
+    if err := c.ReadInConfig(); err != nil {
+        t.Fatal(err)
+    }
+
+    events := make(chan ChangeEvent, 100)
+    c.OnConfigChange(func(e ChangeEvent) { events <- e })
+    if err := c.WatchConfig(); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(c.StopWatching)
+
+    return c, file, events
+}
+
+func nextEvent(t *testing.T, events <-chan ChangeEvent) ChangeEvent {
+    t.Helper()
+
+    select {
+    case e := <-events:
+        return e
+    case <-time.After(5 * time.Second):
+        t.Fatal("no reload within 5s")
+    }
+    return ChangeEvent{}
+}
+
+func TestWatchConfig(t *testing.T) {
+    tests := []struct {
+        name    string
+        doc     string
+        wantErr bool
+        want    string
+    }{
+        {"changed", "name: web\n", false, "web"},
+        {"invalid", "name: [web\n", true, "api"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            // A write may be seen as a truncation and a write, the debounce makes
+            // them one reload.
+            c, file, events := watchedConfig(t, "name: api\n", 50*time.Millisecond)
+
+            if err := os.WriteFile(file, []byte(tt.doc), 0644); err != nil {
+                t.Fatal(err)
+            }
+
+            e := nextEvent(t, events)
+            if (e.Err != nil) != tt.wantErr {
+                t.Errorf("reload error = %v, want error %v", e.Err, tt.wantErr)
+            }
+            if got := c.GetString("name"); got != tt.want {
+                t.Errorf("GetString(name) = %q, want %q", got, tt.want)
+            }
+            if !tt.wantErr && (len(e.Changes.Changed) != 1 || e.Changes.Changed[0].Key != "name") {
+                t.Errorf("reload changes = %+v, want name changed", e.Changes)
+            }
+        })
+    }
+}
+
+func TestWatchConfigDebounce(t *testing.T) {
+    c, file, events := watchedConfig(t, "count: 0\n", 200*time.Millisecond)
+
+    for i := 1; i <= 5; i++ {
+        if err := os.WriteFile(file, []byte(fmt.Sprintf("count: %d\n", i)), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    nextEvent(t, events)
+    if got := c.GetInt("count"); got != 5 {
+        t.Errorf("GetInt(count) = %d, want 5", got)
+    }
+
+    select {
+    case e := <-events:
+        t.Errorf("burst of writes reloaded again: %+v", e)
+    case <-time.After(400 * time.Millisecond):
+    }
+}
@@ -0,0 +1,63 @@
+// Package winreg provides a cfg source backed by a key of the Windows registry, so
+// services installed by MSI or configured through group policy can be configured the
+// way Windows administrators expect.
+//
+// The values of the key become config keys named after them and its subkeys nested
+// maps, so the value Port of HKLM\SOFTWARE\Acme\App\Server is read as server.port.
+// REG_SZ values are strings, REG_EXPAND_SZ strings with their environment variables
+// expanded, REG_DWORD and REG_QWORD integers, REG_MULTI_SZ lists of strings and
+// REG_BINARY byte slices. The default value of a key is ignored.
+package winreg
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Adds the registry key path, such as HKLM\SOFTWARE\Acme\App, as a named source on c.
+//
+// The path starts with its root key, HKLM, HKCU, HKCR, HKU or HKCC, or their long
+// names such as HKEY_LOCAL_MACHINE. A non-zero refresh re-reads the key on that
+// interval so changes are picked up. On other platforms adding the source fails.
+func AddRegistrySource(c *cfg.Config, path string, refresh time.Duration) error {
+    root, sub, err := splitPath(path)
+    if err != nil {
+        return err
+    }
+
+    fetch := func() (map[string]interface{}, error) {
+        return readKey(root, sub)
+    }
+
+    return c.AddSource("winreg:"+path, fetch, refresh)
+}
+
+// Root keys by their short and long names.
+var roots = map[string]string{
+    "HKLM":                "HKLM",
+    "HKEY_LOCAL_MACHINE":  "HKLM",
+    "HKCU":                "HKCU",
+    "HKEY_CURRENT_USER":   "HKCU",
+    "HKCR":                "HKCR",
+    "HKEY_CLASSES_ROOT":   "HKCR",
+    "HKU":                 "HKU",
+    "HKEY_USERS":          "HKU",
+    "HKCC":                "HKCC",
+    "HKEY_CURRENT_CONFIG": "HKCC",
+}
+
+// splitPath splits path into the short name of its root key and the path beneath it.
+func splitPath(path string) (root, sub string, err error) {
+    path = strings.Trim(strings.ReplaceAll(path, "/", `\`), `\`)
+    root, sub, _ = strings.Cut(path, `\`)
+
+    short, ok := roots[strings.ToUpper(root)]
+    if !ok {
+        return "", "", fmt.Errorf("Registry path %q does not start with a root key such as HKLM", path)
+    }
+
+    return short, sub, nil
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package winreg
+
+import (
+    "errors"
+)
+
+func readKey(root, path string) (map[string]interface{}, error) {
+    return nil, errors.New("The Windows registry is only available on Windows")
+}
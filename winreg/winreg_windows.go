@@ -0,0 +1,98 @@
+//go:build windows
+
+package winreg
+
+import (
+    "golang.org/x/sys/windows/registry"
+)
+
+var rootKeys = map[string]registry.Key{
+    "HKLM": registry.LOCAL_MACHINE,
+    "HKCU": registry.CURRENT_USER,
+    "HKCR": registry.CLASSES_ROOT,
+    "HKU":  registry.USERS,
+    "HKCC": registry.CURRENT_CONFIG,
+}
+
+func readKey(root, path string) (map[string]interface{}, error) {
+    k, err := registry.OpenKey(rootKeys[root], path, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+    if err != nil {
+        return nil, err
+    }
+    defer k.Close()
+
+    return readValues(k)
+}
+
+// readValues returns the values of k by name and its subkeys as nested maps.
+func readValues(k registry.Key) (map[string]interface{}, error) {
+    m := make(map[string]interface{})
+
+    names, err := k.ReadValueNames(0)
+    if err != nil {
+        return nil, err
+    }
+    for _, name := range names {
+        if name == "" {
+            continue
+        }
+
+        val, err := readValue(k, name)
+        if err != nil {
+            return nil, err
+        }
+        if val != nil {
+            m[name] = val
+        }
+    }
+
+    subkeys, err := k.ReadSubKeyNames(0)
+    if err != nil {
+        return nil, err
+    }
+    for _, name := range subkeys {
+        sub, err := registry.OpenKey(k, name, registry.QUERY_VALUE|registry.ENUMERATE_SUB_KEYS)
+        if err != nil {
+            return nil, err
+        }
+        vals, err := readValues(sub)
+        sub.Close()
+        if err != nil {
+            return nil, err
+        }
+        m[name] = vals
+    }
+
+    return m, nil
+}
+
+// readValue returns the value name of k, nil for types that have no config equivalent.
+func readValue(k registry.Key, name string) (interface{}, error) {
+    _, valtype, err := k.GetValue(name, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    switch valtype {
+    case registry.SZ:
+        s, _, err := k.GetStringValue(name)
+        return s, err
+    case registry.EXPAND_SZ:
+        s, _, err := k.GetStringValue(name)
+        if err != nil {
+            return nil, err
+        }
+        return registry.ExpandString(s)
+    case registry.DWORD, registry.QWORD:
+        n, _, err := k.GetIntegerValue(name)
+        return n, err
+    case registry.MULTI_SZ:
+        s, _, err := k.GetStringsValue(name)
+        return s, err
+    case registry.BINARY:
+        b, _, err := k.GetBinaryValue(name)
+        return b, err
+    }
+
+    return nil, nil
+}
@@ -0,0 +1,398 @@
+package cfg
+
+import (
+    "fmt"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/pmezard/go-difflib/difflib"
+)
+
+// Writes the current settings back to the config file in use, in its format.
+//
+// Defaults, values read from the config file and overrides are merged by precedence
+// into a single nested document. Values from sources are never written, so secrets
+// fetched at runtime do not end up on disk.
+func WriteConfig(opts ...WriteOption) error { return c.WriteConfig(opts...) }
+func (c *Config) WriteConfig(opts ...WriteOption) error {
+    filename, err := c.writableConfigFile()
+    if err != nil {
+        return err
+    }
+
+    return c.writeConfig(filename, c.getConfigType(), opts)
+}
+
+// writableConfigFile returns the config file in use, failing when it cannot be written.
+func (c *Config) writableConfigFile() (string, error) {
+    filename := c.ConfigFileUsed()
+    if filename == "" {
+        return "", ConfigFileNotFoundError{c.configName, fmt.Sprintf("%s", c.configPaths)}
+    }
+    if isURL(filename) {
+        return "", fmt.Errorf("Cannot write remote config %q", filename)
+    }
+    if filename == StdinConfigFile {
+        return "", fmt.Errorf("Cannot write config read from standard input")
+    }
+
+    return filename, nil
+}
+
+// Adjusts what the write functions emit.
+type WriteOption func(*writeOptions)
+
+type writeOptions struct {
+    onlyChanged bool
+    backup      bool
+}
+
+// Writes only keys whose value differs from the registered default, producing a
+// minimal file that keeps following the defaults as they change.
+func OnlyChanged() WriteOption {
+    return func(o *writeOptions) {
+        o.onlyChanged = true
+    }
+}
+
+// Keeps the previous version of the file as a .bak file next to it.
+func WithBackup() WriteOption {
+    return func(o *writeOptions) {
+        o.backup = true
+    }
+}
+
+// Denotes refusing to overwrite an existing configuration file.
+type ConfigFileAlreadyExistsError string
+
+// Returns the formatted already exists error.
+func (str ConfigFileAlreadyExistsError) Error() string {
+    return fmt.Sprintf("Config File %q Already Exists", string(str))
+}
+
+// Like WriteConfig, but fails with ConfigFileAlreadyExistsError instead of overwriting
+// an existing file. When no config file is in use the file is created in the first
+// config path, named after the config name and type.
+func SafeWriteConfig(opts ...WriteOption) error { return c.SafeWriteConfig(opts...) }
+func (c *Config) SafeWriteConfig(opts ...WriteOption) error {
+    filename := c.ConfigFileUsed()
+    if filename == "" {
+        if len(c.configPaths) == 0 {
+            return ConfigFileNotFoundError{c.configName, fmt.Sprintf("%s", c.configPaths)}
+        }
+        if c.configType == "" {
+            return UnsupportedConfigError("")
+        }
+        filename = filepath.Join(c.configPaths[0], c.configName+"."+c.configType)
+    }
+    if filename == StdinConfigFile {
+        return fmt.Errorf("Cannot write config read from standard input")
+    }
+
+    return c.SafeWriteConfigAs(filename, opts...)
+}
+
+// Like WriteConfigAs, but fails with ConfigFileAlreadyExistsError if the file exists.
+func SafeWriteConfigAs(filename string, opts ...WriteOption) error {
+    return c.SafeWriteConfigAs(filename, opts...)
+}
+func (c *Config) SafeWriteConfigAs(filename string, opts ...WriteOption) error {
+    if b, err := exists(filename); err != nil {
+        return err
+    } else if b {
+        return ConfigFileAlreadyExistsError(filename)
+    }
+
+    return c.writeConfig(filename, c.typeForFile(filename), opts)
+}
+
+// Writes the current settings to filename, overwriting it if it exists. The format is
+// given by the extension of filename, falling back to the type set with SetConfigType,
+// so a config read from TOML can be written out as YAML.
+func WriteConfigAs(filename string, opts ...WriteOption) error {
+    return c.WriteConfigAs(filename, opts...)
+}
+func (c *Config) WriteConfigAs(filename string, opts ...WriteOption) error {
+    return c.writeConfig(filename, c.typeForFile(filename), opts)
+}
+
+// Writes the current settings to filename in the given format, regardless of its extension.
+func WriteConfigAsType(filename, configType string, opts ...WriteOption) error {
+    return c.WriteConfigAsType(filename, configType, opts...)
+}
+func (c *Config) WriteConfigAsType(filename, configType string, opts ...WriteOption) error {
+    return c.writeConfig(filename, configType, opts)
+}
+
+// canonicalType folds the aliases of a config type together.
+func canonicalType(configType string) string {
+    configType = strings.ToLower(configType)
+    if configType == "yml" {
+        return "yaml"
+    }
+    return configType
+}
+
+// typeForFile returns the config type implied by the extension of filename, or the
+// configured type when the extension is not a supported one.
+func (c *Config) typeForFile(filename string) string {
+    filename, _ = c.cipherFor(filename)
+    ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+    if stringInSlice(ext, SupportedExts) {
+        return ext
+    }
+    return c.configType
+}
+
+// writeConfig writes to filename under an exclusive lock when SetFileLocking is enabled.
+func (c *Config) writeConfig(filename, configType string, opts []WriteOption) error {
+    if c.fileLocking {
+        unlock, err := lockConfigFile(filename, true)
+        if err != nil {
+            return err
+        }
+        defer unlock()
+    }
+
+    return c.writeConfigFile(filename, configType, opts)
+}
+
+// writeConfigFile writes to filename, the caller holding any lock needed.
+func (c *Config) writeConfigFile(filename, configType string, opts []WriteOption) error {
+    var o writeOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    data, err := c.renderConfig(filename, configType, o)
+    if err != nil {
+        return err
+    }
+    if data, err = c.encryptFile(filename, data); err != nil {
+        return err
+    }
+
+    c.logInfo("Writing config to", filename)
+    return atomicWriteFile(filename, data, o.backup)
+}
+
+// renderConfig returns the document that writing to filename would produce.
+func (c *Config) renderConfig(filename, configType string, o writeOptions) ([]byte, error) {
+    configType = strings.ToLower(configType)
+    if !stringInSlice(configType, SupportedExts) {
+        return nil, UnsupportedConfigError(configType)
+    }
+
+    // Edit an existing document of the same type in place so comments and ordering
+    // survive the write.
+    existing, err := c.readPlainFile(filename)
+    if err == nil && canonicalType(c.typeForFile(filename)) == canonicalType(configType) {
+        return mergeIntoDocument(existing, c.writableSettings(o), configType)
+    }
+
+    return marshalConfig(c.writableSettings(o), configType)
+}
+
+// Writes the settings WriteConfig would write to w as a document of configType, so they
+// can be printed or sent elsewhere without a file.
+func WriteConfigTo(w io.Writer, configType string, opts ...WriteOption) error {
+    return c.WriteConfigTo(w, configType, opts...)
+}
+func (c *Config) WriteConfigTo(w io.Writer, configType string, opts ...WriteOption) error {
+    configType = strings.ToLower(configType)
+    if !stringInSlice(configType, SupportedExts) {
+        return UnsupportedConfigError(configType)
+    }
+
+    var o writeOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    data, err := marshalConfig(c.writableSettings(o), configType)
+    if err != nil {
+        return err
+    }
+
+    _, err = w.Write(data)
+    return err
+}
+
+// Returns a unified diff between the config file in use and what WriteConfig would
+// write to it given the same options, without writing anything. The diff is empty when
+// the write would not change the file.
+func DiffAgainstFile(opts ...WriteOption) (string, error) { return c.DiffAgainstFile(opts...) }
+func (c *Config) DiffAgainstFile(opts ...WriteOption) (string, error) {
+    filename := c.ConfigFileUsed()
+    if filename == "" {
+        return "", ConfigFileNotFoundError{c.configName, fmt.Sprintf("%s", c.configPaths)}
+    }
+
+    var o writeOptions
+    for _, opt := range opts {
+        opt(&o)
+    }
+
+    existing, err := c.readPlainFile(filename)
+    if err != nil && !os.IsNotExist(err) {
+        return "", err
+    }
+
+    data, err := c.renderConfig(filename, c.getConfigType(), o)
+    if err != nil {
+        return "", err
+    }
+
+    return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+        A:        difflib.SplitLines(string(existing)),
+        B:        difflib.SplitLines(string(data)),
+        FromFile: filename,
+        ToFile:   filename + " (new)",
+        Context:  3,
+    })
+}
+
+// atomicWriteFile replaces filename with data by writing a temporary file alongside it,
+// syncing it and renaming it over the target, so a crash never leaves a truncated file.
+// Symlinks are followed so the file they point to is replaced rather than the link.
+// With backup set the previous contents are kept in filename.bak.
+func atomicWriteFile(filename string, data []byte, backup bool) error {
+    if real, err := filepath.EvalSymlinks(filename); err == nil {
+        filename = real
+    }
+
+    mode := os.FileMode(0644)
+    if fi, err := os.Stat(filename); err == nil {
+        mode = fi.Mode().Perm()
+
+        if backup {
+            old, err := ioutil.ReadFile(filename)
+            if err != nil {
+                return err
+            }
+            if err := atomicWriteFile(filename+".bak", old, false); err != nil {
+                return err
+            }
+        }
+    }
+
+    dir, base := filepath.Split(filename)
+    if dir == "" {
+        dir = "."
+    }
+
+    tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    if _, err := tmp.Write(data); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Chmod(mode); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Sync(); err != nil {
+        tmp.Close()
+        return err
+    }
+    if err := tmp.Close(); err != nil {
+        return err
+    }
+
+    if err := os.Rename(tmp.Name(), filename); err != nil {
+        return err
+    }
+
+    // Persist the rename itself, not supported everywhere so failures are ignored.
+    if d, err := os.Open(dir); err == nil {
+        d.Sync()
+        d.Close()
+    }
+
+    return nil
+}
+
+// writableSettings merges defaults, the config file's own values and overrides into
+// one nested map, expanding dotted keys. Values merged in from drop-ins, profile
+// overlays and extended files are left out, so they are not copied into the file. Keys
+// are applied in order, so a dotted key always wins over the map holding the same key
+// in its layer.
+func (c *Config) writableSettings(o writeOptions) map[string]interface{} {
+    m := make(map[string]interface{})
+
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+
+    for _, layer := range []map[string]interface{}{c.defaults, c.fileConfig, c.overrides} {
+        for _, key := range sortedKeys(layer) {
+            setNested(m, strings.Split(key, c.keyDelm), normalizeMaps(layer[key]))
+        }
+    }
+
+    if o.onlyChanged {
+        defaults := make(map[string]interface{})
+        for _, key := range sortedKeys(c.defaults) {
+            setNested(defaults, strings.Split(key, c.keyDelm), normalizeMaps(c.defaults[key]))
+        }
+        pruneDefaults(m, defaults)
+    }
+    if c.keyNormalization {
+        c.respell(m)
+    }
+
+    return m
+}
+
+// pruneDefaults removes every value of m equal to its counterpart in defaults, along
+// with any map left empty as a result.
+func pruneDefaults(m, defaults map[string]interface{}) {
+    for key, val := range m {
+        def, exists := defaults[key]
+        if !exists {
+            continue
+        }
+
+        sub, isMap := val.(map[string]interface{})
+        defSub, defIsMap := def.(map[string]interface{})
+        if isMap && defIsMap {
+            pruneDefaults(sub, defSub)
+            if len(sub) == 0 {
+                delete(m, key)
+            }
+        } else if sameValue(val, def) {
+            delete(m, key)
+        }
+    }
+}
+
+// setNested stores val at path within m, creating intermediate maps and merging
+// into maps already present.
+func setNested(m map[string]interface{}, path []string, val interface{}) {
+    for _, p := range path[:len(path)-1] {
+        next, ok := m[p].(map[string]interface{})
+        if !ok {
+            next = make(map[string]interface{})
+            m[p] = next
+        }
+        m = next
+    }
+
+    last := path[len(path)-1]
+    if src, ok := val.(map[string]interface{}); ok {
+        if dst, ok := m[last].(map[string]interface{}); ok {
+            for k, v := range src {
+                setNested(dst, []string{k}, v)
+            }
+            return
+        }
+    }
+
+    m[last] = val
+}
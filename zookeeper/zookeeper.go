@@ -0,0 +1,184 @@
+// Package zookeeper provides a cfg source backed by ZooKeeper znodes.
+package zookeeper
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "path"
+    "strings"
+    "time"
+
+    "github.com/go-zookeeper/zk"
+
+    "github.com/nwlucas/cfg"
+)
+
+// Controls how config is read from ZooKeeper.
+type Options struct {
+    Servers        []string
+    SessionTimeout time.Duration
+
+    // Znode holding the config.
+    Path string
+
+    // Format of the document stored in Path. When empty the subtree beneath Path is
+    // mapped to nested keys instead, leaf znodes supplying string values.
+    Format string
+
+    // Sets watches on every znode read so any change triggers a reload.
+    Watch bool
+}
+
+// A ZooKeeper backed source registered on a Config.
+type Source struct {
+    conn   *zk.Conn
+    reload chan struct{}
+    done   chan struct{}
+}
+
+// Adds the znode described by opts as a source on c.
+func AddSource(c *cfg.Config, opts Options) (*Source, error) {
+    timeout := opts.SessionTimeout
+    if timeout == 0 {
+        timeout = 10 * time.Second
+    }
+
+    conn, _, err := zk.Connect(opts.Servers, timeout)
+    if err != nil {
+        return nil, err
+    }
+
+    s := &Source{conn: conn, reload: make(chan struct{}, 1), done: make(chan struct{})}
+
+    name := "zookeeper:" + opts.Path
+    if err := c.AddSource(name, s.fetcher(opts), 0); err != nil {
+        conn.Close()
+        return nil, err
+    }
+
+    if opts.Watch {
+        go func() {
+            for {
+                select {
+                case <-s.done:
+                    return
+                case <-s.reload:
+                    if err := c.RefreshSource(name); err != nil {
+                        c.Logger().Error(fmt.Sprintf("Failed to reload %s: %v", name, err))
+                    }
+                }
+            }
+        }()
+    }
+
+    return s, nil
+}
+
+// Stops watching and closes the session.
+func (s *Source) Close() error {
+    close(s.done)
+    s.conn.Close()
+    return nil
+}
+
+func (s *Source) fetcher(opts Options) cfg.SourceFunc {
+    return func() (map[string]interface{}, error) {
+        if opts.Format == "" {
+            return s.readTree(opts.Path, opts.Watch)
+        }
+
+        data, err := s.get(opts.Path, opts.Watch)
+        if err != nil {
+            return nil, err
+        }
+
+        if strings.ToLower(opts.Format) == "json" {
+            m := make(map[string]interface{})
+            if err := json.Unmarshal(data, &m); err != nil {
+                return nil, err
+            }
+            return m, nil
+        }
+
+        return cfg.Decode(bytes.NewReader(data), opts.Format)
+    }
+}
+
+func (s *Source) readTree(p string, watch bool) (map[string]interface{}, error) {
+    children, err := s.children(p, watch)
+    if err != nil {
+        return nil, err
+    }
+
+    m := make(map[string]interface{}, len(children))
+    for _, child := range children {
+        childPath := path.Join(p, child)
+
+        grandchildren, err := s.children(childPath, watch)
+        if err != nil {
+            return nil, err
+        }
+
+        if len(grandchildren) > 0 {
+            sub, err := s.readTree(childPath, watch)
+            if err != nil {
+                return nil, err
+            }
+            m[child] = sub
+            continue
+        }
+
+        data, err := s.get(childPath, watch)
+        if err != nil {
+            return nil, err
+        }
+        m[child] = string(data)
+    }
+
+    return m, nil
+}
+
+func (s *Source) get(p string, watch bool) ([]byte, error) {
+    if !watch {
+        data, _, err := s.conn.Get(p)
+        return data, err
+    }
+
+    data, _, events, err := s.conn.GetW(p)
+    if err != nil {
+        return nil, err
+    }
+    go s.notify(events)
+
+    return data, nil
+}
+
+func (s *Source) children(p string, watch bool) ([]string, error) {
+    if !watch {
+        children, _, err := s.conn.Children(p)
+        return children, err
+    }
+
+    children, _, events, err := s.conn.ChildrenW(p)
+    if err != nil {
+        return nil, err
+    }
+    go s.notify(events)
+
+    return children, nil
+}
+
+// notify requests a single reload once the watch fires, coalescing with any pending one.
+func (s *Source) notify(events <-chan zk.Event) {
+    select {
+    case <-events:
+    case <-s.done:
+        return
+    }
+
+    select {
+    case s.reload <- struct{}{}:
+    default:
+    }
+}